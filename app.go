@@ -3,6 +3,7 @@ package main
 import (
 	"context"
 	"embed"
+	"fmt"
 	"log"
 	"os/exec"
 	"path/filepath"
@@ -12,21 +13,31 @@ import (
 	"mooncaketv/models"
 	"mooncaketv/services"
 	"mooncaketv/utils"
+
+	wailsruntime "github.com/wailsapp/wails/v2/pkg/runtime"
 )
 
 // App struct
 type App struct {
 	ctx         context.Context
 	db          *services.DatabaseService
+	backup      *services.BackupService
+	userData    *services.UserDataService
 	authHandler *handlers.AuthHandler
+	proxy       *services.ProxyService
+	playback    *services.PlaybackService
+	links       *services.LinkCheckerService
+	subtitles   *services.SubtitleService
 	migrations  embed.FS
 }
 
 
 // NewApp creates a new App application struct
-func NewApp(migrations embed.FS) *App {
+func NewApp(migrations embed.FS, proxy *services.ProxyService, subtitles *services.SubtitleService) *App {
 	return &App{
 		migrations: migrations,
+		proxy:      proxy,
+		subtitles:  subtitles,
 	}
 }
 
@@ -47,6 +58,42 @@ func (a *App) startup(ctx context.Context) {
 	}
 	a.db = db
 
+	// Forward internal events to the frontend and let Go services follow settings changes
+	db.Events().SubscribeAll(func(e services.Event) {
+		wailsruntime.EventsEmit(a.ctx, e.Name, e.Payload)
+	})
+	services.WireProxySettings(a.proxy, db)
+	services.WireSourceStats(a.proxy, db)
+	services.WireSubtitles(a.subtitles, db)
+
+	// Segments read ahead by the proxy spill to disk once the memory budget is used
+	cacheDir, err := utils.GetAppDataPath("segment-cache")
+	if err != nil {
+		log.Fatalf("Failed to get segment cache path: %v", err)
+	}
+	if err := services.EnableSegmentDiskCache(a.proxy, cacheDir); err != nil {
+		log.Printf("Segment disk cache disabled: %v", err)
+	}
+
+	// Initialize scheduled backups
+	backupDir, err := utils.GetAppDataPath("backups")
+	if err != nil {
+		log.Fatalf("Failed to get backup path: %v", err)
+	}
+	backup, err := services.NewBackupService(db, backupDir)
+	if err != nil {
+		log.Fatalf("Failed to initialize backups: %v", err)
+	}
+	a.backup = backup
+	a.backup.Start()
+
+	a.userData = services.NewUserDataService(db)
+	a.playback = services.NewPlaybackService(db, a.proxy)
+
+	// Check the sources of bookmarked media on a schedule
+	a.links = services.NewLinkCheckerService(db, a.proxy)
+	a.links.Start()
+
 	// Initialize auth service and handler
 	authService := services.NewAuthService(db)
 	a.authHandler = handlers.NewAuthHandler(authService)
@@ -54,6 +101,12 @@ func (a *App) startup(ctx context.Context) {
 
 // shutdown is called when the app is shutting down
 func (a *App) shutdown(ctx context.Context) {
+	if a.backup != nil {
+		a.backup.Stop()
+	}
+	if a.links != nil {
+		a.links.Stop()
+	}
 	if a.db != nil {
 		a.db.Close()
 	}
@@ -68,179 +121,1259 @@ func (a *App) Login(username, password string) models.APIResponse[*services.User
 	return models.NewSuccessResponse(user)
 }
 
-// Signup creates a new user account - delegates to auth handler
-func (a *App) Signup(username, email, password string) models.APIResponse[*services.User] {
-	user, err := a.authHandler.Signup(username, email, password)
+// Signup creates a new user account - delegates to auth handler.
+// inviteCode may be empty unless registration is invite-only.
+func (a *App) Signup(username, email, password, inviteCode string) models.APIResponse[*services.User] {
+	user, err := a.authHandler.Signup(username, email, password, inviteCode)
 	if err != nil {
 		return models.NewErrorResponse[*services.User](err.Error())
 	}
 	return models.NewSuccessResponse(user)
 }
 
-// Database Management Functions
+// NeedsSetup reports whether the app is on first run and needs an admin account
+func (a *App) NeedsSetup() models.APIResponse[bool] {
+	needsSetup, err := a.authHandler.NeedsSetup()
+	if err != nil {
+		return models.NewErrorResponse[bool](err.Error())
+	}
+	return models.NewSuccessResponse(needsSetup)
+}
 
-// GetDatabaseTables returns a list of all tables in the database
-func (a *App) GetDatabaseTables() models.APIResponse[[]string] {
-	tables, err := a.db.GetAllTables()
+// SetupAdmin creates the first admin account; it fails once an admin exists
+func (a *App) SetupAdmin(username, email, password string) models.APIResponse[*services.User] {
+	user, err := a.authHandler.SetupAdmin(username, email, password)
 	if err != nil {
-		return models.NewErrorResponse[[]string](err.Error())
+		return models.NewErrorResponse[*services.User](err.Error())
 	}
-	return models.NewSuccessResponse(tables)
+	return models.NewSuccessResponse(user)
 }
 
-// GetMigrations returns all migration records
-func (a *App) GetMigrations() models.APIResponse[[]map[string]interface{}] {
-	migrations, err := a.db.GetMigrations()
+// LoginWithSecondFactor completes a two-step login using the token returned by Login
+// and a TOTP code or an unused recovery code
+func (a *App) LoginWithSecondFactor(challengeToken, code string) models.APIResponse[*services.User] {
+	user, err := a.authHandler.LoginWithSecondFactor(challengeToken, code)
 	if err != nil {
-		return models.NewErrorResponse[[]map[string]interface{}](err.Error())
+		return models.NewErrorResponse[*services.User](err.Error())
 	}
-	return models.NewSuccessResponse(migrations)
+	return models.NewSuccessResponse(user)
 }
 
-// GetAllUsers returns all users in the database
-func (a *App) GetAllUsers() models.APIResponse[[]map[string]interface{}] {
-	users, err := a.db.GetAllUsers()
+// Logout ends the given session
+func (a *App) Logout(sessionToken string) models.APIResponse[bool] {
+	if err := a.authHandler.Logout(sessionToken); err != nil {
+		return models.NewErrorResponse[bool](err.Error())
+	}
+	return models.NewSuccessResponse(true)
+}
+
+// ValidateSession returns the user a session token belongs to
+func (a *App) ValidateSession(sessionToken string) models.APIResponse[*services.User] {
+	user, err := a.authHandler.ValidateSession(sessionToken)
 	if err != nil {
-		return models.NewErrorResponse[[]map[string]interface{}](err.Error())
+		return models.NewErrorResponse[*services.User](err.Error())
 	}
-	return models.NewSuccessResponse(users)
+	return models.NewSuccessResponse(user)
 }
 
-// GetAllSettings returns all settings records
-func (a *App) GetAllSettings() models.APIResponse[[]map[string]interface{}] {
-	settings, err := a.db.GetAllSettings()
+// sessionUserID resolves the user making a call from their session token.
+// An empty token browses as a guest, user 0; any other token must belong to a live session.
+// Until a temporary password is replaced the session is only good for ChangePassword and Logout.
+func (a *App) sessionUserID(sessionToken string) (int, error) {
+	if sessionToken == "" {
+		return 0, nil
+	}
+	user, err := a.authHandler.ValidateSession(sessionToken)
 	if err != nil {
-		return models.NewErrorResponse[[]map[string]interface{}](err.Error())
+		return 0, err
 	}
-	return models.NewSuccessResponse(settings)
+	if user.MustChangePassword {
+		return 0, fmt.Errorf("password change required")
+	}
+	return user.ID, nil
 }
 
-// GetCurrentUser returns the current user's information by user ID
-func (a *App) GetCurrentUser(userID int) models.APIResponse[map[string]interface{}] {
-	user, err := a.db.GetUserByID(userID)
+// Account Self-Service Functions
+
+// ChangePassword changes the user's password after verifying the old one.
+// All of the user's sessions except sessionToken are signed out.
+func (a *App) ChangePassword(sessionToken, oldPassword, newPassword string) models.APIResponse[bool] {
+	// Not sessionUserID: this is the one call a session with a temporary password may make
+	user, err := a.authHandler.ValidateSession(sessionToken)
 	if err != nil {
-		return models.NewErrorResponse[map[string]interface{}](err.Error())
+		return models.NewErrorResponse[bool](err.Error())
 	}
-	return models.NewSuccessResponse(user)
+	if err := a.authHandler.ChangePassword(user.ID, sessionToken, oldPassword, newPassword); err != nil {
+		return models.NewErrorResponse[bool](err.Error())
+	}
+	return models.NewSuccessResponse(true)
 }
 
-// GetUserSettings returns all settings for a specific user
-func (a *App) GetUserSettings(userID int) models.APIResponse[[]map[string]interface{}] {
-	settings, err := a.db.GetUserSettings(userID)
+// ChangeUsername changes the user's username after verifying their password
+func (a *App) ChangeUsername(sessionToken, password, newUsername string) models.APIResponse[bool] {
+	userID, err := a.sessionUserID(sessionToken)
 	if err != nil {
-		return models.NewErrorResponse[[]map[string]interface{}](err.Error())
+		return models.NewErrorResponse[bool](err.Error())
 	}
-	return models.NewSuccessResponse(settings)
+	if err := a.authHandler.ChangeUsername(userID, password, newUsername); err != nil {
+		return models.NewErrorResponse[bool](err.Error())
+	}
+	return models.NewSuccessResponse(true)
 }
 
-// UpdateSetting updates a setting value
-func (a *App) UpdateSetting(settingID int, newValue string, userID int, isAdmin bool) models.APIResponse[bool] {
-	err := a.db.UpdateSetting(settingID, newValue, userID, isAdmin)
+// ChangeEmail changes the user's email address after verifying their password
+func (a *App) ChangeEmail(sessionToken, password, newEmail string) models.APIResponse[bool] {
+	userID, err := a.sessionUserID(sessionToken)
 	if err != nil {
 		return models.NewErrorResponse[bool](err.Error())
 	}
+	if err := a.authHandler.ChangeEmail(userID, password, newEmail); err != nil {
+		return models.NewErrorResponse[bool](err.Error())
+	}
 	return models.NewSuccessResponse(true)
 }
 
-// DeleteSetting deletes a setting
-func (a *App) DeleteSetting(settingID int, userID int, isAdmin bool) models.APIResponse[bool] {
-	err := a.db.DeleteSetting(settingID, userID, isAdmin)
+// DeleteAccount deletes the user's own account and data after verifying their password
+func (a *App) DeleteAccount(sessionToken, password string) models.APIResponse[bool] {
+	userID, err := a.sessionUserID(sessionToken)
 	if err != nil {
 		return models.NewErrorResponse[bool](err.Error())
 	}
+	if err := a.authHandler.DeleteOwnAccount(userID, password); err != nil {
+		return models.NewErrorResponse[bool](err.Error())
+	}
 	return models.NewSuccessResponse(true)
 }
 
-// OpenDatabaseDirectory opens the directory containing the SQLite database file
-func (a *App) OpenDatabaseDirectory() models.APIResponse[string] {
-	dbPath, err := utils.GetAppDataPath("mooncaketv.db")
+// Profile Functions
+
+// ListProfiles returns the viewer profiles of the user's account, marking the active one
+func (a *App) ListProfiles(sessionToken string) models.APIResponse[[]services.Profile] {
+	userID, err := a.sessionUserID(sessionToken)
 	if err != nil {
-		return models.NewErrorResponse[string](err.Error())
+		return models.NewErrorResponse[[]services.Profile](err.Error())
+	}
+	profiles, err := a.authHandler.ListProfiles(userID)
+	if err != nil {
+		return models.NewErrorResponse[[]services.Profile](err.Error())
 	}
+	return models.NewSuccessResponse(profiles)
+}
 
-	dir := filepath.Dir(dbPath)
+// CreateProfile adds a viewer profile; pin is optional and must be 4 digits when given.
+// parentalPIN is required while the active profile has parental controls.
+func (a *App) CreateProfile(sessionToken, name, avatar, pin, parentalPIN string) models.APIResponse[*services.Profile] {
+	userID, err := a.sessionUserID(sessionToken)
+	if err != nil {
+		return models.NewErrorResponse[*services.Profile](err.Error())
+	}
+	profile, err := a.authHandler.CreateProfile(userID, name, avatar, pin, parentalPIN)
+	if err != nil {
+		return models.NewErrorResponse[*services.Profile](err.Error())
+	}
+	return models.NewSuccessResponse(profile)
+}
 
-	var cmd *exec.Cmd
-	switch runtime.GOOS {
-	case "windows":
-		cmd = exec.Command("explorer", dir)
-	case "darwin":
-		cmd = exec.Command("open", dir)
-	case "linux":
-		cmd = exec.Command("xdg-open", dir)
-	default:
-		return models.NewErrorResponse[string]("Unsupported operating system")
+// SwitchProfile makes a profile active; bookmarks, history and personal settings follow it.
+// parentalPIN is required to leave a profile with parental controls.
+func (a *App) SwitchProfile(sessionToken string, profileID int, pin, parentalPIN string) models.APIResponse[*services.Profile] {
+	userID, err := a.sessionUserID(sessionToken)
+	if err != nil {
+		return models.NewErrorResponse[*services.Profile](err.Error())
+	}
+	profile, err := a.authHandler.SwitchProfile(userID, profileID, pin, parentalPIN)
+	if err != nil {
+		return models.NewErrorResponse[*services.Profile](err.Error())
 	}
+	return models.NewSuccessResponse(profile)
+}
 
-	if err := cmd.Start(); err != nil {
-		return models.NewErrorResponse[string]("Failed to open directory: " + err.Error())
+// DeleteProfile removes a profile with its data; the profile's PIN is required if it has one,
+// and parentalPIN while the active profile has parental controls
+func (a *App) DeleteProfile(sessionToken string, profileID int, pin, parentalPIN string) models.APIResponse[bool] {
+	userID, err := a.sessionUserID(sessionToken)
+	if err != nil {
+		return models.NewErrorResponse[bool](err.Error())
+	}
+	if err := a.authHandler.DeleteProfile(userID, profileID, pin, parentalPIN); err != nil {
+		return models.NewErrorResponse[bool](err.Error())
 	}
+	return models.NewSuccessResponse(true)
+}
 
-	return models.NewSuccessResponse(dir)
+// Two-Factor Authentication Functions
+
+// BeginTOTPEnrollment generates a TOTP secret, otpauth URI and QR code for the user
+func (a *App) BeginTOTPEnrollment(sessionToken string) models.APIResponse[*services.TOTPEnrollment] {
+	userID, err := a.sessionUserID(sessionToken)
+	if err != nil {
+		return models.NewErrorResponse[*services.TOTPEnrollment](err.Error())
+	}
+	enrollment, err := a.authHandler.BeginTOTPEnrollment(userID)
+	if err != nil {
+		return models.NewErrorResponse[*services.TOTPEnrollment](err.Error())
+	}
+	return models.NewSuccessResponse(enrollment)
 }
 
-// Bookmark Management Functions
+// ConfirmTOTPEnrollment verifies the first code, enables 2FA and returns one-time recovery codes
+func (a *App) ConfirmTOTPEnrollment(sessionToken, code string) models.APIResponse[[]string] {
+	userID, err := a.sessionUserID(sessionToken)
+	if err != nil {
+		return models.NewErrorResponse[[]string](err.Error())
+	}
+	codes, err := a.authHandler.ConfirmTOTPEnrollment(userID, code)
+	if err != nil {
+		return models.NewErrorResponse[[]string](err.Error())
+	}
+	return models.NewSuccessResponse(codes)
+}
 
-// AddBookmark adds a bookmark for a user
-func (a *App) AddBookmark(userID int, mcID string) models.APIResponse[bool] {
-	err := a.db.AddBookmark(userID, mcID)
+// DisableTOTP turns off 2FA after verifying the user's password
+func (a *App) DisableTOTP(sessionToken, password string) models.APIResponse[bool] {
+	userID, err := a.sessionUserID(sessionToken)
 	if err != nil {
 		return models.NewErrorResponse[bool](err.Error())
 	}
+	if err := a.authHandler.DisableTOTP(userID, password); err != nil {
+		return models.NewErrorResponse[bool](err.Error())
+	}
 	return models.NewSuccessResponse(true)
 }
 
-// RemoveBookmark removes a bookmark for a user
-func (a *App) RemoveBookmark(userID int, mcID string) models.APIResponse[bool] {
-	err := a.db.RemoveBookmark(userID, mcID)
+// RegenerateRecoveryCodes replaces the user's recovery codes after verifying their password
+func (a *App) RegenerateRecoveryCodes(sessionToken, password string) models.APIResponse[[]string] {
+	userID, err := a.sessionUserID(sessionToken)
+	if err != nil {
+		return models.NewErrorResponse[[]string](err.Error())
+	}
+	codes, err := a.authHandler.RegenerateRecoveryCodes(userID, password)
+	if err != nil {
+		return models.NewErrorResponse[[]string](err.Error())
+	}
+	return models.NewSuccessResponse(codes)
+}
+
+// User Management Functions
+
+// SetUserRole changes another user's role; the caller needs manage_users
+func (a *App) SetUserRole(sessionToken string, targetID int, role string) models.APIResponse[bool] {
+	adminID, err := a.sessionUserID(sessionToken)
 	if err != nil {
 		return models.NewErrorResponse[bool](err.Error())
 	}
+	if err := a.db.Authorize(adminID, services.PermManageUsers); err != nil {
+		return models.NewErrorResponse[bool](err.Error())
+	}
+	if err := a.authHandler.SetUserRole(adminID, targetID, role); err != nil {
+		return models.NewErrorResponse[bool](err.Error())
+	}
 	return models.NewSuccessResponse(true)
 }
 
-// IsBookmarked checks if a user has bookmarked a specific media
-func (a *App) IsBookmarked(userID int, mcID string) models.APIResponse[bool] {
-	isBookmarked, err := a.db.IsBookmarked(userID, mcID)
+// SetUserDisabled disables or re-enables a user's account; the caller needs manage_users
+func (a *App) SetUserDisabled(sessionToken string, targetID int, disabled bool) models.APIResponse[bool] {
+	adminID, err := a.sessionUserID(sessionToken)
 	if err != nil {
 		return models.NewErrorResponse[bool](err.Error())
 	}
-	return models.NewSuccessResponse(isBookmarked)
+	if err := a.db.Authorize(adminID, services.PermManageUsers); err != nil {
+		return models.NewErrorResponse[bool](err.Error())
+	}
+	if err := a.authHandler.SetUserDisabled(adminID, targetID, disabled); err != nil {
+		return models.NewErrorResponse[bool](err.Error())
+	}
+	return models.NewSuccessResponse(true)
 }
 
-// GetUserBookmarks returns all bookmarked mc_ids for a user
-func (a *App) GetUserBookmarks(userID int) models.APIResponse[[]string] {
-	bookmarks, err := a.db.GetUserBookmarks(userID)
+// DeleteUser deletes a user and all of their data; the caller needs manage_users
+func (a *App) DeleteUser(sessionToken string, targetID int) models.APIResponse[bool] {
+	adminID, err := a.sessionUserID(sessionToken)
+	if err != nil {
+		return models.NewErrorResponse[bool](err.Error())
+	}
+	if err := a.db.Authorize(adminID, services.PermManageUsers); err != nil {
+		return models.NewErrorResponse[bool](err.Error())
+	}
+	if err := a.authHandler.DeleteUser(adminID, targetID); err != nil {
+		return models.NewErrorResponse[bool](err.Error())
+	}
+	return models.NewSuccessResponse(true)
+}
+
+// SetTemporaryPassword resets a user's password and returns the temporary one; the caller needs manage_users
+func (a *App) SetTemporaryPassword(sessionToken string, targetID int) models.APIResponse[string] {
+	adminID, err := a.sessionUserID(sessionToken)
+	if err != nil {
+		return models.NewErrorResponse[string](err.Error())
+	}
+	if err := a.db.Authorize(adminID, services.PermManageUsers); err != nil {
+		return models.NewErrorResponse[string](err.Error())
+	}
+	password, err := a.authHandler.SetTemporaryPassword(adminID, targetID)
+	if err != nil {
+		return models.NewErrorResponse[string](err.Error())
+	}
+	return models.NewSuccessResponse(password)
+}
+
+// UnlockAccount clears failed login attempts and any lockout for a username; the caller needs manage_users
+func (a *App) UnlockAccount(sessionToken, username string) models.APIResponse[bool] {
+	adminID, err := a.sessionUserID(sessionToken)
+	if err != nil {
+		return models.NewErrorResponse[bool](err.Error())
+	}
+	if err := a.db.Authorize(adminID, services.PermManageUsers); err != nil {
+		return models.NewErrorResponse[bool](err.Error())
+	}
+	if err := a.authHandler.UnlockAccount(adminID, username); err != nil {
+		return models.NewErrorResponse[bool](err.Error())
+	}
+	return models.NewSuccessResponse(true)
+}
+
+// GetLoginLockouts returns usernames with recent failed logins or an active lockout
+func (a *App) GetLoginLockouts(sessionToken string) models.APIResponse[[]services.LoginLockout] {
+	adminID, err := a.sessionUserID(sessionToken)
+	if err != nil {
+		return models.NewErrorResponse[[]services.LoginLockout](err.Error())
+	}
+	if err := a.db.Authorize(adminID, services.PermManageUsers); err != nil {
+		return models.NewErrorResponse[[]services.LoginLockout](err.Error())
+	}
+	lockouts, err := a.authHandler.ListLoginLockouts()
+	if err != nil {
+		return models.NewErrorResponse[[]services.LoginLockout](err.Error())
+	}
+	return models.NewSuccessResponse(lockouts)
+}
+
+// GetAuthEvents returns recent auth events, newest first; username may be empty
+func (a *App) GetAuthEvents(sessionToken, username string, limit, offset int) models.APIResponse[[]services.AuthEvent] {
+	adminID, err := a.sessionUserID(sessionToken)
+	if err != nil {
+		return models.NewErrorResponse[[]services.AuthEvent](err.Error())
+	}
+	if err := a.db.Authorize(adminID, services.PermManageUsers); err != nil {
+		return models.NewErrorResponse[[]services.AuthEvent](err.Error())
+	}
+	events, err := a.authHandler.ListAuthEvents(username, limit, offset)
+	if err != nil {
+		return models.NewErrorResponse[[]services.AuthEvent](err.Error())
+	}
+	return models.NewSuccessResponse(events)
+}
+
+// CreateInviteCode issues an invite code; maxUses 0 is unlimited, expiresInHours 0 never expires
+func (a *App) CreateInviteCode(sessionToken, role string, maxUses, expiresInHours int) models.APIResponse[*services.InviteCode] {
+	adminID, err := a.sessionUserID(sessionToken)
+	if err != nil {
+		return models.NewErrorResponse[*services.InviteCode](err.Error())
+	}
+	if err := a.db.Authorize(adminID, services.PermManageUsers); err != nil {
+		return models.NewErrorResponse[*services.InviteCode](err.Error())
+	}
+	invite, err := a.authHandler.CreateInviteCode(adminID, role, maxUses, expiresInHours)
+	if err != nil {
+		return models.NewErrorResponse[*services.InviteCode](err.Error())
+	}
+	return models.NewSuccessResponse(invite)
+}
+
+// ListInviteCodes returns all invite codes with their usage
+func (a *App) ListInviteCodes(sessionToken string) models.APIResponse[[]services.InviteCode] {
+	adminID, err := a.sessionUserID(sessionToken)
+	if err != nil {
+		return models.NewErrorResponse[[]services.InviteCode](err.Error())
+	}
+	if err := a.db.Authorize(adminID, services.PermManageUsers); err != nil {
+		return models.NewErrorResponse[[]services.InviteCode](err.Error())
+	}
+	invites, err := a.authHandler.ListInviteCodes()
+	if err != nil {
+		return models.NewErrorResponse[[]services.InviteCode](err.Error())
+	}
+	return models.NewSuccessResponse(invites)
+}
+
+// RevokeInviteCode deletes an invite code
+func (a *App) RevokeInviteCode(sessionToken string, codeID int) models.APIResponse[bool] {
+	adminID, err := a.sessionUserID(sessionToken)
+	if err != nil {
+		return models.NewErrorResponse[bool](err.Error())
+	}
+	if err := a.db.Authorize(adminID, services.PermManageUsers); err != nil {
+		return models.NewErrorResponse[bool](err.Error())
+	}
+	if err := a.authHandler.RevokeInviteCode(adminID, codeID); err != nil {
+		return models.NewErrorResponse[bool](err.Error())
+	}
+	return models.NewSuccessResponse(true)
+}
+
+// Role and Permission Functions
+
+// GetPermissions returns the permissions a user holds; an empty token returns the guest role's
+func (a *App) GetPermissions(sessionToken string) models.APIResponse[[]string] {
+	userID, err := a.sessionUserID(sessionToken)
 	if err != nil {
 		return models.NewErrorResponse[[]string](err.Error())
 	}
-	return models.NewSuccessResponse(bookmarks)
+	permissions, err := a.db.GetUserPermissions(userID)
+	if err != nil {
+		return models.NewErrorResponse[[]string](err.Error())
+	}
+	return models.NewSuccessResponse(permissions)
 }
 
-// GetBookmarkedMediaDetails returns full media details for user's bookmarks
-func (a *App) GetBookmarkedMediaDetails(userID int) models.APIResponse[[]map[string]interface{}] {
-	bookmarks, err := a.db.GetBookmarkedMediaDetails(userID)
+// ListPermissions returns every permission a role can be granted
+func (a *App) ListPermissions() models.APIResponse[[]services.PermissionInfo] {
+	return models.NewSuccessResponse(services.AvailablePermissions)
+}
+
+// ListRoles returns all roles with their permissions
+func (a *App) ListRoles(sessionToken string) models.APIResponse[[]services.Role] {
+	adminID, err := a.sessionUserID(sessionToken)
 	if err != nil {
-		return models.NewErrorResponse[[]map[string]interface{}](err.Error())
+		return models.NewErrorResponse[[]services.Role](err.Error())
 	}
-	return models.NewSuccessResponse(bookmarks)
+	if err := a.db.Authorize(adminID, services.PermManageUsers); err != nil {
+		return models.NewErrorResponse[[]services.Role](err.Error())
+	}
+	roles, err := a.db.ListRoles()
+	if err != nil {
+		return models.NewErrorResponse[[]services.Role](err.Error())
+	}
+	return models.NewSuccessResponse(roles)
 }
 
-// SaveMediaInfo saves media information to the database
-func (a *App) SaveMediaInfo(mcID, title, description string, year int, genre, region, category, posterURL, videoURLs string, rating float64) models.APIResponse[bool] {
-	err := a.db.SaveOrUpdateMedia(mcID, title, description, year, genre, region, category, posterURL, videoURLs, rating)
+// SaveRole creates a custom role or replaces a role's permissions
+func (a *App) SaveRole(sessionToken, name, description string, permissions []string) models.APIResponse[bool] {
+	adminID, err := a.sessionUserID(sessionToken)
 	if err != nil {
 		return models.NewErrorResponse[bool](err.Error())
 	}
+	if err := a.db.Authorize(adminID, services.PermManageUsers); err != nil {
+		return models.NewErrorResponse[bool](err.Error())
+	}
+	if err := a.db.SaveRole(adminID, name, description, permissions); err != nil {
+		return models.NewErrorResponse[bool](err.Error())
+	}
 	return models.NewSuccessResponse(true)
 }
 
-// DeleteMediaInfo deletes media information from the database
-func (a *App) DeleteMediaInfo(mcID string) models.APIResponse[bool] {
-	err := a.db.DeleteMedia(mcID)
+// DeleteRole removes a custom role that is no longer assigned
+func (a *App) DeleteRole(sessionToken, name string) models.APIResponse[bool] {
+	adminID, err := a.sessionUserID(sessionToken)
 	if err != nil {
 		return models.NewErrorResponse[bool](err.Error())
 	}
+	if err := a.db.Authorize(adminID, services.PermManageUsers); err != nil {
+		return models.NewErrorResponse[bool](err.Error())
+	}
+	if err := a.db.DeleteRole(adminID, name); err != nil {
+		return models.NewErrorResponse[bool](err.Error())
+	}
 	return models.NewSuccessResponse(true)
 }
 
+// GetAuditLog returns a filtered page of privileged changes, newest first
+func (a *App) GetAuditLog(sessionToken string, query services.AuditQuery) models.APIResponse[*services.AuditPage] {
+	userID, err := a.sessionUserID(sessionToken)
+	if err != nil {
+		return models.NewErrorResponse[*services.AuditPage](err.Error())
+	}
+	if err := a.db.Authorize(userID, services.PermViewAdminDB); err != nil {
+		return models.NewErrorResponse[*services.AuditPage](err.Error())
+	}
+	page, err := a.db.QueryAuditLog(query)
+	if err != nil {
+		return models.NewErrorResponse[*services.AuditPage](err.Error())
+	}
+	return models.NewSuccessResponse(page)
+}
+
+// Database Management Functions
+
+// GetDatabaseTables returns a list of all tables in the database
+func (a *App) GetDatabaseTables(sessionToken string) models.APIResponse[[]string] {
+	userID, err := a.sessionUserID(sessionToken)
+	if err != nil {
+		return models.NewErrorResponse[[]string](err.Error())
+	}
+	if err := a.db.Authorize(userID, services.PermViewAdminDB); err != nil {
+		return models.NewErrorResponse[[]string](err.Error())
+	}
+	tables, err := a.db.GetAllTables()
+	if err != nil {
+		return models.NewErrorResponse[[]string](err.Error())
+	}
+	return models.NewSuccessResponse(tables)
+}
+
+// GetBrowsableTables returns column metadata and row counts for the tables the admin browser can read
+func (a *App) GetBrowsableTables(sessionToken string) models.APIResponse[[]services.TableInfo] {
+	userID, err := a.sessionUserID(sessionToken)
+	if err != nil {
+		return models.NewErrorResponse[[]services.TableInfo](err.Error())
+	}
+	if err := a.db.Authorize(userID, services.PermViewAdminDB); err != nil {
+		return models.NewErrorResponse[[]services.TableInfo](err.Error())
+	}
+	tables, err := a.db.GetBrowsableTables()
+	if err != nil {
+		return models.NewErrorResponse[[]services.TableInfo](err.Error())
+	}
+	return models.NewSuccessResponse(tables)
+}
+
+// QueryTableRows returns a paginated, sorted and filtered page of rows from a browsable table
+func (a *App) QueryTableRows(sessionToken string, query services.TableQuery) models.APIResponse[*services.TablePage] {
+	userID, err := a.sessionUserID(sessionToken)
+	if err != nil {
+		return models.NewErrorResponse[*services.TablePage](err.Error())
+	}
+	if err := a.db.Authorize(userID, services.PermViewAdminDB); err != nil {
+		return models.NewErrorResponse[*services.TablePage](err.Error())
+	}
+	page, err := a.db.QueryTableRows(query)
+	if err != nil {
+		return models.NewErrorResponse[*services.TablePage](err.Error())
+	}
+	return models.NewSuccessResponse(page)
+}
+
+// GetMigrations returns all migration records
+func (a *App) GetMigrations(sessionToken string) models.APIResponse[[]map[string]interface{}] {
+	userID, err := a.sessionUserID(sessionToken)
+	if err != nil {
+		return models.NewErrorResponse[[]map[string]interface{}](err.Error())
+	}
+	if err := a.db.Authorize(userID, services.PermViewAdminDB); err != nil {
+		return models.NewErrorResponse[[]map[string]interface{}](err.Error())
+	}
+	migrations, err := a.db.GetMigrations()
+	if err != nil {
+		return models.NewErrorResponse[[]map[string]interface{}](err.Error())
+	}
+	return models.NewSuccessResponse(migrations)
+}
+
+// GetAllUsers returns all users in the database
+func (a *App) GetAllUsers(sessionToken string) models.APIResponse[[]map[string]interface{}] {
+	userID, err := a.sessionUserID(sessionToken)
+	if err != nil {
+		return models.NewErrorResponse[[]map[string]interface{}](err.Error())
+	}
+	if err := a.db.Authorize(userID, services.PermViewAdminDB); err != nil {
+		return models.NewErrorResponse[[]map[string]interface{}](err.Error())
+	}
+	users, err := a.db.GetAllUsers()
+	if err != nil {
+		return models.NewErrorResponse[[]map[string]interface{}](err.Error())
+	}
+	return models.NewSuccessResponse(users)
+}
+
+// GetAllSettings returns all settings records
+func (a *App) GetAllSettings(sessionToken string) models.APIResponse[[]map[string]interface{}] {
+	userID, err := a.sessionUserID(sessionToken)
+	if err != nil {
+		return models.NewErrorResponse[[]map[string]interface{}](err.Error())
+	}
+	if err := a.db.Authorize(userID, services.PermViewAdminDB); err != nil {
+		return models.NewErrorResponse[[]map[string]interface{}](err.Error())
+	}
+	settings, err := a.db.GetAllSettings()
+	if err != nil {
+		return models.NewErrorResponse[[]map[string]interface{}](err.Error())
+	}
+	return models.NewSuccessResponse(settings)
+}
+
+// GetCurrentUser returns the current user's information by user ID
+func (a *App) GetCurrentUser(sessionToken string) models.APIResponse[map[string]interface{}] {
+	userID, err := a.sessionUserID(sessionToken)
+	if err != nil {
+		return models.NewErrorResponse[map[string]interface{}](err.Error())
+	}
+	user, err := a.db.GetUserByID(userID)
+	if err != nil {
+		return models.NewErrorResponse[map[string]interface{}](err.Error())
+	}
+	return models.NewSuccessResponse(user)
+}
+
+// GetUserSettings returns all settings for a specific user
+func (a *App) GetUserSettings(sessionToken string) models.APIResponse[[]map[string]interface{}] {
+	userID, err := a.sessionUserID(sessionToken)
+	if err != nil {
+		return models.NewErrorResponse[[]map[string]interface{}](err.Error())
+	}
+	settings, err := a.db.GetUserSettings(userID)
+	if err != nil {
+		return models.NewErrorResponse[[]map[string]interface{}](err.Error())
+	}
+	return models.NewSuccessResponse(settings)
+}
+
+// UpdateSetting updates a setting value; global settings need edit_global_settings
+func (a *App) UpdateSetting(sessionToken string, settingID int, newValue string) models.APIResponse[bool] {
+	userID, err := a.sessionUserID(sessionToken)
+	if err != nil {
+		return models.NewErrorResponse[bool](err.Error())
+	}
+	err = a.db.UpdateSetting(settingID, newValue, userID)
+	if err != nil {
+		return models.NewErrorResponse[bool](err.Error())
+	}
+	return models.NewSuccessResponse(true)
+}
+
+// GetSettingDefinitions returns every registered setting with its type, default and scope
+func (a *App) GetSettingDefinitions() models.APIResponse[[]services.SettingDefinition] {
+	return models.NewSuccessResponse(services.ListSettingDefinitions())
+}
+
+// GetEffectiveSetting resolves a setting for a user from personal, global and default values
+func (a *App) GetEffectiveSetting(sessionToken, key string) models.APIResponse[*services.EffectiveSetting] {
+	userID, err := a.sessionUserID(sessionToken)
+	if err != nil {
+		return models.NewErrorResponse[*services.EffectiveSetting](err.Error())
+	}
+	setting, err := a.db.GetEffectiveSetting(userID, key)
+	if err != nil {
+		return models.NewErrorResponse[*services.EffectiveSetting](err.Error())
+	}
+	return models.NewSuccessResponse(setting)
+}
+
+// UpsertSetting validates and creates or updates a registered setting
+func (a *App) UpsertSetting(sessionToken, key, value string, global bool) models.APIResponse[bool] {
+	userID, err := a.sessionUserID(sessionToken)
+	if err != nil {
+		return models.NewErrorResponse[bool](err.Error())
+	}
+	if err := a.db.UpsertSetting(key, value, userID, global); err != nil {
+		return models.NewErrorResponse[bool](err.Error())
+	}
+	return models.NewSuccessResponse(true)
+}
+
+// DeleteSetting deletes a setting; global settings need edit_global_settings
+func (a *App) DeleteSetting(sessionToken string, settingID int) models.APIResponse[bool] {
+	userID, err := a.sessionUserID(sessionToken)
+	if err != nil {
+		return models.NewErrorResponse[bool](err.Error())
+	}
+	err = a.db.DeleteSetting(settingID, userID)
+	if err != nil {
+		return models.NewErrorResponse[bool](err.Error())
+	}
+	return models.NewSuccessResponse(true)
+}
+
+// OpenDatabaseDirectory opens the directory containing the SQLite database file
+func (a *App) OpenDatabaseDirectory(sessionToken string) models.APIResponse[string] {
+	userID, err := a.sessionUserID(sessionToken)
+	if err != nil {
+		return models.NewErrorResponse[string](err.Error())
+	}
+	if err := a.db.Authorize(userID, services.PermViewAdminDB); err != nil {
+		return models.NewErrorResponse[string](err.Error())
+	}
+	dbPath, err := utils.GetAppDataPath("mooncaketv.db")
+	if err != nil {
+		return models.NewErrorResponse[string](err.Error())
+	}
+
+	dir := filepath.Dir(dbPath)
+
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "windows":
+		cmd = exec.Command("explorer", dir)
+	case "darwin":
+		cmd = exec.Command("open", dir)
+	case "linux":
+		cmd = exec.Command("xdg-open", dir)
+	default:
+		return models.NewErrorResponse[string]("Unsupported operating system")
+	}
+
+	if err := cmd.Start(); err != nil {
+		return models.NewErrorResponse[string]("Failed to open directory: " + err.Error())
+	}
+
+	return models.NewSuccessResponse(dir)
+}
+
+// Bookmark Management Functions
+
+// AddBookmark adds a bookmark for a user
+func (a *App) AddBookmark(sessionToken, mcID string) models.APIResponse[bool] {
+	userID, err := a.sessionUserID(sessionToken)
+	if err != nil {
+		return models.NewErrorResponse[bool](err.Error())
+	}
+	err = a.db.AddBookmark(userID, mcID)
+	if err != nil {
+		return models.NewErrorResponse[bool](err.Error())
+	}
+	return models.NewSuccessResponse(true)
+}
+
+// RemoveBookmark removes a bookmark for a user
+func (a *App) RemoveBookmark(sessionToken, mcID string) models.APIResponse[bool] {
+	userID, err := a.sessionUserID(sessionToken)
+	if err != nil {
+		return models.NewErrorResponse[bool](err.Error())
+	}
+	err = a.db.RemoveBookmark(userID, mcID)
+	if err != nil {
+		return models.NewErrorResponse[bool](err.Error())
+	}
+	return models.NewSuccessResponse(true)
+}
+
+// IsBookmarked checks if a user has bookmarked a specific media
+func (a *App) IsBookmarked(sessionToken, mcID string) models.APIResponse[bool] {
+	userID, err := a.sessionUserID(sessionToken)
+	if err != nil {
+		return models.NewErrorResponse[bool](err.Error())
+	}
+	isBookmarked, err := a.db.IsBookmarked(userID, mcID)
+	if err != nil {
+		return models.NewErrorResponse[bool](err.Error())
+	}
+	return models.NewSuccessResponse(isBookmarked)
+}
+
+// GetUserBookmarks returns all bookmarked mc_ids for a user
+func (a *App) GetUserBookmarks(sessionToken string) models.APIResponse[[]string] {
+	userID, err := a.sessionUserID(sessionToken)
+	if err != nil {
+		return models.NewErrorResponse[[]string](err.Error())
+	}
+	bookmarks, err := a.db.GetUserBookmarks(userID)
+	if err != nil {
+		return models.NewErrorResponse[[]string](err.Error())
+	}
+	return models.NewSuccessResponse(bookmarks)
+}
+
+// GetBookmarkedMediaDetails returns full media details for user's bookmarks
+func (a *App) GetBookmarkedMediaDetails(sessionToken string) models.APIResponse[[]map[string]interface{}] {
+	userID, err := a.sessionUserID(sessionToken)
+	if err != nil {
+		return models.NewErrorResponse[[]map[string]interface{}](err.Error())
+	}
+	bookmarks, err := a.db.GetBookmarkedMediaDetails(userID)
+	if err != nil {
+		return models.NewErrorResponse[[]map[string]interface{}](err.Error())
+	}
+	return models.NewSuccessResponse(bookmarks)
+}
+
+// SaveMediaInfo saves media information to the database with its sources and
+// episodes. Sources already stored but not given are kept.
+// Callers without manage_media only cache titles not stored yet.
+func (a *App) SaveMediaInfo(sessionToken, mcID, title, description string, year int, genre, region, category, posterURL string, sources []services.MediaSource, rating float64) models.APIResponse[bool] {
+	userID, err := a.sessionUserID(sessionToken)
+	if err != nil {
+		return models.NewErrorResponse[bool](err.Error())
+	}
+	if err := a.db.Authorize(userID, services.PermBrowse); err != nil {
+		return models.NewErrorResponse[bool](err.Error())
+	}
+	canManage, err := a.db.HasPermission(userID, services.PermManageMedia)
+	if err != nil {
+		return models.NewErrorResponse[bool](err.Error())
+	}
+	if canManage {
+		err = a.db.SaveOrUpdateMedia(mcID, title, description, year, genre, region, category, posterURL, sources, rating)
+	} else {
+		err = a.db.CacheMedia(mcID, title, description, year, genre, region, category, posterURL, sources, rating)
+	}
+	if err != nil {
+		return models.NewErrorResponse[bool](err.Error())
+	}
+	return models.NewSuccessResponse(true)
+}
+
+// DeleteMediaInfo deletes media information from the database
+func (a *App) DeleteMediaInfo(sessionToken, mcID string) models.APIResponse[bool] {
+	userID, err := a.sessionUserID(sessionToken)
+	if err != nil {
+		return models.NewErrorResponse[bool](err.Error())
+	}
+	if err := a.db.Authorize(userID, services.PermManageMedia); err != nil {
+		return models.NewErrorResponse[bool](err.Error())
+	}
+	err = a.db.DeleteMedia(userID, mcID)
+	if err != nil {
+		return models.NewErrorResponse[bool](err.Error())
+	}
+	return models.NewSuccessResponse(true)
+}
+
+// SetMediaContentRating records a cached media item's content rating, e.g. PG-13 or 12+
+func (a *App) SetMediaContentRating(sessionToken, mcID, rating string) models.APIResponse[bool] {
+	userID, err := a.sessionUserID(sessionToken)
+	if err != nil {
+		return models.NewErrorResponse[bool](err.Error())
+	}
+	if err := a.db.Authorize(userID, services.PermManageMedia); err != nil {
+		return models.NewErrorResponse[bool](err.Error())
+	}
+	if err := a.db.SetMediaContentRating(userID, mcID, rating); err != nil {
+		return models.NewErrorResponse[bool](err.Error())
+	}
+	return models.NewSuccessResponse(true)
+}
+
+// Parental Control Functions
+
+// FilterMediaList removes catalog or search results blocked for the user's active profile; an empty token browses as a guest
+func (a *App) FilterMediaList(sessionToken string, items []map[string]interface{}) models.APIResponse[[]map[string]interface{}] {
+	userID, err := a.sessionUserID(sessionToken)
+	if err != nil {
+		return models.NewErrorResponse[[]map[string]interface{}](err.Error())
+	}
+	if err := a.db.Authorize(userID, services.PermBrowse); err != nil {
+		return models.NewErrorResponse[[]map[string]interface{}](err.Error())
+	}
+	filtered, err := a.db.FilterMediaList(userID, items)
+	if err != nil {
+		return models.NewErrorResponse[[]map[string]interface{}](err.Error())
+	}
+	return models.NewSuccessResponse(filtered)
+}
+
+// CheckPlayback reports whether the user's active profile may play a media item right now
+func (a *App) CheckPlayback(sessionToken, mcID string) models.APIResponse[bool] {
+	userID, err := a.sessionUserID(sessionToken)
+	if err != nil {
+		return models.NewErrorResponse[bool](err.Error())
+	}
+	if err := a.db.Authorize(userID, services.PermBrowse); err != nil {
+		return models.NewErrorResponse[bool](err.Error())
+	}
+	if err := a.db.CheckPlayback(userID, mcID); err != nil {
+		return models.NewErrorResponse[bool](err.Error())
+	}
+	return models.NewSuccessResponse(true)
+}
+
+// GetParentalRules returns a profile's restrictions to its owner or a user with manage_users
+func (a *App) GetParentalRules(sessionToken string, profileID int) models.APIResponse[*services.ParentalRules] {
+	userID, err := a.sessionUserID(sessionToken)
+	if err != nil {
+		return models.NewErrorResponse[*services.ParentalRules](err.Error())
+	}
+	rules, err := a.authHandler.GetParentalRules(userID, profileID)
+	if err != nil {
+		return models.NewErrorResponse[*services.ParentalRules](err.Error())
+	}
+	return models.NewSuccessResponse(rules)
+}
+
+// SetParentalRules replaces a profile's restrictions; owners without manage_users must give the parental PIN
+func (a *App) SetParentalRules(sessionToken string, profileID int, rules services.ParentalRules, pin string) models.APIResponse[bool] {
+	userID, err := a.sessionUserID(sessionToken)
+	if err != nil {
+		return models.NewErrorResponse[bool](err.Error())
+	}
+	if err := a.authHandler.SetParentalRules(userID, profileID, rules, pin); err != nil {
+		return models.NewErrorResponse[bool](err.Error())
+	}
+	return models.NewSuccessResponse(true)
+}
+
+// SetParentalPIN sets or clears the account's parental PIN after verifying the password
+func (a *App) SetParentalPIN(sessionToken, password, pin string) models.APIResponse[bool] {
+	userID, err := a.sessionUserID(sessionToken)
+	if err != nil {
+		return models.NewErrorResponse[bool](err.Error())
+	}
+	if err := a.authHandler.SetParentalPIN(userID, password, pin); err != nil {
+		return models.NewErrorResponse[bool](err.Error())
+	}
+	return models.NewSuccessResponse(true)
+}
+
+// Playback Functions
+
+// StartPlayback opens a failover session for an episode. sources lists every
+// provider for the title; when empty, the title's cached video URLs are used.
+func (a *App) StartPlayback(sessionToken, mcID, episode string, sources []services.PlaybackSource) models.APIResponse[*services.PlaybackState] {
+	userID, err := a.sessionUserID(sessionToken)
+	if err != nil {
+		return models.NewErrorResponse[*services.PlaybackState](err.Error())
+	}
+	if err := a.db.Authorize(userID, services.PermBrowse); err != nil {
+		return models.NewErrorResponse[*services.PlaybackState](err.Error())
+	}
+	if err := a.db.CheckPlayback(userID, mcID); err != nil {
+		return models.NewErrorResponse[*services.PlaybackState](err.Error())
+	}
+	state, err := a.playback.StartPlayback(userID, mcID, episode, sources)
+	if err != nil {
+		return models.NewErrorResponse[*services.PlaybackState](err.Error())
+	}
+	return models.NewSuccessResponse(state)
+}
+
+// ReportPlaybackError passes a player error to the session and returns which source to switch to, if any
+func (a *App) ReportPlaybackError(sessionID string, report services.PlaybackErrorReport) models.APIResponse[*services.PlaybackSwitch] {
+	next, err := a.playback.ReportPlaybackError(sessionID, report)
+	if err != nil {
+		return models.NewErrorResponse[*services.PlaybackSwitch](err.Error())
+	}
+	return models.NewSuccessResponse(next)
+}
+
+// EndPlayback closes a playback session
+func (a *App) EndPlayback(sessionID string) models.APIResponse[bool] {
+	a.playback.EndPlayback(sessionID)
+	return models.NewSuccessResponse(true)
+}
+
+// GetSkipMarkers returns the intro and outro markers of a series for the active profile
+func (a *App) GetSkipMarkers(sessionToken, mcID string) models.APIResponse[*services.SkipMarkers] {
+	userID, err := a.sessionUserID(sessionToken)
+	if err != nil {
+		return models.NewErrorResponse[*services.SkipMarkers](err.Error())
+	}
+	markers, err := a.db.GetSkipMarkers(userID, mcID)
+	if err != nil {
+		return models.NewErrorResponse[*services.SkipMarkers](err.Error())
+	}
+	return models.NewSuccessResponse(markers)
+}
+
+// SetSkipMarker sets an intro or outro marker of a series from the current
+// position in an episode of the given duration
+func (a *App) SetSkipMarker(sessionToken, mcID, marker string, position, duration float64) models.APIResponse[*services.SkipMarkers] {
+	userID, err := a.sessionUserID(sessionToken)
+	if err != nil {
+		return models.NewErrorResponse[*services.SkipMarkers](err.Error())
+	}
+	markers, err := a.db.SetSkipMarker(userID, mcID, marker, position, duration)
+	if err != nil {
+		return models.NewErrorResponse[*services.SkipMarkers](err.Error())
+	}
+	return models.NewSuccessResponse(markers)
+}
+
+// ClearSkipMarkers removes the intro and outro markers of a series for the active profile
+func (a *App) ClearSkipMarkers(sessionToken, mcID string) models.APIResponse[bool] {
+	userID, err := a.sessionUserID(sessionToken)
+	if err != nil {
+		return models.NewErrorResponse[bool](err.Error())
+	}
+	if err := a.db.ClearSkipMarkers(userID, mcID); err != nil {
+		return models.NewErrorResponse[bool](err.Error())
+	}
+	return models.NewSuccessResponse(true)
+}
+
+// Subtitle Functions
+
+// ListSubtitles returns the subtitles attached to an episode
+func (a *App) ListSubtitles(sessionToken, mcID, episode string) models.APIResponse[[]services.SubtitleTrack] {
+	userID, err := a.sessionUserID(sessionToken)
+	if err != nil {
+		return models.NewErrorResponse[[]services.SubtitleTrack](err.Error())
+	}
+	if err := a.db.Authorize(userID, services.PermBrowse); err != nil {
+		return models.NewErrorResponse[[]services.SubtitleTrack](err.Error())
+	}
+	tracks, err := a.subtitles.List(mcID, episode)
+	if err != nil {
+		return models.NewErrorResponse[[]services.SubtitleTrack](err.Error())
+	}
+	return models.NewSuccessResponse(tracks)
+}
+
+// AttachSubtitle converts an SRT, ASS/SSA or WebVTT file to WebVTT and
+// attaches it to an episode. contentBase64 is the file as read from disk.
+func (a *App) AttachSubtitle(sessionToken, mcID, episode, fileName, contentBase64 string) models.APIResponse[*services.SubtitleTrack] {
+	userID, err := a.sessionUserID(sessionToken)
+	if err != nil {
+		return models.NewErrorResponse[*services.SubtitleTrack](err.Error())
+	}
+	if err := a.db.Authorize(userID, services.PermBrowse); err != nil {
+		return models.NewErrorResponse[*services.SubtitleTrack](err.Error())
+	}
+	track, err := a.subtitles.Attach(userID, mcID, episode, fileName, contentBase64)
+	if err != nil {
+		return models.NewErrorResponse[*services.SubtitleTrack](err.Error())
+	}
+	return models.NewSuccessResponse(track)
+}
+
+// SetSubtitleOffset shifts a subtitle's timing by offsetMS milliseconds
+func (a *App) SetSubtitleOffset(sessionToken string, id int64, offsetMS int) models.APIResponse[*services.SubtitleTrack] {
+	userID, err := a.sessionUserID(sessionToken)
+	if err != nil {
+		return models.NewErrorResponse[*services.SubtitleTrack](err.Error())
+	}
+	track, err := a.subtitles.SetOffset(userID, id, offsetMS)
+	if err != nil {
+		return models.NewErrorResponse[*services.SubtitleTrack](err.Error())
+	}
+	return models.NewSuccessResponse(track)
+}
+
+// DeleteSubtitle removes an attached subtitle
+func (a *App) DeleteSubtitle(sessionToken string, id int64) models.APIResponse[bool] {
+	userID, err := a.sessionUserID(sessionToken)
+	if err != nil {
+		return models.NewErrorResponse[bool](err.Error())
+	}
+	if err := a.subtitles.Delete(userID, id); err != nil {
+		return models.NewErrorResponse[bool](err.Error())
+	}
+	return models.NewSuccessResponse(true)
+}
+
+// Danmaku Functions
+
+// GetDanmaku returns an episode's bullet comments between from and to, in
+// seconds, leaving out those matched by filter or the profile's saved filter
+func (a *App) GetDanmaku(sessionToken, mcID, episode string, from, to float64, filter services.DanmakuFilter) models.APIResponse[[]services.DanmakuComment] {
+	userID, err := a.sessionUserID(sessionToken)
+	if err != nil {
+		return models.NewErrorResponse[[]services.DanmakuComment](err.Error())
+	}
+	if err := a.db.Authorize(userID, services.PermBrowse); err != nil {
+		return models.NewErrorResponse[[]services.DanmakuComment](err.Error())
+	}
+	comments, err := a.db.GetDanmaku(userID, mcID, episode, from, to, filter)
+	if err != nil {
+		return models.NewErrorResponse[[]services.DanmakuComment](err.Error())
+	}
+	return models.NewSuccessResponse(comments)
+}
+
+// PostDanmaku adds the user's own bullet comment at a position in an episode
+func (a *App) PostDanmaku(sessionToken, mcID, episode string, seconds float64, text, mode string, color int) models.APIResponse[*services.DanmakuComment] {
+	userID, err := a.sessionUserID(sessionToken)
+	if err != nil {
+		return models.NewErrorResponse[*services.DanmakuComment](err.Error())
+	}
+	if err := a.db.Authorize(userID, services.PermPostComments); err != nil {
+		return models.NewErrorResponse[*services.DanmakuComment](err.Error())
+	}
+	comment, err := a.db.PostDanmaku(userID, mcID, episode, seconds, text, mode, color)
+	if err != nil {
+		return models.NewErrorResponse[*services.DanmakuComment](err.Error())
+	}
+	return models.NewSuccessResponse(comment)
+}
+
+// DeleteDanmaku removes a bullet comment; others' need the moderate_comments permission
+func (a *App) DeleteDanmaku(sessionToken string, id int64) models.APIResponse[bool] {
+	userID, err := a.sessionUserID(sessionToken)
+	if err != nil {
+		return models.NewErrorResponse[bool](err.Error())
+	}
+	if err := a.db.DeleteDanmaku(userID, id); err != nil {
+		return models.NewErrorResponse[bool](err.Error())
+	}
+	return models.NewSuccessResponse(true)
+}
+
+// ImportDanmaku stores the comments of a Bilibili XML or JSON, or DPlayer JSON,
+// danmaku file for an episode. contentBase64 is the file as read from disk.
+func (a *App) ImportDanmaku(sessionToken, mcID, episode, fileName, contentBase64 string) models.APIResponse[*services.DanmakuImportResult] {
+	userID, err := a.sessionUserID(sessionToken)
+	if err != nil {
+		return models.NewErrorResponse[*services.DanmakuImportResult](err.Error())
+	}
+	if err := a.db.Authorize(userID, services.PermManageMedia); err != nil {
+		return models.NewErrorResponse[*services.DanmakuImportResult](err.Error())
+	}
+	result, err := a.db.ImportDanmaku(mcID, episode, fileName, contentBase64)
+	if err != nil {
+		return models.NewErrorResponse[*services.DanmakuImportResult](err.Error())
+	}
+	return models.NewSuccessResponse(result)
+}
+
+// ClearImportedDanmaku removes an episode's imported bullet comments, keeping
+// ones posted here, and returns how many were removed
+func (a *App) ClearImportedDanmaku(sessionToken, mcID, episode string) models.APIResponse[int64] {
+	userID, err := a.sessionUserID(sessionToken)
+	if err != nil {
+		return models.NewErrorResponse[int64](err.Error())
+	}
+	if err := a.db.Authorize(userID, services.PermManageMedia); err != nil {
+		return models.NewErrorResponse[int64](err.Error())
+	}
+	removed, err := a.db.ClearImportedDanmaku(mcID, episode)
+	if err != nil {
+		return models.NewErrorResponse[int64](err.Error())
+	}
+	return models.NewSuccessResponse(removed)
+}
+
+// GetSourceReport returns the speed and reliability history of every source host
+func (a *App) GetSourceReport(sessionToken string) models.APIResponse[[]services.HostReport] {
+	userID, err := a.sessionUserID(sessionToken)
+	if err != nil {
+		return models.NewErrorResponse[[]services.HostReport](err.Error())
+	}
+	if err := a.db.Authorize(userID, services.PermViewAdminDB); err != nil {
+		return models.NewErrorResponse[[]services.HostReport](err.Error())
+	}
+	report, err := a.db.GetHostReport()
+	if err != nil {
+		return models.NewErrorResponse[[]services.HostReport](err.Error())
+	}
+	return models.NewSuccessResponse(report)
+}
+
+// CheckBookmarkLinks probes the sources of every bookmarked title now instead of waiting for the schedule
+func (a *App) CheckBookmarkLinks(sessionToken string) models.APIResponse[*services.LinkCheckReport] {
+	userID, err := a.sessionUserID(sessionToken)
+	if err != nil {
+		return models.NewErrorResponse[*services.LinkCheckReport](err.Error())
+	}
+	if err := a.db.Authorize(userID, services.PermManageMedia); err != nil {
+		return models.NewErrorResponse[*services.LinkCheckReport](err.Error())
+	}
+	report, err := a.links.CheckNow()
+	if err != nil {
+		return models.NewErrorResponse[*services.LinkCheckReport](err.Error())
+	}
+	return models.NewSuccessResponse(report)
+}
+
+// GetSourceStatus returns the last link check result for each source of a title
+func (a *App) GetSourceStatus(sessionToken, mcID string) models.APIResponse[[]services.SourceCheck] {
+	userID, err := a.sessionUserID(sessionToken)
+	if err != nil {
+		return models.NewErrorResponse[[]services.SourceCheck](err.Error())
+	}
+	if err := a.db.Authorize(userID, services.PermBrowse); err != nil {
+		return models.NewErrorResponse[[]services.SourceCheck](err.Error())
+	}
+	checks, err := a.links.GetSourceChecks(mcID)
+	if err != nil {
+		return models.NewErrorResponse[[]services.SourceCheck](err.Error())
+	}
+	return models.NewSuccessResponse(checks)
+}
+
+// Backup Management Functions
+
+// CreateBackup writes a database snapshot immediately
+func (a *App) CreateBackup(sessionToken string) models.APIResponse[*services.BackupSnapshot] {
+	userID, err := a.sessionUserID(sessionToken)
+	if err != nil {
+		return models.NewErrorResponse[*services.BackupSnapshot](err.Error())
+	}
+	if err := a.db.Authorize(userID, services.PermManageBackups); err != nil {
+		return models.NewErrorResponse[*services.BackupSnapshot](err.Error())
+	}
+	snapshot, err := a.backup.CreateSnapshot()
+	if err != nil {
+		return models.NewErrorResponse[*services.BackupSnapshot](err.Error())
+	}
+	return models.NewSuccessResponse(snapshot)
+}
+
+// ListBackups returns all database snapshots, newest first
+func (a *App) ListBackups(sessionToken string) models.APIResponse[[]services.BackupSnapshot] {
+	userID, err := a.sessionUserID(sessionToken)
+	if err != nil {
+		return models.NewErrorResponse[[]services.BackupSnapshot](err.Error())
+	}
+	if err := a.db.Authorize(userID, services.PermManageBackups); err != nil {
+		return models.NewErrorResponse[[]services.BackupSnapshot](err.Error())
+	}
+	snapshots, err := a.backup.ListSnapshots()
+	if err != nil {
+		return models.NewErrorResponse[[]services.BackupSnapshot](err.Error())
+	}
+	return models.NewSuccessResponse(snapshots)
+}
+
+// RestoreBackup stages a snapshot to replace the database on next restart
+func (a *App) RestoreBackup(sessionToken, name string) models.APIResponse[bool] {
+	userID, err := a.sessionUserID(sessionToken)
+	if err != nil {
+		return models.NewErrorResponse[bool](err.Error())
+	}
+	if err := a.db.Authorize(userID, services.PermManageBackups); err != nil {
+		return models.NewErrorResponse[bool](err.Error())
+	}
+	if err := a.backup.RestoreSnapshot(userID, name); err != nil {
+		return models.NewErrorResponse[bool](err.Error())
+	}
+	return models.NewSuccessResponse(true)
+}
+
+// CancelRestoreBackup discards a staged restore
+func (a *App) CancelRestoreBackup(sessionToken string) models.APIResponse[bool] {
+	userID, err := a.sessionUserID(sessionToken)
+	if err != nil {
+		return models.NewErrorResponse[bool](err.Error())
+	}
+	if err := a.db.Authorize(userID, services.PermManageBackups); err != nil {
+		return models.NewErrorResponse[bool](err.Error())
+	}
+	if err := a.backup.CancelPendingRestore(); err != nil {
+		return models.NewErrorResponse[bool](err.Error())
+	}
+	return models.NewSuccessResponse(true)
+}
+
+// HasPendingRestore reports whether a restore will be applied on next restart
+func (a *App) HasPendingRestore(sessionToken string) models.APIResponse[bool] {
+	userID, err := a.sessionUserID(sessionToken)
+	if err != nil {
+		return models.NewErrorResponse[bool](err.Error())
+	}
+	if err := a.db.Authorize(userID, services.PermManageBackups); err != nil {
+		return models.NewErrorResponse[bool](err.Error())
+	}
+	return models.NewSuccessResponse(a.backup.HasPendingRestore())
+}
+
+// User Data Export/Import Functions
+
+// ExportUserData returns a versioned JSON archive of the user's bookmarks, history and settings
+func (a *App) ExportUserData(sessionToken string) models.APIResponse[string] {
+	userID, err := a.sessionUserID(sessionToken)
+	if err != nil {
+		return models.NewErrorResponse[string](err.Error())
+	}
+	if userID == 0 {
+		return models.NewErrorResponse[string]("sign in to export your data")
+	}
+	archive, err := a.userData.ExportUserDataJSON(userID)
+	if err != nil {
+		return models.NewErrorResponse[string](err.Error())
+	}
+	return models.NewSuccessResponse(archive)
+}
+
+// ImportUserData merges an exported archive into the user's data.
+// strategy is one of "skip", "overwrite" or "newest".
+func (a *App) ImportUserData(sessionToken, archiveJSON, strategy string) models.APIResponse[*services.ImportReport] {
+	userID, err := a.sessionUserID(sessionToken)
+	if err != nil {
+		return models.NewErrorResponse[*services.ImportReport](err.Error())
+	}
+	if userID == 0 {
+		return models.NewErrorResponse[*services.ImportReport]("sign in to import your data")
+	}
+	report, err := a.userData.ImportUserDataJSON(userID, archiveJSON, strategy)
+	if err != nil {
+		return models.NewErrorResponse[*services.ImportReport](err.Error())
+	}
+	return models.NewSuccessResponse(report)
+}
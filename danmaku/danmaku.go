@@ -0,0 +1,143 @@
+// Package danmaku reads bullet comment (弹幕) files exported from Bilibili,
+// in its XML or JSON form, and from DPlayer-compatible danmaku servers.
+package danmaku
+
+import (
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// Format is a danmaku file format
+type Format string
+
+const (
+	FormatXML  Format = "xml"  // Bilibili <i><d p="...">text</d></i>
+	FormatJSON Format = "json" // Bilibili elems or DPlayer data arrays
+)
+
+// Mode is where a comment is drawn
+type Mode string
+
+const (
+	ModeScroll Mode = "scroll" // right to left across the video
+	ModeTop    Mode = "top"    // fixed at the top, centered
+	ModeBottom Mode = "bottom" // fixed at the bottom, centered
+)
+
+const (
+	// DefaultColor is white, the color of most comments
+	DefaultColor = 0xFFFFFF
+
+	// DefaultSize is Bilibili's normal font size; small is 18 and large 36
+	DefaultSize = 25
+
+	// MaxTextLength is the longest comment kept, in characters. Bilibili
+	// limits posts to 100; anything longer is an advanced comment or spam.
+	MaxTextLength = 100
+)
+
+// Comment is one timed bullet comment
+type Comment struct {
+	ID     string // id in the source file, empty if it has none
+	Time   time.Duration
+	Mode   Mode
+	Color  int // 0xRRGGBB
+	Size   int
+	Author string // sender as given in the file; Bilibili only has a hash of the user id
+	Text   string
+}
+
+// ValidMode reports whether m is a mode the player can draw
+func ValidMode(m Mode) bool {
+	return m == ModeScroll || m == ModeTop || m == ModeBottom
+}
+
+// DetectFormat guesses the format from the file name, falling back to the content
+func DetectFormat(name string, data []byte) (Format, error) {
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".xml":
+		return FormatXML, nil
+	case ".json":
+		return FormatJSON, nil
+	}
+
+	head := bytes.TrimLeft(bytes.TrimPrefix(data, []byte{0xEF, 0xBB, 0xBF}), " \t\r\n")
+	if len(head) > 0 {
+		switch head[0] {
+		case '<':
+			return FormatXML, nil
+		case '{', '[':
+			return FormatJSON, nil
+		}
+	}
+	return "", fmt.Errorf("unrecognized danmaku format")
+}
+
+// Parse reads a danmaku file, detecting its format. Comments the player
+// cannot draw, such as Bilibili's advanced and code comments, are skipped.
+// The result is sorted by time.
+func Parse(name string, data []byte) (Format, []Comment, error) {
+	format, err := DetectFormat(name, data)
+	if err != nil {
+		return "", nil, err
+	}
+	data = bytes.TrimPrefix(data, []byte{0xEF, 0xBB, 0xBF})
+
+	var comments []Comment
+	switch format {
+	case FormatXML:
+		comments, err = parseXML(data)
+	case FormatJSON:
+		comments, err = parseJSON(data)
+	}
+	if err != nil {
+		return "", nil, err
+	}
+
+	kept := comments[:0]
+	for _, c := range comments {
+		c.Text = CleanText(c.Text)
+		if c.Text == "" || c.Time < 0 || !ValidMode(c.Mode) {
+			continue
+		}
+		if c.Size <= 0 {
+			c.Size = DefaultSize
+		}
+		c.Color &= 0xFFFFFF
+		kept = append(kept, c)
+	}
+	if len(kept) == 0 {
+		return "", nil, fmt.Errorf("no danmaku found in the file")
+	}
+
+	sort.SliceStable(kept, func(i, j int) bool {
+		return kept[i].Time < kept[j].Time
+	})
+	return format, kept, nil
+}
+
+// CleanText puts a comment on one line, drops control characters and cuts it
+// to MaxTextLength characters. Bilibili writes line breaks as a literal "/n".
+func CleanText(text string) string {
+	text = strings.ReplaceAll(text, "/n", " ")
+	text = strings.Map(func(r rune) rune {
+		if unicode.IsSpace(r) {
+			return ' '
+		}
+		if unicode.IsControl(r) {
+			return -1
+		}
+		return r
+	}, text)
+	text = strings.Join(strings.Fields(text), " ")
+
+	if runes := []rune(text); len(runes) > MaxTextLength {
+		text = string(runes[:MaxTextLength])
+	}
+	return text
+}
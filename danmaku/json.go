@@ -0,0 +1,206 @@
+package danmaku
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// listKeys are the fields that hold the comment list in the JSON shapes seen
+// in the wild: Bilibili's {"elems": [...]}, DPlayer's {"data": [...]} and
+// older DPlayer servers' {"danmaku": [...]}, possibly nested one level
+var listKeys = []string{"elems", "data", "danmaku"}
+
+// jsonComment covers both Bilibili elems, timed by progress in milliseconds,
+// and DPlayer objects, timed by time in seconds
+type jsonComment struct {
+	ID       json.Number     `json:"id"`
+	IDStr    string          `json:"idStr"`
+	Progress *float64        `json:"progress"`
+	Time     *float64        `json:"time"`
+	Mode     json.RawMessage `json:"mode"`
+	Type     json.RawMessage `json:"type"`
+	FontSize int             `json:"fontsize"`
+	Color    json.RawMessage `json:"color"`
+	MidHash  string          `json:"midHash"`
+	Author   string          `json:"author"`
+	Content  string          `json:"content"`
+	Text     string          `json:"text"`
+}
+
+func parseJSON(data []byte) ([]Comment, error) {
+	items, err := jsonItems(data, 2)
+	if err != nil {
+		return nil, err
+	}
+
+	var comments []Comment
+	for _, item := range items {
+		var c Comment
+		var ok bool
+		if trimmed := bytes.TrimSpace(item); len(trimmed) > 0 && trimmed[0] == '[' {
+			c, ok = parseDPlayerArray(trimmed)
+		} else {
+			c, ok = parseJSONObject(item)
+		}
+		if ok {
+			comments = append(comments, c)
+		}
+	}
+	return comments, nil
+}
+
+// jsonItems finds the comment list, either the document itself or one of
+// listKeys inside it, looking at most depth objects deep
+func jsonItems(data []byte, depth int) ([]json.RawMessage, error) {
+	data = bytes.TrimSpace(data)
+	if len(data) > 0 && data[0] == '[' {
+		var items []json.RawMessage
+		if err := json.Unmarshal(data, &items); err != nil {
+			return nil, fmt.Errorf("invalid danmaku json: %w", err)
+		}
+		return items, nil
+	}
+
+	var obj map[string]json.RawMessage
+	if err := json.Unmarshal(data, &obj); err != nil {
+		return nil, fmt.Errorf("invalid danmaku json: %w", err)
+	}
+	if depth > 0 {
+		for _, key := range listKeys {
+			if inner, ok := obj[key]; ok {
+				return jsonItems(inner, depth-1)
+			}
+		}
+	}
+	return nil, fmt.Errorf("no danmaku list found in the json")
+}
+
+// parseDPlayerArray reads DPlayer's [time, type, color, author, text]
+func parseDPlayerArray(item []byte) (Comment, bool) {
+	var fields []json.RawMessage
+	if err := json.Unmarshal(item, &fields); err != nil || len(fields) < 5 {
+		return Comment{}, false
+	}
+	var seconds float64
+	var author, text string
+	if json.Unmarshal(fields[0], &seconds) != nil || json.Unmarshal(fields[4], &text) != nil {
+		return Comment{}, false
+	}
+	json.Unmarshal(fields[3], &author)
+
+	return Comment{
+		Time:   time.Duration(seconds * float64(time.Second)),
+		Mode:   dplayerMode(fields[1]),
+		Color:  jsonColor(fields[2]),
+		Author: author,
+		Text:   text,
+	}, true
+}
+
+func parseJSONObject(item []byte) (Comment, bool) {
+	dec := json.NewDecoder(bytes.NewReader(item))
+	dec.UseNumber()
+	var j jsonComment
+	if err := dec.Decode(&j); err != nil {
+		return Comment{}, false
+	}
+
+	c := Comment{
+		Color: jsonColor(j.Color),
+		Size:  j.FontSize,
+	}
+	switch {
+	case j.Progress != nil:
+		c.Time = time.Duration(*j.Progress * float64(time.Millisecond))
+	case j.Time != nil:
+		c.Time = time.Duration(*j.Time * float64(time.Second))
+	default:
+		// Bilibili leaves progress out for comments at 0s
+		if j.Content == "" {
+			return Comment{}, false
+		}
+	}
+
+	if j.Content != "" {
+		var mode int
+		json.Unmarshal(j.Mode, &mode)
+		c.Mode = bilibiliMode(mode)
+		c.Author = j.MidHash
+		c.Text = j.Content
+		if len(j.Color) == 0 {
+			c.Color = 0 // zero fields are left out, so a missing color is black
+		}
+		c.ID = j.IDStr
+		if c.ID == "" {
+			c.ID = j.ID.String()
+		}
+	} else {
+		c.Mode = dplayerMode(j.Type)
+		c.Author = j.Author
+		c.Text = j.Text
+	}
+	return c, true
+}
+
+// dplayerMode maps DPlayer's type, 0 right, 1 top and 2 bottom in current
+// versions and the names themselves in older ones
+func dplayerMode(raw json.RawMessage) Mode {
+	var n int
+	if json.Unmarshal(raw, &n) == nil {
+		switch n {
+		case 0:
+			return ModeScroll
+		case 1:
+			return ModeTop
+		case 2:
+			return ModeBottom
+		}
+		return ""
+	}
+	var s string
+	json.Unmarshal(raw, &s)
+	switch s {
+	case "", "right", "scroll":
+		return ModeScroll
+	case "top":
+		return ModeTop
+	case "bottom":
+		return ModeBottom
+	}
+	return ""
+}
+
+// jsonColor reads a color given as a decimal number or a "#rrggbb" string
+func jsonColor(raw json.RawMessage) int {
+	var n int
+	if json.Unmarshal(raw, &n) == nil {
+		return n
+	}
+	var s string
+	if json.Unmarshal(raw, &s) == nil {
+		if n, ok := ParseColor(s); ok {
+			return n
+		}
+	}
+	return DefaultColor
+}
+
+// ParseColor reads "#rrggbb" or "#rgb"
+func ParseColor(s string) (int, bool) {
+	s = strings.TrimPrefix(strings.TrimSpace(s), "#")
+	if len(s) == 3 {
+		s = string([]byte{s[0], s[0], s[1], s[1], s[2], s[2]})
+	}
+	if len(s) != 6 {
+		return 0, false
+	}
+	n, err := strconv.ParseUint(s, 16, 32)
+	if err != nil {
+		return 0, false
+	}
+	return int(n), true
+}
@@ -0,0 +1,101 @@
+package danmaku
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// parseXML reads Bilibili's XML format. Each comment is a <d> element whose
+// p attribute is "time,mode,size,color,sent,pool,senderHash,id[,weight]".
+func parseXML(data []byte) ([]Comment, error) {
+	// Bilibili's exports often hold control characters XML does not allow
+	data = bytes.Map(func(r rune) rune {
+		if r < 0x20 && r != '\t' && r != '\n' && r != '\r' {
+			return -1
+		}
+		return r
+	}, data)
+
+	dec := xml.NewDecoder(bytes.NewReader(data))
+	dec.Strict = false
+
+	var comments []Comment
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid danmaku xml: %w", err)
+		}
+		start, ok := tok.(xml.StartElement)
+		if !ok || start.Name.Local != "d" {
+			continue
+		}
+
+		var d struct {
+			P    string `xml:"p,attr"`
+			Text string `xml:",chardata"`
+		}
+		if err := dec.DecodeElement(&d, &start); err != nil {
+			return nil, fmt.Errorf("invalid danmaku xml: %w", err)
+		}
+		c, ok := parseXMLComment(d.P, d.Text)
+		if ok {
+			comments = append(comments, c)
+		}
+	}
+	return comments, nil
+}
+
+func parseXMLComment(p, text string) (Comment, bool) {
+	fields := strings.Split(p, ",")
+	if len(fields) < 4 {
+		return Comment{}, false
+	}
+	seconds, err := strconv.ParseFloat(fields[0], 64)
+	if err != nil {
+		return Comment{}, false
+	}
+	mode, _ := strconv.Atoi(fields[1])
+	size, _ := strconv.Atoi(fields[2])
+	color, err := strconv.Atoi(fields[3])
+	if err != nil {
+		color = DefaultColor
+	}
+
+	c := Comment{
+		Time:  time.Duration(seconds * float64(time.Second)),
+		Mode:  bilibiliMode(mode),
+		Color: color,
+		Size:  size,
+		Text:  text,
+	}
+	if len(fields) > 6 {
+		c.Author = fields[6]
+	}
+	if len(fields) > 7 {
+		c.ID = fields[7]
+	}
+	return c, true
+}
+
+// bilibiliMode maps Bilibili's numeric modes. 1 to 3 all scroll, 6 scrolls
+// the other way and is drawn like the rest; 7 (positioned), 8 (code) and 9
+// (BAS) are advanced comments the player does not support.
+func bilibiliMode(mode int) Mode {
+	switch mode {
+	case 1, 2, 3, 6:
+		return ModeScroll
+	case 4:
+		return ModeBottom
+	case 5:
+		return ModeTop
+	}
+	return ""
+}
@@ -0,0 +1,206 @@
+import { useEffect, useRef, useState, type CSSProperties, type RefObject } from "react";
+import { cn } from "../../lib/utils";
+import { services } from "../../../wailsjs/go/models";
+
+// Comments are fetched in windows of this many seconds, one window ahead
+const WINDOW_SECONDS = 30;
+const SCROLL_SECONDS = 8;
+const FIXED_SECONDS = 4;
+const LANE_HEIGHT = 30;
+// Keep bottom comments clear of the native controls
+const BOTTOM_OFFSET = 48;
+// A jump bigger than this is a seek; the comments skipped over are not shown
+const SEEK_THRESHOLD = 1;
+
+interface ActiveComment {
+  key: number;
+  comment: services.DanmakuComment;
+  lane: number;
+  distance: number; // Pixels a scrolling comment travels before it is off screen
+}
+
+interface DanmakuLayerProps {
+  videoRef: RefObject<HTMLVideoElement | null>;
+  // Loads the comments between two positions in seconds; a new function reloads everything
+  load: (from: number, to: number) => Promise<services.DanmakuComment[]>;
+  posted?: services.DanmakuComment[]; // The user's own new comments, shown straight away
+}
+
+function cssColor(color: number): string {
+  return `#${color.toString(16).padStart(6, "0")}`;
+}
+
+function fontSize(comment: services.DanmakuComment): number {
+  return Math.round((comment.size || 25) * 0.9);
+}
+
+export function DanmakuLayer({ videoRef, load, posted }: DanmakuLayerProps) {
+  const containerRef = useRef<HTMLDivElement>(null);
+  const [active, setActive] = useState<ActiveComment[]>([]);
+  const [paused, setPaused] = useState(true);
+  const nextKey = useRef(0);
+  // When each lane of each mode is free again, in performance.now() milliseconds
+  const lanes = useRef<Record<string, number[]>>({});
+  const windows = useRef(new Map<number, services.DanmakuComment[]>());
+  const handledPosts = useRef(new Set<number>());
+
+  const show = (comment: services.DanmakuComment) => {
+    const container = containerRef.current;
+    if (!container) return;
+
+    const rows = Math.max(1, Math.floor((container.clientHeight - BOTTOM_OFFSET) / LANE_HEIGHT));
+    const laneCount = comment.mode === "scroll" ? rows : Math.max(1, Math.floor(rows / 2));
+    const width = comment.text.length * fontSize(comment);
+    const now = performance.now();
+    const freeAt = (lanes.current[comment.mode] ??= []);
+
+    let lane = -1;
+    for (let i = 0; i < laneCount; i++) {
+      if ((freeAt[i] ?? 0) <= now) {
+        lane = i;
+        break;
+      }
+    }
+    // Every lane is taken; drop the comment rather than pile them up
+    if (lane < 0) return;
+
+    const distance = container.clientWidth;
+    freeAt[lane] =
+      now +
+      (comment.mode === "scroll"
+        ? ((width + 16) / (distance + width)) * SCROLL_SECONDS * 1000
+        : FIXED_SECONDS * 1000);
+
+    const key = nextKey.current++;
+    setActive((current) => [...current, { key, comment, lane, distance }]);
+  };
+
+  // Fetch windows around the playhead and show comments as it passes them
+  useEffect(() => {
+    const video = videoRef.current;
+    if (!video) return;
+
+    let cancelled = false;
+    const requested = new Set<number>();
+    windows.current = new Map();
+    lanes.current = {};
+    setActive([]);
+    setPaused(video.paused);
+
+    const ensure = (index: number) => {
+      if (index < 0 || requested.has(index)) return;
+      requested.add(index);
+      load(index * WINDOW_SECONDS, (index + 1) * WINDOW_SECONDS)
+        .then((comments) => {
+          if (cancelled) return;
+          // Keep comments posted while the window was loading
+          const posted = (windows.current.get(index) || []).filter(
+            (p) => !comments.some((c) => c.id === p.id)
+          );
+          windows.current.set(index, [...comments, ...posted]);
+        })
+        .catch((err) => console.error("Error loading danmaku:", err));
+    };
+
+    let last = video.currentTime;
+    const tick = () => {
+      const now = video.currentTime;
+      const index = Math.floor(now / WINDOW_SECONDS);
+      ensure(index);
+      ensure(index + 1);
+
+      if (now > last && now - last <= SEEK_THRESHOLD) {
+        const from = Math.floor(last / WINDOW_SECONDS);
+        for (let i = from; i <= index; i++) {
+          for (const comment of windows.current.get(i) || []) {
+            if (comment.time > last && comment.time <= now) show(comment);
+          }
+        }
+      }
+      last = now;
+    };
+
+    let frame = requestAnimationFrame(function loop() {
+      tick();
+      frame = requestAnimationFrame(loop);
+    });
+
+    const handlePlay = () => setPaused(false);
+    const handlePause = () => setPaused(true);
+    const handleSeeking = () => {
+      lanes.current = {};
+      setActive([]);
+    };
+    video.addEventListener("play", handlePlay);
+    video.addEventListener("pause", handlePause);
+    video.addEventListener("seeking", handleSeeking);
+
+    return () => {
+      cancelled = true;
+      cancelAnimationFrame(frame);
+      video.removeEventListener("play", handlePlay);
+      video.removeEventListener("pause", handlePause);
+      video.removeEventListener("seeking", handleSeeking);
+    };
+  }, [videoRef, load]);
+
+  // Show the user's own comments at once and keep them for when the window comes round again
+  useEffect(() => {
+    for (const comment of posted || []) {
+      if (handledPosts.current.has(comment.id)) continue;
+      handledPosts.current.add(comment.id);
+      const index = Math.floor(comment.time / WINDOW_SECONDS);
+      windows.current.set(index, [...(windows.current.get(index) || []), comment]);
+      show(comment);
+    }
+  }, [posted]);
+
+  const remove = (key: number) => {
+    setActive((current) => current.filter((a) => a.key !== key));
+  };
+
+  return (
+    <div
+      ref={containerRef}
+      className="pointer-events-none absolute inset-0 z-40 overflow-hidden rounded-lg"
+    >
+      {active.map(({ key, comment, lane, distance }) => {
+        const style: CSSProperties = {
+          color: cssColor(comment.color),
+          fontSize: `${fontSize(comment)}px`,
+          animationName: comment.mode === "scroll" ? "danmaku-scroll" : "danmaku-fixed",
+          animationDuration: `${comment.mode === "scroll" ? SCROLL_SECONDS : FIXED_SECONDS}s`,
+          animationTimingFunction: "linear",
+          animationFillMode: "forwards",
+          animationPlayState: paused ? "paused" : "running",
+        };
+        if (comment.mode === "scroll") {
+          style.top = 8 + lane * LANE_HEIGHT;
+          style.left = "100%";
+          (style as Record<string, string | number>)["--danmaku-distance"] = `${distance}px`;
+        } else {
+          style.left = "50%";
+          style.transform = "translateX(-50%)";
+          if (comment.mode === "top") {
+            style.top = 8 + lane * LANE_HEIGHT;
+          } else {
+            style.bottom = BOTTOM_OFFSET + lane * LANE_HEIGHT;
+          }
+        }
+        return (
+          <span
+            key={key}
+            style={style}
+            className={cn(
+              "absolute whitespace-nowrap font-medium leading-tight [text-shadow:0_0_2px_#000,0_0_2px_#000]",
+              comment.own && "rounded border border-white/70 px-1"
+            )}
+            onAnimationEnd={() => remove(key)}
+          >
+            {comment.text}
+          </span>
+        );
+      })}
+    </div>
+  );
+}
@@ -1,11 +1,16 @@
 import { SidebarInset, SidebarProvider } from "../ui/sidebar";
 import { McSidebar } from "../mc-sidebar";
+import { Toaster } from "../ui/sonner";
+import { useDeadLinkAlerts } from "../../hooks/use-dead-link-alerts";
 
 export function McLayout({ children }: { children: React.ReactNode }) {
+  useDeadLinkAlerts();
+
   return (
     <SidebarProvider defaultOpen={true}>
       <McSidebar />
       <SidebarInset>{children}</SidebarInset>
+      <Toaster />
     </SidebarProvider>
   );
 }
@@ -31,16 +31,18 @@ function getSpeedColor(speed: number): { text: string; icon: string } {
 }
 
 async function testMediaSpeed(
+  mcId: string,
   m3u8_urls: Record<string, string>
 ): Promise<number> {
   const urls = Object.values(m3u8_urls);
   if (urls.length === 0) return Infinity;
 
-  // Test the first available URL using Go backend (bypasses CORS)
+  // Test the first available URL using Go backend (bypasses CORS); the backend
+  // records the result and reuses a recent one instead of testing again
   const url = urls[0];
 
   try {
-    const result = await TestMediaSpeed(url);
+    const result = await TestMediaSpeed(url, mcId);
 
     if (result.error) {
       console.error("Speed test error:", result.error);
@@ -71,7 +73,7 @@ export function MediaCard({
 
     if (mediaItem.m3u8_urls && Object.keys(mediaItem.m3u8_urls).length > 0) {
       setIsTesting(true);
-      testMediaSpeed(mediaItem.m3u8_urls)
+      testMediaSpeed(mediaItem.mc_id, mediaItem.m3u8_urls)
         .then((speed) => {
           if (speed === Infinity) {
             setHasError(true);
@@ -83,7 +85,7 @@ export function MediaCard({
           setIsTesting(false);
         });
     }
-  }, [mediaItem.m3u8_urls, mediaItem.isLoading]);
+  }, [mediaItem.mc_id, mediaItem.m3u8_urls, mediaItem.isLoading]);
   return (
     <Card
       onClick={onClick}
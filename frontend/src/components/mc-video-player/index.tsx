@@ -1,22 +1,62 @@
 import { useEffect, useRef, useState } from "react";
 import Hls from "hls.js";
-import { Maximize, Minimize } from "lucide-react";
+import { Maximize, Minimize, SkipForward } from "lucide-react";
 import { Button } from "../ui/button";
+import { DanmakuLayer } from "../mc-danmaku-layer";
 import { cn } from "../../lib/utils";
 import { WindowFullscreen, WindowUnfullscreen } from "../../../wailsjs/runtime/runtime";
+import { services } from "../../../wailsjs/go/models";
+
+export interface PlaybackError {
+  url: string;
+  details: string;
+  fatal: boolean;
+  position: number;
+  segment_index: number;
+}
 
 interface VideoPlayerProps {
   src: string;
   poster?: string;
   className?: string;
+  startAt?: number; // Seconds to resume from, e.g. after switching source
+  // Called on every HLS error; resolves true when the caller is switching to another source
+  onPlaybackError?: (error: PlaybackError) => Promise<boolean>;
+  markers?: services.SkipMarkers; // Intro and outro markers of the series
+  onNextEpisode?: () => void; // Omitted on the last episode
+  onTimeUpdate?: (position: number, duration: number) => void;
+  subtitles?: services.SubtitleTrack[]; // The first one is shown by default
+  // Loads bullet comments for a stretch of the episode; omitted when they are turned off
+  loadDanmaku?: (from: number, to: number) => Promise<services.DanmakuComment[]>;
+  postedDanmaku?: services.DanmakuComment[];
 }
 
-export function VideoPlayer({ src, poster, className = "" }: VideoPlayerProps) {
+export function VideoPlayer({
+  src,
+  poster,
+  className = "",
+  startAt,
+  onPlaybackError,
+  markers,
+  onNextEpisode,
+  onTimeUpdate,
+  subtitles,
+  loadDanmaku,
+  postedDanmaku,
+}: VideoPlayerProps) {
   const videoRef = useRef<HTMLVideoElement>(null);
   const containerRef = useRef<HTMLDivElement>(null);
   const hlsRef = useRef<Hls | null>(null);
+  const onPlaybackErrorRef = useRef(onPlaybackError);
+  onPlaybackErrorRef.current = onPlaybackError;
+  const onNextEpisodeRef = useRef(onNextEpisode);
+  onNextEpisodeRef.current = onNextEpisode;
+  const onTimeUpdateRef = useRef(onTimeUpdate);
+  onTimeUpdateRef.current = onTimeUpdate;
   const [error, setError] = useState<string>("");
   const [isFullscreen, setIsFullscreen] = useState(false);
+  const [inIntro, setInIntro] = useState(false);
+  const [inOutro, setInOutro] = useState(false);
 
   useEffect(() => {
     const video = videoRef.current;
@@ -33,6 +73,7 @@ export function VideoPlayer({ src, poster, className = "" }: VideoPlayerProps) {
         backBufferLength: 90,
         maxBufferLength: 30,
         maxMaxBufferLength: 600,
+        startPosition: startAt && startAt > 0 ? startAt : -1,
         xhrSetup: (xhr) => {
           xhr.withCredentials = false;
         },
@@ -54,6 +95,24 @@ export function VideoPlayer({ src, poster, className = "" }: VideoPlayerProps) {
         });
       });
 
+      const recover = (data: { type: string }) => {
+        switch (data.type) {
+          case Hls.ErrorTypes.NETWORK_ERROR:
+            console.log("Network error, attempting to recover...");
+            hls.startLoad();
+            break;
+          case Hls.ErrorTypes.MEDIA_ERROR:
+            console.log("Media error, attempting to recover...");
+            hls.recoverMediaError();
+            break;
+          default:
+            console.error("Fatal error, cannot recover");
+            setError("Failed to load video");
+            hls.destroy();
+            break;
+        }
+      };
+
       // Handle errors
       hls.on(Hls.Events.ERROR, (_event, data) => {
         console.error("HLS error:", {
@@ -63,28 +122,34 @@ export function VideoPlayer({ src, poster, className = "" }: VideoPlayerProps) {
           url: data.url,
           response: data.response,
         });
-        if (data.fatal) {
-          switch (data.type) {
-            case Hls.ErrorTypes.NETWORK_ERROR:
-              console.log("Network error, attempting to recover...");
-              hls.startLoad();
-              break;
-            case Hls.ErrorTypes.MEDIA_ERROR:
-              console.log("Media error, attempting to recover...");
-              hls.recoverMediaError();
-              break;
-            default:
-              console.error("Fatal error, cannot recover");
-              setError("Failed to load video");
-              hls.destroy();
-              break;
-          }
+
+        const report = onPlaybackErrorRef.current;
+        if (!report) {
+          if (data.fatal) recover(data);
+          return;
         }
+
+        // Let the playback session decide whether to move to another source
+        const sn = data.frag?.sn;
+        report({
+          url: data.frag?.url || data.url || "",
+          details: data.details,
+          fatal: data.fatal,
+          position: video.currentTime,
+          segment_index: typeof sn === "number" ? sn : -1,
+        })
+          .catch(() => false)
+          .then((switching) => {
+            if (!switching && data.fatal) recover(data);
+          });
       });
     } else if (video.canPlayType("application/vnd.apple.mpegurl")) {
       // Native HLS support (Safari)
       console.log("Using native HLS support");
       video.src = src;
+      if (startAt && startAt > 0) {
+        video.currentTime = startAt;
+      }
     } else {
       console.error("HLS is not supported in this browser");
       setError("HLS is not supported in this browser");
@@ -99,6 +164,70 @@ export function VideoPlayer({ src, poster, className = "" }: VideoPlayerProps) {
     };
   }, [src]);
 
+  // Skip the intro and move on at the outro, each at most once per episode
+  useEffect(() => {
+    const video = videoRef.current;
+    if (!video) return;
+
+    let introSkipped = false;
+    let advanced = false;
+    setInIntro(false);
+    setInOutro(false);
+
+    const advance = () => {
+      if (advanced || !onNextEpisodeRef.current) return;
+      advanced = true;
+      onNextEpisodeRef.current();
+    };
+
+    const handleTimeUpdate = () => {
+      const position = video.currentTime;
+      const duration = video.duration;
+      onTimeUpdateRef.current?.(position, Number.isFinite(duration) ? duration : 0);
+      if (!markers) return;
+
+      const introEnd = markers.intro_end;
+      const intro =
+        introEnd != null && position >= (markers.intro_start ?? 0) && position < introEnd - 0.5;
+      if (intro && markers.auto_skip && !introSkipped) {
+        introSkipped = true;
+        video.currentTime = introEnd!;
+        setInIntro(false);
+      } else {
+        setInIntro(intro);
+      }
+
+      const outro =
+        markers.outro_start != null &&
+        Number.isFinite(duration) &&
+        duration > 0 &&
+        position >= duration - markers.outro_start &&
+        position < duration - (markers.outro_end ?? 0);
+      if (outro && markers.auto_advance) {
+        advance();
+      }
+      setInOutro(outro);
+    };
+
+    const handleEnded = () => {
+      if (markers?.auto_advance) advance();
+    };
+
+    video.addEventListener("timeupdate", handleTimeUpdate);
+    video.addEventListener("ended", handleEnded);
+    return () => {
+      video.removeEventListener("timeupdate", handleTimeUpdate);
+      video.removeEventListener("ended", handleEnded);
+    };
+  }, [src, markers]);
+
+  const skipIntro = () => {
+    const video = videoRef.current;
+    if (video && markers?.intro_end != null) {
+      video.currentTime = markers.intro_end;
+    }
+  };
+
   // Listen for fullscreen changes (both standard and webkit)
   useEffect(() => {
     const handleFullscreenChange = () => {
@@ -120,7 +249,8 @@ export function VideoPlayer({ src, poster, className = "" }: VideoPlayerProps) {
   }, []);
 
   const toggleFullscreen = async () => {
-    const video = videoRef.current;
+    // With bullet comments the whole player goes fullscreen so the overlay stays visible
+    const video = loadDanmaku ? containerRef.current : videoRef.current;
     if (!video) return;
 
     try {
@@ -183,9 +313,43 @@ export function VideoPlayer({ src, poster, className = "" }: VideoPlayerProps) {
             className="w-full h-full rounded-lg bg-black"
             playsInline
           >
+            {subtitles?.map((track, index) => (
+              <track
+                key={track.url}
+                kind="subtitles"
+                src={track.url}
+                label={track.label}
+                default={index === 0}
+              />
+            ))}
             Your browser does not support the video tag.
           </video>
 
+          {loadDanmaku && (
+            <DanmakuLayer videoRef={videoRef} load={loadDanmaku} posted={postedDanmaku} />
+          )}
+
+          {inIntro && (
+            <Button
+              variant="secondary"
+              className="absolute bottom-16 right-4 z-50 opacity-80 hover:opacity-100"
+              onClick={skipIntro}
+            >
+              <SkipForward className="h-4 w-4 mr-2" />
+              Skip intro
+            </Button>
+          )}
+          {inOutro && !markers?.auto_advance && onNextEpisode && (
+            <Button
+              variant="secondary"
+              className="absolute bottom-16 right-4 z-50 opacity-80 hover:opacity-100"
+              onClick={onNextEpisode}
+            >
+              <SkipForward className="h-4 w-4 mr-2" />
+              Next episode
+            </Button>
+          )}
+
           <Button
             variant="secondary"
             size="icon"
@@ -89,7 +89,7 @@ export function MyProfile() {
         setError(null);
 
         // Fetch user data
-        const userResponse = await GetCurrentUser(user.id);
+        const userResponse = await GetCurrentUser(user.session_token);
         if (userResponse.success && userResponse.data) {
           setUserData(userResponse.data as UserData);
         } else {
@@ -97,7 +97,7 @@ export function MyProfile() {
         }
 
         // Fetch user settings
-        const settingsResponse = await GetUserSettings(user.id);
+        const settingsResponse = await GetUserSettings(user.session_token);
         if (settingsResponse.success && settingsResponse.data) {
           setUserSettings(settingsResponse.data as UserSetting[]);
         } else {
@@ -169,10 +169,9 @@ export function MyProfile() {
     setEditLoading(true);
     try {
       const response = await UpdateSetting(
+        user.session_token,
         editingSetting.id,
-        editValue,
-        user.id,
-        userData?.user_role === "admin"
+        editValue
       );
 
       if (response.success) {
@@ -4,7 +4,11 @@ import { Loader2 } from "lucide-react";
 import { toast } from "sonner";
 import { MediaCard, type MediaItem } from "../mc-media-card";
 import { useUserStore } from "../../stores/user-store";
-import { parse_m3u8_urls } from "../../lib/media-utils";
+import {
+  filter_for_profile,
+  parse_m3u8_urls,
+  sources_from_m3u8_urls,
+} from "../../lib/media-utils";
 import {
   AddBookmark,
   RemoveBookmark,
@@ -32,8 +36,12 @@ export function RandomMedia() {
           return;
         }
 
+        const items = await filter_for_profile(
+          user?.session_token ?? "",
+          json.data?.items || []
+        );
         setRandomMediaItems(
-          (json.data?.items || []).map((item: any) => {
+          items.map((item: any) => {
             const m3u8_urls = parse_m3u8_urls(item.m3u8_urls);
 
             return {
@@ -56,14 +64,14 @@ export function RandomMedia() {
       }
     };
     fetchRandomMedia();
-  }, []);
+  }, [user?.session_token]);
 
   // Fetch user bookmarks
   useEffect(() => {
     const fetchBookmarks = async () => {
       if (!user?.id) return;
       try {
-        const response = await GetUserBookmarks(user.id);
+        const response = await GetUserBookmarks(user.session_token);
         if (response.success && response.data) {
           setBookmarks(new Set(response.data));
         }
@@ -84,7 +92,7 @@ export function RandomMedia() {
 
     try {
       if (isBookmarked) {
-        const response = await RemoveBookmark(user.id, mcId);
+        const response = await RemoveBookmark(user.session_token, mcId);
         if (response.success) {
           setBookmarks((prev) => {
             const newSet = new Set(prev);
@@ -101,9 +109,9 @@ export function RandomMedia() {
         if (media) {
           // Save media info to database first
           const year = media.year ? parseInt(media.year) : 0;
-          const m3u8UrlsStr = JSON.stringify(media.m3u8_urls || {});
 
           await SaveMediaInfo(
+            user.session_token,
             media.mc_id,
             media.title,
             "", // description
@@ -112,12 +120,12 @@ export function RandomMedia() {
             media.region || "",
             media.category || "",
             media.poster || "",
-            m3u8UrlsStr,
+            sources_from_m3u8_urls(media.m3u8_urls || {}),
             media.rating || 0
           );
         }
 
-        const response = await AddBookmark(user.id, mcId);
+        const response = await AddBookmark(user.session_token, mcId);
         if (response.success) {
           setBookmarks((prev) => new Set(prev).add(mcId));
           toast.success("收藏成功");
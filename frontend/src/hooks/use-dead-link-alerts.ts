@@ -0,0 +1,36 @@
+import { useEffect } from "react";
+import { toast } from "sonner";
+import { EventsOn } from "../../wailsjs/runtime/runtime";
+import { GetUserBookmarks } from "../../wailsjs/go/main/App";
+import { useUserStore } from "../stores/user-store";
+
+interface DeadLinksEvent {
+  titles: { mc_id: string; title: string; sources: number }[];
+  checked_at: string;
+}
+
+// Warns the signed-in user when the link checker finds bookmarked titles with no working source
+export function useDeadLinkAlerts() {
+  const user = useUserStore((state) => state.user);
+
+  useEffect(() => {
+    if (!user) return;
+
+    return EventsOn("media:dead-links", async (event: DeadLinksEvent) => {
+      try {
+        const response = await GetUserBookmarks(user.session_token);
+        if (!response.success || !response.data) return;
+
+        const bookmarked = new Set(response.data);
+        const dead = event.titles.filter((t) => bookmarked.has(t.mc_id));
+        if (dead.length === 0) return;
+
+        toast.warning(`${dead.length} 部收藏的影片所有线路均已失效`, {
+          description: dead.map((t) => t.title).join("、"),
+        });
+      } catch (error) {
+        console.error("Error handling dead link alert:", error);
+      }
+    });
+  }, [user]);
+}
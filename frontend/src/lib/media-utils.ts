@@ -1,3 +1,6 @@
+import { FilterMediaList } from "../../wailsjs/go/main/App";
+import { services } from "../../wailsjs/go/models";
+
 /**
  * Safely parses m3u8_urls which can be either a JSON string or an already-parsed object
  * @param m3u8_urls - The m3u8_urls data that can be a string, object, or undefined
@@ -27,3 +30,49 @@ export function parse_m3u8_urls(
   // Default to empty object
   return {};
 }
+
+/**
+ * Builds the source list SaveMediaInfo takes from a provider's m3u8_urls
+ * @param m3u8_urls - Episode label to playlist URL, in episode order
+ * @param name - Name the source is stored under; other stored sources are kept
+ * @returns One source holding every episode with a URL, or none if there are none
+ */
+export function sources_from_m3u8_urls(
+  m3u8_urls: Record<string, string>,
+  name = "default"
+): services.MediaSource[] {
+  const episodes = Object.entries(m3u8_urls)
+    .filter(([, url]) => !!url)
+    .map(([title, url], index) =>
+      services.Episode.createFrom({ season: 1, number: index + 1, title, url })
+    );
+  if (episodes.length === 0) {
+    return [];
+  }
+  return [
+    services.MediaSource.createFrom({
+      name,
+      type: "m3u8",
+      health: "unknown",
+      episodes,
+    }),
+  ];
+}
+
+/**
+ * Drops catalog or search results hidden by the active profile's parental controls
+ * @param sessionToken - The signed-in user's session token, or "" for a guest
+ * @param items - Raw result items as returned by the catalog API
+ * @returns The items the profile may see; throws if they could not be checked
+ */
+export async function filter_for_profile<T extends Record<string, any>>(
+  sessionToken: string,
+  items: T[]
+): Promise<T[]> {
+  const response = await FilterMediaList(sessionToken, items);
+  // Show nothing rather than everything when the check fails
+  if (!response.success) {
+    throw new Error(response.error || "Failed to apply parental controls");
+  }
+  return (response.data || []) as T[];
+}
@@ -85,6 +85,7 @@ function DatabaseManagement() {
   }, [isLoggedIn, user, navigate]);
 
   const fetchData = async () => {
+    if (!user) return;
     setLoading(true);
     setError(null);
 
@@ -92,10 +93,10 @@ function DatabaseManagement() {
       // Fetch all data in parallel
       const [tablesRes, migrationsRes, usersRes, settingsRes] =
         await Promise.all([
-          GetDatabaseTables(),
-          GetMigrations(),
-          GetAllUsers(),
-          GetAllSettings(),
+          GetDatabaseTables(user.session_token),
+          GetMigrations(user.session_token),
+          GetAllUsers(user.session_token),
+          GetAllSettings(user.session_token),
         ]);
 
       if (tablesRes.success) {
@@ -121,8 +122,9 @@ function DatabaseManagement() {
   };
 
   const handleOpenDirectory = async () => {
+    if (!user) return;
     try {
-      const result = await OpenDatabaseDirectory();
+      const result = await OpenDatabaseDirectory(user.session_token);
       if (!result.success) {
         setError(result.error || "Failed to open directory");
       }
@@ -16,5 +16,6 @@ export const Route = createFileRoute("/play")({
 declare module "@tanstack/react-router" {
   interface HistoryState {
     mediaItem?: MediaItem;
+    alternates?: MediaItem[]; // Same title from other sources, used for failover
   }
 }
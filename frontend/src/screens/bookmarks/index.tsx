@@ -4,7 +4,7 @@ import { Loader2, Bookmark as BookmarkIcon, RefreshCw } from "lucide-react";
 import { toast } from "sonner";
 import { MediaCard, type MediaItem } from "../../components/mc-media-card";
 import { useUserStore } from "../../stores/user-store";
-import { parse_m3u8_urls } from "../../lib/media-utils";
+import { parse_m3u8_urls, sources_from_m3u8_urls } from "../../lib/media-utils";
 import {
   RemoveBookmark,
   GetBookmarkedMediaDetails,
@@ -32,7 +32,7 @@ export function Bookmarks() {
 
       try {
         // Fetch bookmarked mc_ids
-        const bookmarkIdsResponse = await GetUserBookmarks(user.id);
+        const bookmarkIdsResponse = await GetUserBookmarks(user.session_token);
         if (!bookmarkIdsResponse.success || !bookmarkIdsResponse.data) {
           return;
         }
@@ -41,7 +41,7 @@ export function Bookmarks() {
         setBookmarks(new Set(mcIds));
 
         // Fetch from database
-        const dbResponse = await GetBookmarkedMediaDetails(user.id);
+        const dbResponse = await GetBookmarkedMediaDetails(user.session_token);
         const dbMediaMap = new Map<string, any>();
 
         if (dbResponse.success && dbResponse.data) {
@@ -131,9 +131,9 @@ export function Bookmarks() {
                 // Save to database
                 try {
                   const year = item.year || 0;
-                  const m3u8UrlsStr = JSON.stringify(m3u8_urls);
 
                   await SaveMediaInfo(
+                    user.session_token,
                     item.mc_id,
                     item.title || "未知",
                     item.summary || "",
@@ -142,7 +142,7 @@ export function Bookmarks() {
                     item.region || "",
                     item.category || "",
                     item.cover_image || "",
-                    m3u8UrlsStr,
+                    sources_from_m3u8_urls(m3u8_urls),
                     0 // rating
                   );
                 } catch (saveError) {
@@ -188,7 +188,7 @@ export function Bookmarks() {
     }
 
     try {
-      const response = await RemoveBookmark(user.id, mcId);
+      const response = await RemoveBookmark(user.session_token, mcId);
       if (response.success) {
         setBookmarks((prev) => {
           const newSet = new Set(prev);
@@ -1,6 +1,12 @@
 import { useState } from "react";
 import { useNavigate } from "@tanstack/react-router";
-import { Login as LoginAPI } from "../../../wailsjs/go/main/App";
+import { REGEXP_ONLY_DIGITS } from "input-otp";
+import {
+  ChangePassword,
+  Login as LoginAPI,
+  LoginWithSecondFactor,
+} from "../../../wailsjs/go/main/App";
+import { services } from "../../../wailsjs/go/models";
 import { useUserStore } from "../../stores/user-store";
 import {
   Card,
@@ -14,6 +20,11 @@ import { Input } from "../../components/ui/input";
 import { Button } from "../../components/ui/button";
 import { Label } from "../../components/ui/label";
 import { Alert, AlertDescription } from "../../components/ui/alert";
+import {
+  InputOTP,
+  InputOTPGroup,
+  InputOTPSlot,
+} from "../../components/ui/input-otp";
 import { Link } from "@tanstack/react-router";
 
 export function Login() {
@@ -21,9 +32,29 @@ export function Login() {
   const [password, setPassword] = useState("");
   const [error, setError] = useState("");
   const [loading, setLoading] = useState(false);
+  // Set once the password is accepted and a second factor is needed
+  const [challengeToken, setChallengeToken] = useState("");
+  const [code, setCode] = useState("");
+  const [useRecoveryCode, setUseRecoveryCode] = useState(false);
+  // Set when the account has a temporary password that must be replaced first
+  const [pendingUser, setPendingUser] = useState<services.User | null>(null);
+  const [newPassword, setNewPassword] = useState("");
+  const [confirmPassword, setConfirmPassword] = useState("");
   const navigate = useNavigate();
   const { login } = useUserStore();
 
+  const finishLogin = (user?: services.User) => {
+    if (!user?.session_token) return false;
+    if (user.must_change_password) {
+      setChallengeToken("");
+      setPendingUser(user);
+      return true;
+    }
+    login({ ...user, session_token: user.session_token });
+    navigate({ to: "/" });
+    return true;
+  };
+
   const handleSubmit = async (e: React.FormEvent) => {
     e.preventDefault();
     setError("");
@@ -31,10 +62,12 @@ export function Login() {
 
     try {
       const response = await LoginAPI(username, password);
-      if (response.success && response.data) {
-        login(response.data);
-        navigate({ to: "/" });
-      } else {
+      const user = response.data;
+      if (response.success && user?.two_factor_required && user.two_factor_token) {
+        setChallengeToken(user.two_factor_token);
+        setCode("");
+        setUseRecoveryCode(false);
+      } else if (!response.success || !finishLogin(user)) {
         setError(response.error || "登录失败");
       }
     } catch (err) {
@@ -45,6 +78,204 @@ export function Login() {
     }
   };
 
+  const handleVerify = async (e: React.FormEvent) => {
+    e.preventDefault();
+    setError("");
+    setLoading(true);
+
+    try {
+      const response = await LoginWithSecondFactor(challengeToken, code.trim());
+      if (!response.success || !finishLogin(response.data)) {
+        setError(response.error || "验证失败");
+      }
+    } catch (err) {
+      setError("验证时发生错误");
+      console.error(err);
+    } finally {
+      setLoading(false);
+    }
+  };
+
+  const handleChangePassword = async (e: React.FormEvent) => {
+    e.preventDefault();
+    setError("");
+
+    if (newPassword.length < 6) {
+      setError("密码至少需要6个字符");
+      return;
+    }
+    if (newPassword !== confirmPassword) {
+      setError("两次输入的密码不一致");
+      return;
+    }
+    if (!pendingUser?.session_token) return;
+
+    setLoading(true);
+    try {
+      const response = await ChangePassword(
+        pendingUser.session_token,
+        password,
+        newPassword
+      );
+      if (response.success) {
+        finishLogin({ ...pendingUser, must_change_password: false });
+      } else {
+        setError(response.error || "修改密码失败");
+      }
+    } catch (err) {
+      setError("修改密码时发生错误");
+      console.error(err);
+    } finally {
+      setLoading(false);
+    }
+  };
+
+  const handleBack = () => {
+    setChallengeToken("");
+    setCode("");
+    setPendingUser(null);
+    setNewPassword("");
+    setConfirmPassword("");
+    setPassword("");
+    setError("");
+  };
+
+  if (pendingUser) {
+    return (
+      <div className="flex items-center justify-center min-h-screen">
+        <Card className="w-full max-w-md">
+          <CardHeader>
+            <CardTitle>修改密码</CardTitle>
+            <CardDescription>你正在使用临时密码，请先设置一个新密码</CardDescription>
+          </CardHeader>
+          <form onSubmit={handleChangePassword} className="space-y-4">
+            <CardContent className="space-y-4">
+              {error && (
+                <Alert variant="destructive">
+                  <AlertDescription>{error}</AlertDescription>
+                </Alert>
+              )}
+              <div className="space-y-2">
+                <Label htmlFor="new-password">新密码</Label>
+                <Input
+                  id="new-password"
+                  type="password"
+                  placeholder="请输入新密码"
+                  value={newPassword}
+                  onChange={(e) => setNewPassword(e.target.value)}
+                  required
+                  disabled={loading}
+                />
+              </div>
+              <div className="space-y-2">
+                <Label htmlFor="confirm-password">确认新密码</Label>
+                <Input
+                  id="confirm-password"
+                  type="password"
+                  placeholder="请再次输入新密码"
+                  value={confirmPassword}
+                  onChange={(e) => setConfirmPassword(e.target.value)}
+                  required
+                  disabled={loading}
+                />
+              </div>
+            </CardContent>
+            <CardFooter className="flex flex-col space-y-2">
+              <Button type="submit" className="w-full" disabled={loading}>
+                {loading ? "保存中..." : "保存并登录"}
+              </Button>
+              <Button type="button" variant="ghost" onClick={handleBack} disabled={loading}>
+                返回
+              </Button>
+            </CardFooter>
+          </form>
+        </Card>
+      </div>
+    );
+  }
+
+  if (challengeToken) {
+    return (
+      <div className="flex items-center justify-center min-h-screen">
+        <Card className="w-full max-w-md">
+          <CardHeader>
+            <CardTitle>两步验证</CardTitle>
+            <CardDescription>
+              {useRecoveryCode
+                ? "请输入一个未使用过的恢复码"
+                : "请输入验证器应用中的6位验证码"}
+            </CardDescription>
+          </CardHeader>
+          <form onSubmit={handleVerify} className="space-y-4">
+            <CardContent className="space-y-4">
+              {error && (
+                <Alert variant="destructive">
+                  <AlertDescription>{error}</AlertDescription>
+                </Alert>
+              )}
+              {useRecoveryCode ? (
+                <div className="space-y-2">
+                  <Label htmlFor="recovery-code">恢复码</Label>
+                  <Input
+                    id="recovery-code"
+                    type="text"
+                    placeholder="请输入恢复码"
+                    value={code}
+                    onChange={(e) => setCode(e.target.value)}
+                    autoComplete="off"
+                    required
+                    disabled={loading}
+                  />
+                </div>
+              ) : (
+                <div className="flex justify-center">
+                  <InputOTP
+                    maxLength={6}
+                    pattern={REGEXP_ONLY_DIGITS}
+                    value={code}
+                    onChange={setCode}
+                    disabled={loading}
+                    autoFocus
+                  >
+                    <InputOTPGroup>
+                      {Array.from({ length: 6 }, (_, i) => (
+                        <InputOTPSlot key={i} index={i} />
+                      ))}
+                    </InputOTPGroup>
+                  </InputOTP>
+                </div>
+              )}
+            </CardContent>
+            <CardFooter className="flex flex-col space-y-2">
+              <Button
+                type="submit"
+                className="w-full"
+                disabled={loading || (!useRecoveryCode && code.length < 6)}
+              >
+                {loading ? "验证中..." : "验证"}
+              </Button>
+              <Button
+                type="button"
+                variant="link"
+                onClick={() => {
+                  setUseRecoveryCode(!useRecoveryCode);
+                  setCode("");
+                  setError("");
+                }}
+                disabled={loading}
+              >
+                {useRecoveryCode ? "使用验证码" : "无法使用验证器？使用恢复码"}
+              </Button>
+              <Button type="button" variant="ghost" onClick={handleBack} disabled={loading}>
+                返回
+              </Button>
+            </CardFooter>
+          </form>
+        </Card>
+      </div>
+    );
+  }
+
   return (
     <div className="flex items-center justify-center min-h-screen">
       <Card className="w-full max-w-md">
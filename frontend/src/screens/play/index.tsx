@@ -1,16 +1,83 @@
-import { useState, useEffect } from "react";
+import { useState, useEffect, useRef, useCallback } from "react";
 import { useNavigate, useLocation } from "@tanstack/react-router";
 import { Route } from "../../routes/play";
-import { ArrowLeft, Bookmark } from "lucide-react";
+import { ArrowLeft, Bookmark, Trash2, X } from "lucide-react";
 import { Button } from "../../components/ui/button";
-import { VideoPlayer } from "../../components/mc-video-player";
+import { VideoPlayer, type PlaybackError } from "../../components/mc-video-player";
 import { useMediaDetails } from "../../hooks/use-media-details";
 import { useUserStore } from "../../stores/user-store";
 import {
   AddBookmark,
   RemoveBookmark,
   IsBookmarked,
+  StartPlayback,
+  ReportPlaybackError,
+  EndPlayback,
+  SetSkipMarker,
+  ClearSkipMarkers,
+  ListSubtitles,
+  AttachSubtitle,
+  SetSubtitleOffset,
+  DeleteSubtitle,
+  GetDanmaku,
+  PostDanmaku,
+  ImportDanmaku,
+  ClearImportedDanmaku,
+  GetEffectiveSetting,
+  UpsertSetting,
 } from "../../../wailsjs/go/main/App";
+import { services } from "../../../wailsjs/go/models";
+
+// Play through the local proxy, which sanitizes playlists and reads segments ahead
+function proxied(url: string): string {
+  return `/proxy/hls?url=${encodeURIComponent(url)}`;
+}
+
+interface PlaybackSession {
+  id: string;
+  url: string;
+  startAt: number;
+}
+
+const markerButtons: { marker: string; label: string }[] = [
+  { marker: "intro_start", label: "Intro starts" },
+  { marker: "intro_end", label: "Intro ends" },
+  { marker: "outro_start", label: "Outro starts" },
+  { marker: "outro_end", label: "Outro ends" },
+];
+
+const danmakuModes: { mode: string; label: string }[] = [
+  { mode: "scroll", label: "Scroll" },
+  { mode: "top", label: "Top" },
+  { mode: "bottom", label: "Bottom" },
+];
+
+// Subtitle files may be GBK or Big5, so they go to the backend as raw bytes
+function readFileBase64(file: File): Promise<string> {
+  return new Promise((resolve, reject) => {
+    const reader = new FileReader();
+    reader.onload = () => resolve(String(reader.result).split(",")[1] || "");
+    reader.onerror = () => reject(reader.error);
+    reader.readAsDataURL(file);
+  });
+}
+
+function formatTime(seconds: number): string {
+  const s = Math.round(seconds);
+  return `${Math.floor(s / 60)}:${String(s % 60).padStart(2, "0")}`;
+}
+
+// Describes the markers, e.g. "Intro 0:00–1:30 · Outro from 2:05 before the end"
+function describeMarkers(markers: services.SkipMarkers): string {
+  const parts: string[] = [];
+  if (markers.intro_end != null) {
+    parts.push(`Intro ${formatTime(markers.intro_start ?? 0)}–${formatTime(markers.intro_end)}`);
+  }
+  if (markers.outro_start != null) {
+    parts.push(`Outro from ${formatTime(markers.outro_start)} before the end`);
+  }
+  return parts.join(" · ");
+}
 
 export function Play() {
   const navigate = useNavigate();
@@ -38,6 +105,35 @@ export function Play() {
     episodes.length > 0 ? episodes[0][0] : ""
   );
 
+  // Playback session, which moves to another source when the current one fails
+  const [session, setSession] = useState<PlaybackSession | null>(null);
+  const [playbackError, setPlaybackError] = useState("");
+  const [failoverNotice, setFailoverNotice] = useState("");
+
+  // Intro and outro markers of the series, and where the player currently is
+  const [markers, setMarkers] = useState<services.SkipMarkers | undefined>();
+  const [markerError, setMarkerError] = useState("");
+  const playerPosition = useRef({ position: 0, duration: 0 });
+
+  // Subtitles attached to the selected episode
+  const [subtitles, setSubtitles] = useState<services.SubtitleTrack[]>([]);
+  const [subtitleError, setSubtitleError] = useState("");
+  const subtitleInput = useRef<HTMLInputElement>(null);
+
+  // Bullet comments: whether they are shown, what is hidden and what the user posted
+  const [danmakuEnabled, setDanmakuEnabled] = useState(true);
+  const [danmakuFilter, setDanmakuFilter] = useState<services.DanmakuFilter>(
+    services.DanmakuFilter.createFrom({ keywords: [], users: [] })
+  );
+  const [danmakuVersion, setDanmakuVersion] = useState(0);
+  const [postedDanmaku, setPostedDanmaku] = useState<services.DanmakuComment[]>([]);
+  const [danmakuText, setDanmakuText] = useState("");
+  const [danmakuMode, setDanmakuMode] = useState("scroll");
+  const [blockInput, setBlockInput] = useState("");
+  const [danmakuNotice, setDanmakuNotice] = useState("");
+  const [danmakuError, setDanmakuError] = useState("");
+  const danmakuInput = useRef<HTMLInputElement>(null);
+
   // Bookmark state
   const [isBookmarked, setIsBookmarked] = useState(false);
   const [isBookmarking, setIsBookmarking] = useState(false);
@@ -47,7 +143,7 @@ export function Play() {
     const checkBookmarkStatus = async () => {
       if (user && mc_id) {
         try {
-          const response = await IsBookmarked(user.id, mc_id);
+          const response = await IsBookmarked(user.session_token, mc_id);
           if (response.success && response.data !== undefined) {
             setIsBookmarked(response.data);
           }
@@ -60,18 +156,334 @@ export function Play() {
     checkBookmarkStatus();
   }, [user, mc_id]);
 
+  // Start a playback session for the selected episode with every known source
+  useEffect(() => {
+    if (!mc_id || !mediaItem || !selectedEpisode) return;
+
+    let cancelled = false;
+    let sessionId = "";
+    const sources = [mediaItem, ...(location.state?.alternates || [])]
+      .filter((m) => m.m3u8_urls && Object.keys(m.m3u8_urls).length > 0)
+      .map((m) => ({ name: m.mc_id, urls: m.m3u8_urls! }));
+
+    setSession(null);
+    setPlaybackError("");
+    setFailoverNotice("");
+
+    StartPlayback(user?.session_token ?? "", mc_id, selectedEpisode, sources)
+      .then((response) => {
+        if (!response.success || !response.data) {
+          if (!cancelled) setPlaybackError(response.error || "Failed to start playback");
+          return;
+        }
+        sessionId = response.data.session_id;
+        if (cancelled) {
+          EndPlayback(sessionId);
+          return;
+        }
+        setSession({ id: sessionId, url: response.data.url, startAt: 0 });
+        setMarkers(response.data.markers);
+      })
+      .catch((error) => {
+        console.error("Error starting playback:", error);
+        if (!cancelled) setPlaybackError("Failed to start playback");
+      });
+
+    return () => {
+      cancelled = true;
+      if (sessionId) EndPlayback(sessionId);
+    };
+  }, [user, mc_id, mediaItem, selectedEpisode]);
+
+  const handlePlaybackError = async (error: PlaybackError): Promise<boolean> => {
+    if (!session) return false;
+    try {
+      const response = await ReportPlaybackError(session.id, error);
+      const next = response.data;
+      if (!response.success || !next) return false;
+
+      if (next.switch && next.url) {
+        console.log("Switching source:", next.reason);
+        setFailoverNotice("The source failed, switched to another one");
+        setSession({ id: session.id, url: next.url, startAt: next.resume_at });
+        return true;
+      }
+      if (next.exhausted) {
+        setFailoverNotice("All sources for this episode have failed");
+      }
+    } catch (err) {
+      console.error("Error reporting playback error:", err);
+    }
+    return false;
+  };
+
+  const episodeIndex = episodes.findIndex(([episode]) => episode === selectedEpisode);
+  const nextEpisode =
+    episodeIndex >= 0 && episodeIndex < episodes.length - 1 ? episodes[episodeIndex + 1][0] : "";
+
+  const handleSetMarker = async (marker: string) => {
+    if (!user || !mc_id) return;
+    const { position, duration } = playerPosition.current;
+    setMarkerError("");
+    try {
+      const response = await SetSkipMarker(user.session_token, mc_id, marker, position, duration);
+      if (response.success && response.data) {
+        setMarkers(response.data);
+      } else {
+        setMarkerError(response.error || "Failed to set marker");
+      }
+    } catch (err) {
+      console.error("Error setting marker:", err);
+      setMarkerError("Failed to set marker");
+    }
+  };
+
+  const handleClearMarkers = async () => {
+    if (!user || !mc_id) return;
+    setMarkerError("");
+    try {
+      const response = await ClearSkipMarkers(user.session_token, mc_id);
+      if (response.success && markers) {
+        setMarkers(
+          services.SkipMarkers.createFrom({
+            mc_id,
+            auto_skip: markers.auto_skip,
+            auto_advance: markers.auto_advance,
+          })
+        );
+      }
+    } catch (err) {
+      console.error("Error clearing markers:", err);
+    }
+  };
+
+  useEffect(() => {
+    if (!user || !mc_id || !selectedEpisode) {
+      setSubtitles([]);
+      return;
+    }
+    let cancelled = false;
+    setSubtitleError("");
+    ListSubtitles(user.session_token, mc_id, selectedEpisode)
+      .then((response) => {
+        if (!cancelled) setSubtitles(response.success && response.data ? response.data : []);
+      })
+      .catch((err) => console.error("Error loading subtitles:", err));
+    return () => {
+      cancelled = true;
+    };
+  }, [user, mc_id, selectedEpisode]);
+
+  const handleAttachSubtitle = async (file: File) => {
+    if (!user || !mc_id || !selectedEpisode) return;
+    setSubtitleError("");
+    try {
+      const content = await readFileBase64(file);
+      const response = await AttachSubtitle(user.session_token, mc_id, selectedEpisode, file.name, content);
+      if (response.success && response.data) {
+        setSubtitles([...subtitles, response.data]);
+      } else {
+        setSubtitleError(response.error || "Failed to add subtitle");
+      }
+    } catch (err) {
+      console.error("Error attaching subtitle:", err);
+      setSubtitleError("Failed to add subtitle");
+    }
+  };
+
+  const handleSubtitleOffset = async (track: services.SubtitleTrack, deltaMS: number) => {
+    if (!user) return;
+    setSubtitleError("");
+    try {
+      const response = await SetSubtitleOffset(user.session_token, track.id, track.offset_ms + deltaMS);
+      if (response.success && response.data) {
+        const updated = response.data;
+        setSubtitles(subtitles.map((t) => (t.id === updated.id ? updated : t)));
+      } else {
+        setSubtitleError(response.error || "Failed to adjust subtitle");
+      }
+    } catch (err) {
+      console.error("Error adjusting subtitle:", err);
+    }
+  };
+
+  const handleDeleteSubtitle = async (track: services.SubtitleTrack) => {
+    if (!user) return;
+    setSubtitleError("");
+    try {
+      const response = await DeleteSubtitle(user.session_token, track.id);
+      if (response.success) {
+        setSubtitles(subtitles.filter((t) => t.id !== track.id));
+      } else {
+        setSubtitleError(response.error || "Failed to remove subtitle");
+      }
+    } catch (err) {
+      console.error("Error removing subtitle:", err);
+    }
+  };
+
+  // Load the profile's danmaku settings
+  useEffect(() => {
+    if (!user) return;
+    GetEffectiveSetting(user.session_token, "danmaku_enabled")
+      .then((response) => {
+        if (response.success && response.data) setDanmakuEnabled(response.data.value === "true");
+      })
+      .catch((err) => console.error("Error loading danmaku setting:", err));
+    GetEffectiveSetting(user.session_token, "danmaku_filter")
+      .then((response) => {
+        if (response.success && response.data) {
+          setDanmakuFilter(services.DanmakuFilter.createFrom(response.data.value));
+        }
+      })
+      .catch((err) => console.error("Error loading danmaku filter:", err));
+  }, [user]);
+
+  useEffect(() => {
+    setPostedDanmaku([]);
+    setDanmakuNotice("");
+    setDanmakuError("");
+  }, [mc_id, selectedEpisode]);
+
+  // The saved filter is applied by the backend; a new function makes the player reload
+  const loadDanmaku = useCallback(
+    async (from: number, to: number): Promise<services.DanmakuComment[]> => {
+      if (!user || !mc_id || !selectedEpisode) return [];
+      const response = await GetDanmaku(
+        user.session_token,
+        mc_id,
+        selectedEpisode,
+        from,
+        to,
+        services.DanmakuFilter.createFrom({ keywords: [], users: [] })
+      );
+      return response.success && response.data ? response.data : [];
+    },
+    [user, mc_id, selectedEpisode, danmakuFilter, danmakuVersion]
+  );
+
+  const handleToggleDanmaku = async () => {
+    if (!user) return;
+    const enabled = !danmakuEnabled;
+    setDanmakuEnabled(enabled);
+    try {
+      await UpsertSetting(user.session_token, "danmaku_enabled", String(enabled), false);
+    } catch (err) {
+      console.error("Error saving danmaku setting:", err);
+    }
+  };
+
+  const handlePostDanmaku = async () => {
+    if (!user || !mc_id || !selectedEpisode || !danmakuText.trim()) return;
+    setDanmakuError("");
+    try {
+      const response = await PostDanmaku(
+        user.session_token,
+        mc_id,
+        selectedEpisode,
+        playerPosition.current.position,
+        danmakuText,
+        danmakuMode,
+        0xffffff
+      );
+      if (response.success && response.data) {
+        setPostedDanmaku([...postedDanmaku, response.data]);
+        setDanmakuText("");
+      } else {
+        setDanmakuError(response.error || "Failed to send danmaku");
+      }
+    } catch (err) {
+      console.error("Error posting danmaku:", err);
+      setDanmakuError("Failed to send danmaku");
+    }
+  };
+
+  const handleImportDanmaku = async (file: File) => {
+    if (!user || !mc_id || !selectedEpisode) return;
+    setDanmakuError("");
+    setDanmakuNotice("");
+    try {
+      const content = await readFileBase64(file);
+      const response = await ImportDanmaku(user.session_token, mc_id, selectedEpisode, file.name, content);
+      if (response.success && response.data) {
+        const { parsed, added } = response.data;
+        setDanmakuNotice(`Imported ${added} new of ${parsed} comments`);
+        setDanmakuVersion(danmakuVersion + 1);
+      } else {
+        setDanmakuError(response.error || "Failed to import danmaku");
+      }
+    } catch (err) {
+      console.error("Error importing danmaku:", err);
+      setDanmakuError("Failed to import danmaku");
+    }
+  };
+
+  const handleClearDanmaku = async () => {
+    if (!user || !mc_id || !selectedEpisode) return;
+    setDanmakuError("");
+    setDanmakuNotice("");
+    try {
+      const response = await ClearImportedDanmaku(user.session_token, mc_id, selectedEpisode);
+      if (response.success) {
+        setDanmakuNotice(`Removed ${response.data} imported comments`);
+        setDanmakuVersion(danmakuVersion + 1);
+      } else {
+        setDanmakuError(response.error || "Failed to clear danmaku");
+      }
+    } catch (err) {
+      console.error("Error clearing danmaku:", err);
+    }
+  };
+
+  const saveDanmakuFilter = async (filter: services.DanmakuFilter) => {
+    if (!user) return;
+    setDanmakuError("");
+    try {
+      const response = await UpsertSetting(user.session_token, "danmaku_filter", JSON.stringify(filter), false);
+      if (response.success) {
+        setDanmakuFilter(filter);
+      } else {
+        setDanmakuError(response.error || "Failed to save filter");
+      }
+    } catch (err) {
+      console.error("Error saving danmaku filter:", err);
+    }
+  };
+
+  // Entries starting with @ block an author, anything else is a keyword
+  const handleBlock = () => {
+    const entry = blockInput.trim();
+    if (!entry) return;
+    const keywords = danmakuFilter.keywords || [];
+    const users = danmakuFilter.users || [];
+    const filter = entry.startsWith("@")
+      ? { keywords, users: [...users, entry.slice(1)] }
+      : { keywords: [...keywords, entry], users };
+    saveDanmakuFilter(services.DanmakuFilter.createFrom(filter));
+    setBlockInput("");
+  };
+
+  const handleUnblock = (kind: "keywords" | "users", entry: string) => {
+    saveDanmakuFilter(
+      services.DanmakuFilter.createFrom({
+        ...danmakuFilter,
+        [kind]: (danmakuFilter[kind] || []).filter((e) => e !== entry),
+      })
+    );
+  };
+
   const handleBookmarkToggle = async () => {
     if (!user || !mc_id || isBookmarking) return;
 
     setIsBookmarking(true);
     try {
       if (isBookmarked) {
-        const response = await RemoveBookmark(user.id, mc_id);
+        const response = await RemoveBookmark(user.session_token, mc_id);
         if (response.success) {
           setIsBookmarked(false);
         }
       } else {
-        const response = await AddBookmark(user.id, mc_id);
+        const response = await AddBookmark(user.session_token, mc_id);
         if (response.success) {
           setIsBookmarked(true);
         }
@@ -123,13 +535,6 @@ export function Play() {
     );
   }
 
-  let selectedUrl = episodes.find(([ep]) => ep === selectedEpisode)?.[1] || "";
-
-  // Normalize URL: append /index.m3u8 if not already present
-  if (selectedUrl && !selectedUrl.endsWith(".m3u8")) {
-    selectedUrl = selectedUrl + "/index.m3u8";
-  }
-
   return (
     <div className="px-4 sm:px-10 py-4 sm:py-8 w-full min-h-full text-black">
       <div className="flex items-center gap-4 mb-6">
@@ -157,16 +562,200 @@ export function Play() {
       </div>
 
       <div className="space-y-6">
-        {selectedUrl ? (
+        {session ? (
           <VideoPlayer
             key={selectedEpisode}
-            src={selectedUrl.trim()}
+            src={proxied(session.url)}
+            startAt={session.startAt}
+            onPlaybackError={handlePlaybackError}
+            markers={markers}
+            subtitles={subtitles}
+            loadDanmaku={danmakuEnabled ? loadDanmaku : undefined}
+            postedDanmaku={postedDanmaku}
+            onNextEpisode={nextEpisode ? () => setSelectedEpisode(nextEpisode) : undefined}
+            onTimeUpdate={(position, duration) => {
+              playerPosition.current = { position, duration };
+            }}
             poster={mediaItem?.poster}
             className="aspect-video max-w-5xl"
           />
         ) : (
           <div className="aspect-video max-w-5xl bg-muted rounded-lg flex items-center justify-center">
-            <p className="text-muted-foreground">No video available</p>
+            <p className={playbackError ? "text-destructive" : "text-muted-foreground"}>
+              {playbackError || (selectedEpisode ? "Loading..." : "No video available")}
+            </p>
+          </div>
+        )}
+
+        {failoverNotice && (
+          <p className="max-w-5xl text-sm text-muted-foreground">{failoverNotice}</p>
+        )}
+
+        {user && session && (
+          <div className="max-w-5xl flex flex-wrap items-center gap-2">
+            <span className="text-sm text-muted-foreground">Mark at current position:</span>
+            {markerButtons.map(({ marker, label }) => (
+              <Button
+                key={marker}
+                variant="outline"
+                size="sm"
+                onClick={() => handleSetMarker(marker)}
+              >
+                {label}
+              </Button>
+            ))}
+            {markers && describeMarkers(markers) && (
+              <>
+                <span className="text-sm text-muted-foreground">{describeMarkers(markers)}</span>
+                <Button variant="ghost" size="sm" onClick={handleClearMarkers}>
+                  Clear
+                </Button>
+              </>
+            )}
+            {markerError && <span className="text-sm text-destructive">{markerError}</span>}
+          </div>
+        )}
+
+        {user && selectedEpisode && (
+          <div className="max-w-5xl space-y-2">
+            <div className="flex flex-wrap items-center gap-2">
+              <span className="text-sm text-muted-foreground">Subtitles:</span>
+              <Button variant="outline" size="sm" onClick={() => subtitleInput.current?.click()}>
+                Add subtitle file
+              </Button>
+              <input
+                ref={subtitleInput}
+                type="file"
+                accept=".srt,.ass,.ssa,.vtt"
+                className="hidden"
+                onChange={(e) => {
+                  const file = e.target.files?.[0];
+                  if (file) handleAttachSubtitle(file);
+                  e.target.value = "";
+                }}
+              />
+              {subtitleError && <span className="text-sm text-destructive">{subtitleError}</span>}
+            </div>
+            {subtitles.map((track) => (
+              <div key={track.id} className="flex flex-wrap items-center gap-2 text-sm">
+                <span className="font-medium">{track.label}</span>
+                <span className="text-muted-foreground">
+                  {track.format.toUpperCase()} · {track.charset} · offset{" "}
+                  {(track.offset_ms / 1000).toFixed(1)}s
+                </span>
+                <Button variant="ghost" size="sm" onClick={() => handleSubtitleOffset(track, -500)}>
+                  -0.5s
+                </Button>
+                <Button variant="ghost" size="sm" onClick={() => handleSubtitleOffset(track, 500)}>
+                  +0.5s
+                </Button>
+                <Button
+                  variant="ghost"
+                  size="icon"
+                  onClick={() => handleDeleteSubtitle(track)}
+                  title="Remove subtitle"
+                >
+                  <Trash2 className="h-4 w-4" />
+                </Button>
+              </div>
+            ))}
+          </div>
+        )}
+
+        {user && selectedEpisode && (
+          <div className="max-w-5xl space-y-2">
+            <div className="flex flex-wrap items-center gap-2">
+              <span className="text-sm text-muted-foreground">Danmaku:</span>
+              <Button variant="outline" size="sm" onClick={handleToggleDanmaku}>
+                {danmakuEnabled ? "Hide" : "Show"}
+              </Button>
+              <input
+                value={danmakuText}
+                onChange={(e) => setDanmakuText(e.target.value)}
+                onKeyDown={(e) => {
+                  if (e.key === "Enter") handlePostDanmaku();
+                }}
+                maxLength={100}
+                placeholder="Send a comment at the current position"
+                className="h-8 w-64 rounded-md border px-2 text-sm"
+              />
+              <select
+                value={danmakuMode}
+                onChange={(e) => setDanmakuMode(e.target.value)}
+                className="h-8 rounded-md border px-2 text-sm"
+              >
+                {danmakuModes.map(({ mode, label }) => (
+                  <option key={mode} value={mode}>
+                    {label}
+                  </option>
+                ))}
+              </select>
+              <Button
+                size="sm"
+                onClick={handlePostDanmaku}
+                disabled={!danmakuText.trim() || !session}
+              >
+                Send
+              </Button>
+              <Button variant="outline" size="sm" onClick={() => danmakuInput.current?.click()}>
+                Import file
+              </Button>
+              <Button variant="ghost" size="sm" onClick={handleClearDanmaku}>
+                Clear imported
+              </Button>
+              <input
+                ref={danmakuInput}
+                type="file"
+                accept=".xml,.json"
+                className="hidden"
+                onChange={(e) => {
+                  const file = e.target.files?.[0];
+                  if (file) handleImportDanmaku(file);
+                  e.target.value = "";
+                }}
+              />
+              {danmakuNotice && (
+                <span className="text-sm text-muted-foreground">{danmakuNotice}</span>
+              )}
+              {danmakuError && <span className="text-sm text-destructive">{danmakuError}</span>}
+            </div>
+            <div className="flex flex-wrap items-center gap-2 text-sm">
+              <span className="text-muted-foreground">Hide:</span>
+              {(danmakuFilter.keywords || []).map((keyword) => (
+                <Button
+                  key={`k:${keyword}`}
+                  variant="secondary"
+                  size="sm"
+                  onClick={() => handleUnblock("keywords", keyword)}
+                >
+                  {keyword}
+                  <X className="h-3 w-3 ml-1" />
+                </Button>
+              ))}
+              {(danmakuFilter.users || []).map((author) => (
+                <Button
+                  key={`u:${author}`}
+                  variant="secondary"
+                  size="sm"
+                  onClick={() => handleUnblock("users", author)}
+                >
+                  @{author}
+                  <X className="h-3 w-3 ml-1" />
+                </Button>
+              ))}
+              <input
+                value={blockInput}
+                onChange={(e) => setBlockInput(e.target.value)}
+                onKeyDown={(e) => {
+                  if (e.key === "Enter") handleBlock();
+                }}
+                placeholder="Keyword, or @author"
+                className="h-8 w-48 rounded-md border px-2 text-sm"
+              />
+              <Button variant="ghost" size="sm" onClick={handleBlock} disabled={!blockInput.trim()}>
+                Hide
+              </Button>
+            </div>
           </div>
         )}
 
@@ -6,7 +6,11 @@ import { toast } from "sonner";
 import { McSearchBar } from "../../components/mc-search-bar";
 import { MediaCard, type MediaItem } from "../../components/mc-media-card";
 import { useUserStore } from "../../stores/user-store";
-import { parse_m3u8_urls } from "../../lib/media-utils";
+import {
+  filter_for_profile,
+  parse_m3u8_urls,
+  sources_from_m3u8_urls,
+} from "../../lib/media-utils";
 import {
   AddBookmark,
   RemoveBookmark,
@@ -74,8 +78,12 @@ export function Search() {
       //   }
       // }
 
+      const items = await filter_for_profile(
+        user?.session_token ?? "",
+        json.data?.items || []
+      );
       setResults(
-        (json.data?.items || []).map((item: any) => {
+        items.map((item: any) => {
           const m3u8_urls = parse_m3u8_urls(item.m3u8_urls);
 
           return {
@@ -112,8 +120,12 @@ export function Search() {
         return;
       }
 
+      const items = await filter_for_profile(
+        user?.session_token ?? "",
+        json.data?.items || []
+      );
       setResults(
-        (json.data?.items || []).map((item: any) => {
+        items.map((item: any) => {
           const m3u8_urls = parse_m3u8_urls(item.m3u8_urls);
 
           return {
@@ -141,7 +153,7 @@ export function Search() {
     const fetchBookmarks = async () => {
       if (!user?.id) return;
       try {
-        const response = await GetUserBookmarks(user.id);
+        const response = await GetUserBookmarks(user.session_token);
         if (response.success && response.data) {
           setBookmarks(new Set(response.data));
         }
@@ -185,7 +197,7 @@ export function Search() {
 
     try {
       if (isBookmarked) {
-        const response = await RemoveBookmark(user.id, mcId);
+        const response = await RemoveBookmark(user.session_token, mcId);
         if (response.success) {
           setBookmarks((prev) => {
             const newSet = new Set(prev);
@@ -202,9 +214,9 @@ export function Search() {
         if (media) {
           // Save media info to database first
           const year = media.year ? parseInt(media.year) : 0;
-          const m3u8UrlsStr = JSON.stringify(media.m3u8_urls || {});
 
           await SaveMediaInfo(
+            user.session_token,
             media.mc_id,
             media.title,
             "", // description
@@ -213,12 +225,12 @@ export function Search() {
             media.region || "",
             media.category || "",
             media.poster || "",
-            m3u8UrlsStr,
+            sources_from_m3u8_urls(media.m3u8_urls || {}),
             media.rating || 0
           );
         }
 
-        const response = await AddBookmark(user.id, mcId);
+        const response = await AddBookmark(user.session_token, mcId);
         if (response.success) {
           setBookmarks((prev) => new Set(prev).add(mcId));
           toast.success("收藏成功");
@@ -290,7 +302,12 @@ export function Search() {
                 navigate({
                   to: "/play",
                   search: { mc_id: result.mc_id },
-                  state: { mediaItem: result },
+                  state: {
+                    mediaItem: result,
+                    alternates: results.filter(
+                      (r) => r.mc_id !== result.mc_id && r.title === result.title
+                    ),
+                  },
                 });
               }}
               isBookmarked={bookmarks.has(result.mc_id)}
@@ -1,6 +1,10 @@
-import { useState } from "react";
+import { useEffect, useState } from "react";
 import { useNavigate } from "@tanstack/react-router";
-import { Signup as SignupAPI } from "../../../wailsjs/go/main/App";
+import {
+  NeedsSetup,
+  SetupAdmin,
+  Signup as SignupAPI,
+} from "../../../wailsjs/go/main/App";
 import { useUserStore } from "../../stores/user-store";
 import {
   Card,
@@ -21,11 +25,20 @@ export function Signup() {
   const [email, setEmail] = useState("");
   const [password, setPassword] = useState("");
   const [confirmPassword, setConfirmPassword] = useState("");
+  const [inviteCode, setInviteCode] = useState("");
   const [error, setError] = useState("");
   const [loading, setLoading] = useState(false);
+  const [needsSetup, setNeedsSetup] = useState(false);
   const navigate = useNavigate();
   const { login } = useUserStore();
 
+  // On first run the form creates the admin account instead
+  useEffect(() => {
+    NeedsSetup()
+      .then((response) => setNeedsSetup(!!response.success && !!response.data))
+      .catch(console.error);
+  }, []);
+
   const handleSubmit = async (e: React.FormEvent) => {
     e.preventDefault();
     setError("");
@@ -43,10 +56,13 @@ export function Signup() {
     setLoading(true);
 
     try {
-      const response = await SignupAPI(username, email, password);
-      if (response.success && response.data) {
+      const response = needsSetup
+        ? await SetupAdmin(username, email, password)
+        : await SignupAPI(username, email, password, inviteCode);
+      const user = response.data;
+      if (response.success && user?.session_token) {
         // Auto-login after successful signup
-        login(response.data);
+        login({ ...user, session_token: user.session_token });
         navigate({ to: "/" });
       } else {
         setError(response.error || "注册失败");
@@ -63,8 +79,10 @@ export function Signup() {
     <div className="flex items-center justify-center min-h-screen">
       <Card className="w-full max-w-md">
         <CardHeader>
-          <CardTitle>注册</CardTitle>
-          <CardDescription>创建您的月饼TV账号</CardDescription>
+          <CardTitle>{needsSetup ? "初始设置" : "注册"}</CardTitle>
+          <CardDescription>
+            {needsSetup ? "创建月饼TV管理员账号" : "创建您的月饼TV账号"}
+          </CardDescription>
         </CardHeader>
         <form onSubmit={handleSubmit} className="space-y-4">
           <CardContent className="space-y-4">
@@ -121,6 +139,19 @@ export function Signup() {
                 disabled={loading}
               />
             </div>
+            {!needsSetup && (
+              <div className="space-y-2">
+                <Label htmlFor="inviteCode">邀请码</Label>
+                <Input
+                  id="inviteCode"
+                  type="text"
+                  placeholder="如有邀请码请填写"
+                  value={inviteCode}
+                  onChange={(e) => setInviteCode(e.target.value)}
+                  disabled={loading}
+                />
+              </div>
+            )}
           </CardContent>
           <CardFooter className="flex flex-col space-y-2">
             <Button type="submit" className="w-full" disabled={loading}>
@@ -7,6 +7,7 @@ interface User {
   email: string;
   user_role: string;
   meta_data?: string | null;
+  session_token: string;
   created_at: string;
   updated_at: string;
 }
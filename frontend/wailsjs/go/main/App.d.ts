@@ -1,39 +1,178 @@
 // Cynhyrchwyd y ffeil hon yn awtomatig. PEIDIWCH Â MODIWL
 // This file is automatically generated. DO NOT EDIT
 import {models} from '../models';
+import {services} from '../models';
 
-export function AddBookmark(arg1:number,arg2:string):Promise<models.APIResponse_bool_>;
+export function AddBookmark(arg1:string,arg2:string):Promise<models.APIResponse_bool_>;
 
-export function DeleteMediaInfo(arg1:string):Promise<models.APIResponse_bool_>;
+export function AttachSubtitle(arg1:string,arg2:string,arg3:string,arg4:string,arg5:string):Promise<models.APIResponse_mooncaketv_services_SubtitleTrack_>;
 
-export function DeleteSetting(arg1:number,arg2:number,arg3:boolean):Promise<models.APIResponse_bool_>;
+export function BeginTOTPEnrollment(arg1:string):Promise<models.APIResponse_mooncaketv_services_TOTPEnrollment_>;
 
-export function GetAllSettings():Promise<models.APIResponse___map_string_interface____>;
+export function CancelRestoreBackup(arg1:string):Promise<models.APIResponse_bool_>;
 
-export function GetAllUsers():Promise<models.APIResponse___map_string_interface____>;
+export function ChangeEmail(arg1:string,arg2:string,arg3:string):Promise<models.APIResponse_bool_>;
 
-export function GetBookmarkedMediaDetails(arg1:number):Promise<models.APIResponse___map_string_interface____>;
+export function ChangePassword(arg1:string,arg2:string,arg3:string):Promise<models.APIResponse_bool_>;
 
-export function GetCurrentUser(arg1:number):Promise<models.APIResponse_map_string_interface____>;
+export function ChangeUsername(arg1:string,arg2:string,arg3:string):Promise<models.APIResponse_bool_>;
 
-export function GetDatabaseTables():Promise<models.APIResponse___string_>;
+export function CheckBookmarkLinks(arg1:string):Promise<models.APIResponse_mooncaketv_services_LinkCheckReport_>;
 
-export function GetMigrations():Promise<models.APIResponse___map_string_interface____>;
+export function CheckPlayback(arg1:string,arg2:string):Promise<models.APIResponse_bool_>;
 
-export function GetUserBookmarks(arg1:number):Promise<models.APIResponse___string_>;
+export function ClearImportedDanmaku(arg1:string,arg2:string,arg3:string):Promise<models.APIResponse_int64_>;
 
-export function GetUserSettings(arg1:number):Promise<models.APIResponse___map_string_interface____>;
+export function ClearSkipMarkers(arg1:string,arg2:string):Promise<models.APIResponse_bool_>;
 
-export function IsBookmarked(arg1:number,arg2:string):Promise<models.APIResponse_bool_>;
+export function ConfirmTOTPEnrollment(arg1:string,arg2:string):Promise<models.APIResponse___string_>;
+
+export function CreateBackup(arg1:string):Promise<models.APIResponse_mooncaketv_services_BackupSnapshot_>;
+
+export function CreateInviteCode(arg1:string,arg2:string,arg3:number,arg4:number):Promise<models.APIResponse_mooncaketv_services_InviteCode_>;
+
+export function CreateProfile(arg1:string,arg2:string,arg3:string,arg4:string,arg5:string):Promise<models.APIResponse_mooncaketv_services_Profile_>;
+
+export function DeleteAccount(arg1:string,arg2:string):Promise<models.APIResponse_bool_>;
+
+export function DeleteDanmaku(arg1:string,arg2:number):Promise<models.APIResponse_bool_>;
+
+export function DeleteMediaInfo(arg1:string,arg2:string):Promise<models.APIResponse_bool_>;
+
+export function DeleteProfile(arg1:string,arg2:number,arg3:string,arg4:string):Promise<models.APIResponse_bool_>;
+
+export function DeleteRole(arg1:string,arg2:string):Promise<models.APIResponse_bool_>;
+
+export function DeleteSetting(arg1:string,arg2:number):Promise<models.APIResponse_bool_>;
+
+export function DeleteSubtitle(arg1:string,arg2:number):Promise<models.APIResponse_bool_>;
+
+export function DeleteUser(arg1:string,arg2:number):Promise<models.APIResponse_bool_>;
+
+export function DisableTOTP(arg1:string,arg2:string):Promise<models.APIResponse_bool_>;
+
+export function EndPlayback(arg1:string):Promise<models.APIResponse_bool_>;
+
+export function ExportUserData(arg1:string):Promise<models.APIResponse_string_>;
+
+export function FilterMediaList(arg1:string,arg2:Array<Record<string, any>>):Promise<models.APIResponse___map_string_interface____>;
+
+export function GetAllSettings(arg1:string):Promise<models.APIResponse___map_string_interface____>;
+
+export function GetAllUsers(arg1:string):Promise<models.APIResponse___map_string_interface____>;
+
+export function GetAuditLog(arg1:string,arg2:services.AuditQuery):Promise<models.APIResponse_mooncaketv_services_AuditPage_>;
+
+export function GetAuthEvents(arg1:string,arg2:string,arg3:number,arg4:number):Promise<models.APIResponse___mooncaketv_services_AuthEvent_>;
+
+export function GetBookmarkedMediaDetails(arg1:string):Promise<models.APIResponse___map_string_interface____>;
+
+export function GetBrowsableTables(arg1:string):Promise<models.APIResponse___mooncaketv_services_TableInfo_>;
+
+export function GetCurrentUser(arg1:string):Promise<models.APIResponse_map_string_interface____>;
+
+export function GetDanmaku(arg1:string,arg2:string,arg3:string,arg4:number,arg5:number,arg6:services.DanmakuFilter):Promise<models.APIResponse___mooncaketv_services_DanmakuComment_>;
+
+export function GetDatabaseTables(arg1:string):Promise<models.APIResponse___string_>;
+
+export function GetEffectiveSetting(arg1:string,arg2:string):Promise<models.APIResponse_mooncaketv_services_EffectiveSetting_>;
+
+export function GetLoginLockouts(arg1:string):Promise<models.APIResponse___mooncaketv_services_LoginLockout_>;
+
+export function GetMigrations(arg1:string):Promise<models.APIResponse___map_string_interface____>;
+
+export function GetParentalRules(arg1:string,arg2:number):Promise<models.APIResponse_mooncaketv_services_ParentalRules_>;
+
+export function GetPermissions(arg1:string):Promise<models.APIResponse___string_>;
+
+export function GetSettingDefinitions():Promise<models.APIResponse___mooncaketv_services_SettingDefinition_>;
+
+export function GetSkipMarkers(arg1:string,arg2:string):Promise<models.APIResponse_mooncaketv_services_SkipMarkers_>;
+
+export function GetSourceReport(arg1:string):Promise<models.APIResponse___mooncaketv_services_HostReport_>;
+
+export function GetSourceStatus(arg1:string,arg2:string):Promise<models.APIResponse___mooncaketv_services_SourceCheck_>;
+
+export function GetUserBookmarks(arg1:string):Promise<models.APIResponse___string_>;
+
+export function GetUserSettings(arg1:string):Promise<models.APIResponse___map_string_interface____>;
+
+export function HasPendingRestore(arg1:string):Promise<models.APIResponse_bool_>;
+
+export function ImportDanmaku(arg1:string,arg2:string,arg3:string,arg4:string,arg5:string):Promise<models.APIResponse_mooncaketv_services_DanmakuImportResult_>;
+
+export function ImportUserData(arg1:string,arg2:string,arg3:string):Promise<models.APIResponse_mooncaketv_services_ImportReport_>;
+
+export function IsBookmarked(arg1:string,arg2:string):Promise<models.APIResponse_bool_>;
+
+export function ListBackups(arg1:string):Promise<models.APIResponse___mooncaketv_services_BackupSnapshot_>;
+
+export function ListInviteCodes(arg1:string):Promise<models.APIResponse___mooncaketv_services_InviteCode_>;
+
+export function ListPermissions():Promise<models.APIResponse___mooncaketv_services_PermissionInfo_>;
+
+export function ListProfiles(arg1:string):Promise<models.APIResponse___mooncaketv_services_Profile_>;
+
+export function ListRoles(arg1:string):Promise<models.APIResponse___mooncaketv_services_Role_>;
+
+export function ListSubtitles(arg1:string,arg2:string,arg3:string):Promise<models.APIResponse___mooncaketv_services_SubtitleTrack_>;
 
 export function Login(arg1:string,arg2:string):Promise<models.APIResponse_mooncaketv_services_User_>;
 
-export function OpenDatabaseDirectory():Promise<models.APIResponse_string_>;
+export function LoginWithSecondFactor(arg1:string,arg2:string):Promise<models.APIResponse_mooncaketv_services_User_>;
+
+export function Logout(arg1:string):Promise<models.APIResponse_bool_>;
+
+export function NeedsSetup():Promise<models.APIResponse_bool_>;
+
+export function OpenDatabaseDirectory(arg1:string):Promise<models.APIResponse_string_>;
+
+export function PostDanmaku(arg1:string,arg2:string,arg3:string,arg4:number,arg5:string,arg6:string,arg7:number):Promise<models.APIResponse_mooncaketv_services_DanmakuComment_>;
+
+export function QueryTableRows(arg1:string,arg2:services.TableQuery):Promise<models.APIResponse_mooncaketv_services_TablePage_>;
+
+export function RegenerateRecoveryCodes(arg1:string,arg2:string):Promise<models.APIResponse___string_>;
+
+export function RemoveBookmark(arg1:string,arg2:string):Promise<models.APIResponse_bool_>;
+
+export function ReportPlaybackError(arg1:string,arg2:services.PlaybackErrorReport):Promise<models.APIResponse_mooncaketv_services_PlaybackSwitch_>;
+
+export function RestoreBackup(arg1:string,arg2:string):Promise<models.APIResponse_bool_>;
+
+export function RevokeInviteCode(arg1:string,arg2:number):Promise<models.APIResponse_bool_>;
+
+export function SaveMediaInfo(arg1:string,arg2:string,arg3:string,arg4:string,arg5:number,arg6:string,arg7:string,arg8:string,arg9:string,arg10:Array<services.MediaSource>,arg11:number):Promise<models.APIResponse_bool_>;
+
+export function SaveRole(arg1:string,arg2:string,arg3:string,arg4:Array<string>):Promise<models.APIResponse_bool_>;
+
+export function SetMediaContentRating(arg1:string,arg2:string,arg3:string):Promise<models.APIResponse_bool_>;
+
+export function SetParentalPIN(arg1:string,arg2:string,arg3:string):Promise<models.APIResponse_bool_>;
+
+export function SetParentalRules(arg1:string,arg2:number,arg3:services.ParentalRules,arg4:string):Promise<models.APIResponse_bool_>;
+
+export function SetSkipMarker(arg1:string,arg2:string,arg3:string,arg4:number,arg5:number):Promise<models.APIResponse_mooncaketv_services_SkipMarkers_>;
+
+export function SetSubtitleOffset(arg1:string,arg2:number,arg3:number):Promise<models.APIResponse_mooncaketv_services_SubtitleTrack_>;
+
+export function SetTemporaryPassword(arg1:string,arg2:number):Promise<models.APIResponse_string_>;
+
+export function SetUserDisabled(arg1:string,arg2:number,arg3:boolean):Promise<models.APIResponse_bool_>;
+
+export function SetUserRole(arg1:string,arg2:number,arg3:string):Promise<models.APIResponse_bool_>;
+
+export function SetupAdmin(arg1:string,arg2:string,arg3:string):Promise<models.APIResponse_mooncaketv_services_User_>;
+
+export function Signup(arg1:string,arg2:string,arg3:string,arg4:string):Promise<models.APIResponse_mooncaketv_services_User_>;
+
+export function StartPlayback(arg1:string,arg2:string,arg3:string,arg4:Array<services.PlaybackSource>):Promise<models.APIResponse_mooncaketv_services_PlaybackState_>;
+
+export function SwitchProfile(arg1:string,arg2:number,arg3:string,arg4:string):Promise<models.APIResponse_mooncaketv_services_Profile_>;
 
-export function RemoveBookmark(arg1:number,arg2:string):Promise<models.APIResponse_bool_>;
+export function UnlockAccount(arg1:string,arg2:string):Promise<models.APIResponse_bool_>;
 
-export function SaveMediaInfo(arg1:string,arg2:string,arg3:string,arg4:number,arg5:string,arg6:string,arg7:string,arg8:string,arg9:string,arg10:number):Promise<models.APIResponse_bool_>;
+export function UpdateSetting(arg1:string,arg2:number,arg3:string):Promise<models.APIResponse_bool_>;
 
-export function Signup(arg1:string,arg2:string,arg3:string):Promise<models.APIResponse_mooncaketv_services_User_>;
+export function UpsertSetting(arg1:string,arg2:string,arg3:string,arg4:boolean):Promise<models.APIResponse_bool_>;
 
-export function UpdateSetting(arg1:number,arg2:string,arg3:number,arg4:boolean):Promise<models.APIResponse_bool_>;
+export function ValidateSession(arg1:string):Promise<models.APIResponse_mooncaketv_services_User_>;
@@ -6,36 +6,180 @@ export function AddBookmark(arg1, arg2) {
   return window['go']['main']['App']['AddBookmark'](arg1, arg2);
 }
 
-export function DeleteMediaInfo(arg1) {
-  return window['go']['main']['App']['DeleteMediaInfo'](arg1);
+export function AttachSubtitle(arg1, arg2, arg3, arg4, arg5) {
+  return window['go']['main']['App']['AttachSubtitle'](arg1, arg2, arg3, arg4, arg5);
 }
 
-export function DeleteSetting(arg1, arg2, arg3) {
-  return window['go']['main']['App']['DeleteSetting'](arg1, arg2, arg3);
+export function BeginTOTPEnrollment(arg1) {
+  return window['go']['main']['App']['BeginTOTPEnrollment'](arg1);
 }
 
-export function GetAllSettings() {
-  return window['go']['main']['App']['GetAllSettings']();
+export function CancelRestoreBackup(arg1) {
+  return window['go']['main']['App']['CancelRestoreBackup'](arg1);
 }
 
-export function GetAllUsers() {
-  return window['go']['main']['App']['GetAllUsers']();
+export function ChangeEmail(arg1, arg2, arg3) {
+  return window['go']['main']['App']['ChangeEmail'](arg1, arg2, arg3);
+}
+
+export function ChangePassword(arg1, arg2, arg3) {
+  return window['go']['main']['App']['ChangePassword'](arg1, arg2, arg3);
+}
+
+export function ChangeUsername(arg1, arg2, arg3) {
+  return window['go']['main']['App']['ChangeUsername'](arg1, arg2, arg3);
+}
+
+export function CheckBookmarkLinks(arg1) {
+  return window['go']['main']['App']['CheckBookmarkLinks'](arg1);
+}
+
+export function CheckPlayback(arg1, arg2) {
+  return window['go']['main']['App']['CheckPlayback'](arg1, arg2);
+}
+
+export function ClearImportedDanmaku(arg1, arg2, arg3) {
+  return window['go']['main']['App']['ClearImportedDanmaku'](arg1, arg2, arg3);
+}
+
+export function ClearSkipMarkers(arg1, arg2) {
+  return window['go']['main']['App']['ClearSkipMarkers'](arg1, arg2);
+}
+
+export function ConfirmTOTPEnrollment(arg1, arg2) {
+  return window['go']['main']['App']['ConfirmTOTPEnrollment'](arg1, arg2);
+}
+
+export function CreateBackup(arg1) {
+  return window['go']['main']['App']['CreateBackup'](arg1);
+}
+
+export function CreateInviteCode(arg1, arg2, arg3, arg4) {
+  return window['go']['main']['App']['CreateInviteCode'](arg1, arg2, arg3, arg4);
+}
+
+export function CreateProfile(arg1, arg2, arg3, arg4, arg5) {
+  return window['go']['main']['App']['CreateProfile'](arg1, arg2, arg3, arg4, arg5);
+}
+
+export function DeleteAccount(arg1, arg2) {
+  return window['go']['main']['App']['DeleteAccount'](arg1, arg2);
+}
+
+export function DeleteDanmaku(arg1, arg2) {
+  return window['go']['main']['App']['DeleteDanmaku'](arg1, arg2);
+}
+
+export function DeleteMediaInfo(arg1, arg2) {
+  return window['go']['main']['App']['DeleteMediaInfo'](arg1, arg2);
+}
+
+export function DeleteProfile(arg1, arg2, arg3, arg4) {
+  return window['go']['main']['App']['DeleteProfile'](arg1, arg2, arg3, arg4);
+}
+
+export function DeleteRole(arg1, arg2) {
+  return window['go']['main']['App']['DeleteRole'](arg1, arg2);
+}
+
+export function DeleteSetting(arg1, arg2) {
+  return window['go']['main']['App']['DeleteSetting'](arg1, arg2);
+}
+
+export function DeleteSubtitle(arg1, arg2) {
+  return window['go']['main']['App']['DeleteSubtitle'](arg1, arg2);
+}
+
+export function DeleteUser(arg1, arg2) {
+  return window['go']['main']['App']['DeleteUser'](arg1, arg2);
+}
+
+export function DisableTOTP(arg1, arg2) {
+  return window['go']['main']['App']['DisableTOTP'](arg1, arg2);
+}
+
+export function EndPlayback(arg1) {
+  return window['go']['main']['App']['EndPlayback'](arg1);
+}
+
+export function ExportUserData(arg1) {
+  return window['go']['main']['App']['ExportUserData'](arg1);
+}
+
+export function FilterMediaList(arg1, arg2) {
+  return window['go']['main']['App']['FilterMediaList'](arg1, arg2);
+}
+
+export function GetAllSettings(arg1) {
+  return window['go']['main']['App']['GetAllSettings'](arg1);
+}
+
+export function GetAllUsers(arg1) {
+  return window['go']['main']['App']['GetAllUsers'](arg1);
+}
+
+export function GetAuditLog(arg1, arg2) {
+  return window['go']['main']['App']['GetAuditLog'](arg1, arg2);
+}
+
+export function GetAuthEvents(arg1, arg2, arg3, arg4) {
+  return window['go']['main']['App']['GetAuthEvents'](arg1, arg2, arg3, arg4);
 }
 
 export function GetBookmarkedMediaDetails(arg1) {
   return window['go']['main']['App']['GetBookmarkedMediaDetails'](arg1);
 }
 
+export function GetBrowsableTables(arg1) {
+  return window['go']['main']['App']['GetBrowsableTables'](arg1);
+}
+
 export function GetCurrentUser(arg1) {
   return window['go']['main']['App']['GetCurrentUser'](arg1);
 }
 
-export function GetDatabaseTables() {
-  return window['go']['main']['App']['GetDatabaseTables']();
+export function GetDanmaku(arg1, arg2, arg3, arg4, arg5, arg6) {
+  return window['go']['main']['App']['GetDanmaku'](arg1, arg2, arg3, arg4, arg5, arg6);
+}
+
+export function GetDatabaseTables(arg1) {
+  return window['go']['main']['App']['GetDatabaseTables'](arg1);
+}
+
+export function GetEffectiveSetting(arg1, arg2) {
+  return window['go']['main']['App']['GetEffectiveSetting'](arg1, arg2);
+}
+
+export function GetLoginLockouts(arg1) {
+  return window['go']['main']['App']['GetLoginLockouts'](arg1);
+}
+
+export function GetMigrations(arg1) {
+  return window['go']['main']['App']['GetMigrations'](arg1);
+}
+
+export function GetParentalRules(arg1, arg2) {
+  return window['go']['main']['App']['GetParentalRules'](arg1, arg2);
+}
+
+export function GetPermissions(arg1) {
+  return window['go']['main']['App']['GetPermissions'](arg1);
+}
+
+export function GetSettingDefinitions() {
+  return window['go']['main']['App']['GetSettingDefinitions']();
+}
+
+export function GetSkipMarkers(arg1, arg2) {
+  return window['go']['main']['App']['GetSkipMarkers'](arg1, arg2);
+}
+
+export function GetSourceReport(arg1) {
+  return window['go']['main']['App']['GetSourceReport'](arg1);
 }
 
-export function GetMigrations() {
-  return window['go']['main']['App']['GetMigrations']();
+export function GetSourceStatus(arg1, arg2) {
+  return window['go']['main']['App']['GetSourceStatus'](arg1, arg2);
 }
 
 export function GetUserBookmarks(arg1) {
@@ -46,30 +190,162 @@ export function GetUserSettings(arg1) {
   return window['go']['main']['App']['GetUserSettings'](arg1);
 }
 
+export function HasPendingRestore(arg1) {
+  return window['go']['main']['App']['HasPendingRestore'](arg1);
+}
+
+export function ImportDanmaku(arg1, arg2, arg3, arg4, arg5) {
+  return window['go']['main']['App']['ImportDanmaku'](arg1, arg2, arg3, arg4, arg5);
+}
+
+export function ImportUserData(arg1, arg2, arg3) {
+  return window['go']['main']['App']['ImportUserData'](arg1, arg2, arg3);
+}
+
 export function IsBookmarked(arg1, arg2) {
   return window['go']['main']['App']['IsBookmarked'](arg1, arg2);
 }
 
+export function ListBackups(arg1) {
+  return window['go']['main']['App']['ListBackups'](arg1);
+}
+
+export function ListInviteCodes(arg1) {
+  return window['go']['main']['App']['ListInviteCodes'](arg1);
+}
+
+export function ListPermissions() {
+  return window['go']['main']['App']['ListPermissions']();
+}
+
+export function ListProfiles(arg1) {
+  return window['go']['main']['App']['ListProfiles'](arg1);
+}
+
+export function ListRoles(arg1) {
+  return window['go']['main']['App']['ListRoles'](arg1);
+}
+
+export function ListSubtitles(arg1, arg2, arg3) {
+  return window['go']['main']['App']['ListSubtitles'](arg1, arg2, arg3);
+}
+
 export function Login(arg1, arg2) {
   return window['go']['main']['App']['Login'](arg1, arg2);
 }
 
-export function OpenDatabaseDirectory() {
-  return window['go']['main']['App']['OpenDatabaseDirectory']();
+export function LoginWithSecondFactor(arg1, arg2) {
+  return window['go']['main']['App']['LoginWithSecondFactor'](arg1, arg2);
+}
+
+export function Logout(arg1) {
+  return window['go']['main']['App']['Logout'](arg1);
+}
+
+export function NeedsSetup() {
+  return window['go']['main']['App']['NeedsSetup']();
+}
+
+export function OpenDatabaseDirectory(arg1) {
+  return window['go']['main']['App']['OpenDatabaseDirectory'](arg1);
+}
+
+export function PostDanmaku(arg1, arg2, arg3, arg4, arg5, arg6, arg7) {
+  return window['go']['main']['App']['PostDanmaku'](arg1, arg2, arg3, arg4, arg5, arg6, arg7);
+}
+
+export function QueryTableRows(arg1, arg2) {
+  return window['go']['main']['App']['QueryTableRows'](arg1, arg2);
+}
+
+export function RegenerateRecoveryCodes(arg1, arg2) {
+  return window['go']['main']['App']['RegenerateRecoveryCodes'](arg1, arg2);
 }
 
 export function RemoveBookmark(arg1, arg2) {
   return window['go']['main']['App']['RemoveBookmark'](arg1, arg2);
 }
 
-export function SaveMediaInfo(arg1, arg2, arg3, arg4, arg5, arg6, arg7, arg8, arg9, arg10) {
-  return window['go']['main']['App']['SaveMediaInfo'](arg1, arg2, arg3, arg4, arg5, arg6, arg7, arg8, arg9, arg10);
+export function ReportPlaybackError(arg1, arg2) {
+  return window['go']['main']['App']['ReportPlaybackError'](arg1, arg2);
+}
+
+export function RestoreBackup(arg1, arg2) {
+  return window['go']['main']['App']['RestoreBackup'](arg1, arg2);
+}
+
+export function RevokeInviteCode(arg1, arg2) {
+  return window['go']['main']['App']['RevokeInviteCode'](arg1, arg2);
+}
+
+export function SaveMediaInfo(arg1, arg2, arg3, arg4, arg5, arg6, arg7, arg8, arg9, arg10, arg11) {
+  return window['go']['main']['App']['SaveMediaInfo'](arg1, arg2, arg3, arg4, arg5, arg6, arg7, arg8, arg9, arg10, arg11);
+}
+
+export function SaveRole(arg1, arg2, arg3, arg4) {
+  return window['go']['main']['App']['SaveRole'](arg1, arg2, arg3, arg4);
+}
+
+export function SetMediaContentRating(arg1, arg2, arg3) {
+  return window['go']['main']['App']['SetMediaContentRating'](arg1, arg2, arg3);
+}
+
+export function SetParentalPIN(arg1, arg2, arg3) {
+  return window['go']['main']['App']['SetParentalPIN'](arg1, arg2, arg3);
+}
+
+export function SetParentalRules(arg1, arg2, arg3, arg4) {
+  return window['go']['main']['App']['SetParentalRules'](arg1, arg2, arg3, arg4);
+}
+
+export function SetSkipMarker(arg1, arg2, arg3, arg4, arg5) {
+  return window['go']['main']['App']['SetSkipMarker'](arg1, arg2, arg3, arg4, arg5);
+}
+
+export function SetSubtitleOffset(arg1, arg2, arg3) {
+  return window['go']['main']['App']['SetSubtitleOffset'](arg1, arg2, arg3);
+}
+
+export function SetTemporaryPassword(arg1, arg2) {
+  return window['go']['main']['App']['SetTemporaryPassword'](arg1, arg2);
+}
+
+export function SetUserDisabled(arg1, arg2, arg3) {
+  return window['go']['main']['App']['SetUserDisabled'](arg1, arg2, arg3);
+}
+
+export function SetUserRole(arg1, arg2, arg3) {
+  return window['go']['main']['App']['SetUserRole'](arg1, arg2, arg3);
+}
+
+export function SetupAdmin(arg1, arg2, arg3) {
+  return window['go']['main']['App']['SetupAdmin'](arg1, arg2, arg3);
+}
+
+export function Signup(arg1, arg2, arg3, arg4) {
+  return window['go']['main']['App']['Signup'](arg1, arg2, arg3, arg4);
+}
+
+export function StartPlayback(arg1, arg2, arg3, arg4) {
+  return window['go']['main']['App']['StartPlayback'](arg1, arg2, arg3, arg4);
+}
+
+export function SwitchProfile(arg1, arg2, arg3, arg4) {
+  return window['go']['main']['App']['SwitchProfile'](arg1, arg2, arg3, arg4);
+}
+
+export function UnlockAccount(arg1, arg2) {
+  return window['go']['main']['App']['UnlockAccount'](arg1, arg2);
+}
+
+export function UpdateSetting(arg1, arg2, arg3) {
+  return window['go']['main']['App']['UpdateSetting'](arg1, arg2, arg3);
 }
 
-export function Signup(arg1, arg2, arg3) {
-  return window['go']['main']['App']['Signup'](arg1, arg2, arg3);
+export function UpsertSetting(arg1, arg2, arg3, arg4) {
+  return window['go']['main']['App']['UpsertSetting'](arg1, arg2, arg3, arg4);
 }
 
-export function UpdateSetting(arg1, arg2, arg3, arg4) {
-  return window['go']['main']['App']['UpdateSetting'](arg1, arg2, arg3, arg4);
+export function ValidateSession(arg1) {
+  return window['go']['main']['App']['ValidateSession'](arg1);
 }
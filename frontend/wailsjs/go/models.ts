@@ -1,18 +1,18 @@
 export namespace models {
 	
-	export class APIResponse__mooncaketv_services_User_ {
+	export class APIResponse__mooncaketv_services_AuditPage_ {
 	    success: boolean;
-	    data?: services.User;
+	    data?: services.AuditPage;
 	    error: string;
 	
 	    static createFrom(source: any = {}) {
-	        return new APIResponse__mooncaketv_services_User_(source);
+	        return new APIResponse__mooncaketv_services_AuditPage_(source);
 	    }
 	
 	    constructor(source: any = {}) {
 	        if ('string' === typeof source) source = JSON.parse(source);
 	        this.success = source["success"];
-	        this.data = this.convertValues(source["data"], services.User);
+	        this.data = this.convertValues(source["data"], services.AuditPage);
 	        this.error = source["error"];
 	    }
 	
@@ -34,141 +34,2146 @@ export namespace models {
 		    return a;
 		}
 	}
-	export class APIResponse___map_string_interface____ {
+	export class APIResponse__mooncaketv_services_BackupSnapshot_ {
 	    success: boolean;
-	    data: any[];
+	    data?: services.BackupSnapshot;
 	    error: string;
 	
 	    static createFrom(source: any = {}) {
-	        return new APIResponse___map_string_interface____(source);
+	        return new APIResponse__mooncaketv_services_BackupSnapshot_(source);
 	    }
 	
 	    constructor(source: any = {}) {
 	        if ('string' === typeof source) source = JSON.parse(source);
 	        this.success = source["success"];
-	        this.data = source["data"];
+	        this.data = this.convertValues(source["data"], services.BackupSnapshot);
 	        this.error = source["error"];
 	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
 	}
-	export class APIResponse___string_ {
+	export class APIResponse__mooncaketv_services_DanmakuComment_ {
 	    success: boolean;
-	    data: string[];
+	    data?: services.DanmakuComment;
 	    error: string;
 	
 	    static createFrom(source: any = {}) {
-	        return new APIResponse___string_(source);
+	        return new APIResponse__mooncaketv_services_DanmakuComment_(source);
 	    }
 	
 	    constructor(source: any = {}) {
 	        if ('string' === typeof source) source = JSON.parse(source);
 	        this.success = source["success"];
-	        this.data = source["data"];
+	        this.data = this.convertValues(source["data"], services.DanmakuComment);
 	        this.error = source["error"];
 	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
 	}
-	export class APIResponse_bool_ {
+	export class APIResponse__mooncaketv_services_DanmakuImportResult_ {
 	    success: boolean;
-	    data: boolean;
+	    data?: services.DanmakuImportResult;
 	    error: string;
 	
 	    static createFrom(source: any = {}) {
-	        return new APIResponse_bool_(source);
+	        return new APIResponse__mooncaketv_services_DanmakuImportResult_(source);
 	    }
 	
 	    constructor(source: any = {}) {
 	        if ('string' === typeof source) source = JSON.parse(source);
 	        this.success = source["success"];
-	        this.data = source["data"];
+	        this.data = this.convertValues(source["data"], services.DanmakuImportResult);
 	        this.error = source["error"];
 	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
 	}
-	export class APIResponse_map_string_interface____ {
+	export class APIResponse__mooncaketv_services_EffectiveSetting_ {
 	    success: boolean;
-	    data: Record<string, any>;
+	    data?: services.EffectiveSetting;
 	    error: string;
 	
 	    static createFrom(source: any = {}) {
-	        return new APIResponse_map_string_interface____(source);
+	        return new APIResponse__mooncaketv_services_EffectiveSetting_(source);
 	    }
 	
 	    constructor(source: any = {}) {
 	        if ('string' === typeof source) source = JSON.parse(source);
 	        this.success = source["success"];
-	        this.data = source["data"];
+	        this.data = this.convertValues(source["data"], services.EffectiveSetting);
 	        this.error = source["error"];
 	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
 	}
-	export class APIResponse_string_ {
+	export class APIResponse__mooncaketv_services_ImportReport_ {
 	    success: boolean;
-	    data: string;
+	    data?: services.ImportReport;
 	    error: string;
 	
 	    static createFrom(source: any = {}) {
-	        return new APIResponse_string_(source);
+	        return new APIResponse__mooncaketv_services_ImportReport_(source);
 	    }
 	
 	    constructor(source: any = {}) {
 	        if ('string' === typeof source) source = JSON.parse(source);
 	        this.success = source["success"];
-	        this.data = source["data"];
+	        this.data = this.convertValues(source["data"], services.ImportReport);
 	        this.error = source["error"];
 	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
 	}
-
-}
-
-export namespace services {
+	export class APIResponse__mooncaketv_services_InviteCode_ {
+	    success: boolean;
+	    data?: services.InviteCode;
+	    error: string;
 	
-	export class ProxyImageResponse {
-	    data: number[];
-	    contentType: string;
+	    static createFrom(source: any = {}) {
+	        return new APIResponse__mooncaketv_services_InviteCode_(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.success = source["success"];
+	        this.data = this.convertValues(source["data"], services.InviteCode);
+	        this.error = source["error"];
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class APIResponse__mooncaketv_services_LinkCheckReport_ {
+	    success: boolean;
+	    data?: services.LinkCheckReport;
+	    error: string;
 	
 	    static createFrom(source: any = {}) {
-	        return new ProxyImageResponse(source);
+	        return new APIResponse__mooncaketv_services_LinkCheckReport_(source);
 	    }
 	
 	    constructor(source: any = {}) {
 	        if ('string' === typeof source) source = JSON.parse(source);
-	        this.data = source["data"];
-	        this.contentType = source["contentType"];
+	        this.success = source["success"];
+	        this.data = this.convertValues(source["data"], services.LinkCheckReport);
+	        this.error = source["error"];
 	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
 	}
-	export class ProxyURLResponse {
-	    data: number[];
-	    contentType: string;
+	export class APIResponse__mooncaketv_services_ParentalRules_ {
+	    success: boolean;
+	    data?: services.ParentalRules;
+	    error: string;
 	
 	    static createFrom(source: any = {}) {
-	        return new ProxyURLResponse(source);
+	        return new APIResponse__mooncaketv_services_ParentalRules_(source);
 	    }
 	
 	    constructor(source: any = {}) {
 	        if ('string' === typeof source) source = JSON.parse(source);
-	        this.data = source["data"];
-	        this.contentType = source["contentType"];
+	        this.success = source["success"];
+	        this.data = this.convertValues(source["data"], services.ParentalRules);
+	        this.error = source["error"];
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class APIResponse__mooncaketv_services_PlaybackState_ {
+	    success: boolean;
+	    data?: services.PlaybackState;
+	    error: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new APIResponse__mooncaketv_services_PlaybackState_(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.success = source["success"];
+	        this.data = this.convertValues(source["data"], services.PlaybackState);
+	        this.error = source["error"];
 	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
 	}
-	export class SpeedTestResult {
-	    speedMBps: number;
-	    error?: string;
+	export class APIResponse__mooncaketv_services_PlaybackSwitch_ {
+	    success: boolean;
+	    data?: services.PlaybackSwitch;
+	    error: string;
 	
 	    static createFrom(source: any = {}) {
-	        return new SpeedTestResult(source);
+	        return new APIResponse__mooncaketv_services_PlaybackSwitch_(source);
 	    }
 	
 	    constructor(source: any = {}) {
 	        if ('string' === typeof source) source = JSON.parse(source);
-	        this.speedMBps = source["speedMBps"];
+	        this.success = source["success"];
+	        this.data = this.convertValues(source["data"], services.PlaybackSwitch);
 	        this.error = source["error"];
 	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class APIResponse__mooncaketv_services_Profile_ {
+	    success: boolean;
+	    data?: services.Profile;
+	    error: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new APIResponse__mooncaketv_services_Profile_(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.success = source["success"];
+	        this.data = this.convertValues(source["data"], services.Profile);
+	        this.error = source["error"];
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class APIResponse__mooncaketv_services_SkipMarkers_ {
+	    success: boolean;
+	    data?: services.SkipMarkers;
+	    error: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new APIResponse__mooncaketv_services_SkipMarkers_(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.success = source["success"];
+	        this.data = this.convertValues(source["data"], services.SkipMarkers);
+	        this.error = source["error"];
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class APIResponse__mooncaketv_services_SubtitleTrack_ {
+	    success: boolean;
+	    data?: services.SubtitleTrack;
+	    error: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new APIResponse__mooncaketv_services_SubtitleTrack_(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.success = source["success"];
+	        this.data = this.convertValues(source["data"], services.SubtitleTrack);
+	        this.error = source["error"];
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class APIResponse__mooncaketv_services_TOTPEnrollment_ {
+	    success: boolean;
+	    data?: services.TOTPEnrollment;
+	    error: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new APIResponse__mooncaketv_services_TOTPEnrollment_(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.success = source["success"];
+	        this.data = this.convertValues(source["data"], services.TOTPEnrollment);
+	        this.error = source["error"];
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class APIResponse__mooncaketv_services_TablePage_ {
+	    success: boolean;
+	    data?: services.TablePage;
+	    error: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new APIResponse__mooncaketv_services_TablePage_(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.success = source["success"];
+	        this.data = this.convertValues(source["data"], services.TablePage);
+	        this.error = source["error"];
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class APIResponse__mooncaketv_services_User_ {
+	    success: boolean;
+	    data?: services.User;
+	    error: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new APIResponse__mooncaketv_services_User_(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.success = source["success"];
+	        this.data = this.convertValues(source["data"], services.User);
+	        this.error = source["error"];
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class APIResponse___map_string_interface____ {
+	    success: boolean;
+	    data: any[];
+	    error: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new APIResponse___map_string_interface____(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.success = source["success"];
+	        this.data = source["data"];
+	        this.error = source["error"];
+	    }
+	}
+	export class APIResponse___mooncaketv_services_AuthEvent_ {
+	    success: boolean;
+	    data: services.AuthEvent[];
+	    error: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new APIResponse___mooncaketv_services_AuthEvent_(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.success = source["success"];
+	        this.data = this.convertValues(source["data"], services.AuthEvent);
+	        this.error = source["error"];
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class APIResponse___mooncaketv_services_BackupSnapshot_ {
+	    success: boolean;
+	    data: services.BackupSnapshot[];
+	    error: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new APIResponse___mooncaketv_services_BackupSnapshot_(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.success = source["success"];
+	        this.data = this.convertValues(source["data"], services.BackupSnapshot);
+	        this.error = source["error"];
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class APIResponse___mooncaketv_services_DanmakuComment_ {
+	    success: boolean;
+	    data: services.DanmakuComment[];
+	    error: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new APIResponse___mooncaketv_services_DanmakuComment_(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.success = source["success"];
+	        this.data = this.convertValues(source["data"], services.DanmakuComment);
+	        this.error = source["error"];
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class APIResponse___mooncaketv_services_HostReport_ {
+	    success: boolean;
+	    data: services.HostReport[];
+	    error: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new APIResponse___mooncaketv_services_HostReport_(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.success = source["success"];
+	        this.data = this.convertValues(source["data"], services.HostReport);
+	        this.error = source["error"];
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class APIResponse___mooncaketv_services_InviteCode_ {
+	    success: boolean;
+	    data: services.InviteCode[];
+	    error: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new APIResponse___mooncaketv_services_InviteCode_(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.success = source["success"];
+	        this.data = this.convertValues(source["data"], services.InviteCode);
+	        this.error = source["error"];
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class APIResponse___mooncaketv_services_LoginLockout_ {
+	    success: boolean;
+	    data: services.LoginLockout[];
+	    error: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new APIResponse___mooncaketv_services_LoginLockout_(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.success = source["success"];
+	        this.data = this.convertValues(source["data"], services.LoginLockout);
+	        this.error = source["error"];
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class APIResponse___mooncaketv_services_PermissionInfo_ {
+	    success: boolean;
+	    data: services.PermissionInfo[];
+	    error: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new APIResponse___mooncaketv_services_PermissionInfo_(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.success = source["success"];
+	        this.data = this.convertValues(source["data"], services.PermissionInfo);
+	        this.error = source["error"];
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class APIResponse___mooncaketv_services_Profile_ {
+	    success: boolean;
+	    data: services.Profile[];
+	    error: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new APIResponse___mooncaketv_services_Profile_(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.success = source["success"];
+	        this.data = this.convertValues(source["data"], services.Profile);
+	        this.error = source["error"];
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class APIResponse___mooncaketv_services_Role_ {
+	    success: boolean;
+	    data: services.Role[];
+	    error: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new APIResponse___mooncaketv_services_Role_(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.success = source["success"];
+	        this.data = this.convertValues(source["data"], services.Role);
+	        this.error = source["error"];
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class APIResponse___mooncaketv_services_SettingDefinition_ {
+	    success: boolean;
+	    data: services.SettingDefinition[];
+	    error: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new APIResponse___mooncaketv_services_SettingDefinition_(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.success = source["success"];
+	        this.data = this.convertValues(source["data"], services.SettingDefinition);
+	        this.error = source["error"];
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class APIResponse___mooncaketv_services_SourceCheck_ {
+	    success: boolean;
+	    data: services.SourceCheck[];
+	    error: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new APIResponse___mooncaketv_services_SourceCheck_(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.success = source["success"];
+	        this.data = this.convertValues(source["data"], services.SourceCheck);
+	        this.error = source["error"];
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class APIResponse___mooncaketv_services_SubtitleTrack_ {
+	    success: boolean;
+	    data: services.SubtitleTrack[];
+	    error: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new APIResponse___mooncaketv_services_SubtitleTrack_(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.success = source["success"];
+	        this.data = this.convertValues(source["data"], services.SubtitleTrack);
+	        this.error = source["error"];
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class APIResponse___mooncaketv_services_TableInfo_ {
+	    success: boolean;
+	    data: services.TableInfo[];
+	    error: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new APIResponse___mooncaketv_services_TableInfo_(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.success = source["success"];
+	        this.data = this.convertValues(source["data"], services.TableInfo);
+	        this.error = source["error"];
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class APIResponse___string_ {
+	    success: boolean;
+	    data: string[];
+	    error: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new APIResponse___string_(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.success = source["success"];
+	        this.data = source["data"];
+	        this.error = source["error"];
+	    }
+	}
+	export class APIResponse_bool_ {
+	    success: boolean;
+	    data: boolean;
+	    error: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new APIResponse_bool_(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.success = source["success"];
+	        this.data = source["data"];
+	        this.error = source["error"];
+	    }
+	}
+	export class APIResponse_int64_ {
+	    success: boolean;
+	    data: number;
+	    error: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new APIResponse_int64_(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.success = source["success"];
+	        this.data = source["data"];
+	        this.error = source["error"];
+	    }
+	}
+	export class APIResponse_map_string_interface____ {
+	    success: boolean;
+	    data: Record<string, any>;
+	    error: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new APIResponse_map_string_interface____(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.success = source["success"];
+	        this.data = source["data"];
+	        this.error = source["error"];
+	    }
+	}
+	export class APIResponse_string_ {
+	    success: boolean;
+	    data: string;
+	    error: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new APIResponse_string_(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.success = source["success"];
+	        this.data = source["data"];
+	        this.error = source["error"];
+	    }
+	}
+
+}
+
+export namespace services {
+	
+	export class StrippedAdBlock {
+	    firstSegment: number;
+	    segments: number;
+	    seconds: number;
+	    reason: string;
+	    sampleUrl: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new StrippedAdBlock(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.firstSegment = source["firstSegment"];
+	        this.segments = source["segments"];
+	        this.seconds = source["seconds"];
+	        this.reason = source["reason"];
+	        this.sampleUrl = source["sampleUrl"];
+	    }
+	}
+	export class AdFilterReport {
+	    applied: boolean;
+	    rule: string;
+	    removedSegments: number;
+	    removedSeconds: number;
+	    blocks: StrippedAdBlock[];
+	    skipped?: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new AdFilterReport(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.applied = source["applied"];
+	        this.rule = source["rule"];
+	        this.removedSegments = source["removedSegments"];
+	        this.removedSeconds = source["removedSeconds"];
+	        this.blocks = this.convertValues(source["blocks"], StrippedAdBlock);
+	        this.skipped = source["skipped"];
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class AuditEntry {
+	    id: number;
+	    actor_id?: number;
+	    actor_name: string;
+	    action: string;
+	    target_type: string;
+	    target_id: string;
+	    before?: string;
+	    after?: string;
+	    created_at: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new AuditEntry(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.id = source["id"];
+	        this.actor_id = source["actor_id"];
+	        this.actor_name = source["actor_name"];
+	        this.action = source["action"];
+	        this.target_type = source["target_type"];
+	        this.target_id = source["target_id"];
+	        this.before = source["before"];
+	        this.after = source["after"];
+	        this.created_at = source["created_at"];
+	    }
+	}
+	export class AuditPage {
+	    entries: AuditEntry[];
+	    total: number;
+	    page: number;
+	    page_size: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new AuditPage(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.entries = this.convertValues(source["entries"], AuditEntry);
+	        this.total = source["total"];
+	        this.page = source["page"];
+	        this.page_size = source["page_size"];
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class AuditQuery {
+	    actor_id: number;
+	    action: string;
+	    target_type: string;
+	    target_id: string;
+	    since: string;
+	    until: string;
+	    page: number;
+	    page_size: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new AuditQuery(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.actor_id = source["actor_id"];
+	        this.action = source["action"];
+	        this.target_type = source["target_type"];
+	        this.target_id = source["target_id"];
+	        this.since = source["since"];
+	        this.until = source["until"];
+	        this.page = source["page"];
+	        this.page_size = source["page_size"];
+	    }
+	}
+	export class AuthEvent {
+	    id: number;
+	    user_id?: number;
+	    username: string;
+	    event_type: string;
+	    success: boolean;
+	    detail: string;
+	    created_at: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new AuthEvent(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.id = source["id"];
+	        this.user_id = source["user_id"];
+	        this.username = source["username"];
+	        this.event_type = source["event_type"];
+	        this.success = source["success"];
+	        this.detail = source["detail"];
+	        this.created_at = source["created_at"];
+	    }
+	}
+	export class BackupSnapshot {
+	    name: string;
+	    size: number;
+	    created_at: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new BackupSnapshot(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.name = source["name"];
+	        this.size = source["size"];
+	        this.created_at = source["created_at"];
+	    }
+	}
+	export class DanmakuComment {
+	    id: number;
+	    time: number;
+	    mode: string;
+	    color: number;
+	    size: number;
+	    text: string;
+	    author: string;
+	    local: boolean;
+	    own: boolean;
+	
+	    static createFrom(source: any = {}) {
+	        return new DanmakuComment(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.id = source["id"];
+	        this.time = source["time"];
+	        this.mode = source["mode"];
+	        this.color = source["color"];
+	        this.size = source["size"];
+	        this.text = source["text"];
+	        this.author = source["author"];
+	        this.local = source["local"];
+	        this.own = source["own"];
+	    }
+	}
+	export class DanmakuFilter {
+	    keywords: string[];
+	    users: string[];
+	
+	    static createFrom(source: any = {}) {
+	        return new DanmakuFilter(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.keywords = source["keywords"];
+	        this.users = source["users"];
+	    }
+	}
+	export class DanmakuImportResult {
+	    format: string;
+	    parsed: number;
+	    added: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new DanmakuImportResult(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.format = source["format"];
+	        this.parsed = source["parsed"];
+	        this.added = source["added"];
+	    }
+	}
+	export class DeadTitle {
+	    mc_id: string;
+	    title: string;
+	    sources: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new DeadTitle(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.mc_id = source["mc_id"];
+	        this.title = source["title"];
+	        this.sources = source["sources"];
+	    }
+	}
+	export class EffectiveSetting {
+	    key: string;
+	    type: string;
+	    value: string;
+	    source: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new EffectiveSetting(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.key = source["key"];
+	        this.type = source["type"];
+	        this.value = source["value"];
+	        this.source = source["source"];
+	    }
+	}
+	export class Episode {
+	    season: number;
+	    number: number;
+	    title: string;
+	    url: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new Episode(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.season = source["season"];
+	        this.number = source["number"];
+	        this.title = source["title"];
+	        this.url = source["url"];
+	    }
+	}
+	export class HostReport {
+	    host: string;
+	    score: number;
+	    speed_tests: number;
+	    failures: number;
+	    playback_errors: number;
+	    avg_speed_mbps: number;
+	    titles: number;
+	    last_error: string;
+	    updated_at: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new HostReport(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.host = source["host"];
+	        this.score = source["score"];
+	        this.speed_tests = source["speed_tests"];
+	        this.failures = source["failures"];
+	        this.playback_errors = source["playback_errors"];
+	        this.avg_speed_mbps = source["avg_speed_mbps"];
+	        this.titles = source["titles"];
+	        this.last_error = source["last_error"];
+	        this.updated_at = source["updated_at"];
+	    }
+	}
+	export class ImportCounts {
+	    added: number;
+	    updated: number;
+	    skipped: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new ImportCounts(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.added = source["added"];
+	        this.updated = source["updated"];
+	        this.skipped = source["skipped"];
+	    }
+	}
+	export class ImportReport {
+	    strategy: string;
+	    bookmarks: ImportCounts;
+	    history: ImportCounts;
+	    settings: ImportCounts;
+	    medias: ImportCounts;
+	
+	    static createFrom(source: any = {}) {
+	        return new ImportReport(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.strategy = source["strategy"];
+	        this.bookmarks = this.convertValues(source["bookmarks"], ImportCounts);
+	        this.history = this.convertValues(source["history"], ImportCounts);
+	        this.settings = this.convertValues(source["settings"], ImportCounts);
+	        this.medias = this.convertValues(source["medias"], ImportCounts);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class InviteCode {
+	    id: number;
+	    code: string;
+	    user_role: string;
+	    max_uses: number;
+	    use_count: number;
+	    expires_at?: string;
+	    created_by?: number;
+	    created_at: string;
+	    usable: boolean;
+	
+	    static createFrom(source: any = {}) {
+	        return new InviteCode(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.id = source["id"];
+	        this.code = source["code"];
+	        this.user_role = source["user_role"];
+	        this.max_uses = source["max_uses"];
+	        this.use_count = source["use_count"];
+	        this.expires_at = source["expires_at"];
+	        this.created_by = source["created_by"];
+	        this.created_at = source["created_at"];
+	        this.usable = source["usable"];
+	    }
+	}
+	export class LinkCheckReport {
+	    titles: number;
+	    sources: number;
+	    dead_sources: number;
+	    dead_titles: DeadTitle[];
+	    checked_at: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new LinkCheckReport(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.titles = source["titles"];
+	        this.sources = source["sources"];
+	        this.dead_sources = source["dead_sources"];
+	        this.dead_titles = this.convertValues(source["dead_titles"], DeadTitle);
+	        this.checked_at = source["checked_at"];
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class LoginLockout {
+	    username: string;
+	    failed_count: number;
+	    last_failed_at: string;
+	    locked_until?: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new LoginLockout(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.username = source["username"];
+	        this.failed_count = source["failed_count"];
+	        this.last_failed_at = source["last_failed_at"];
+	        this.locked_until = source["locked_until"];
+	    }
+	}
+	export class MediaSource {
+	    id: number;
+	    name: string;
+	    type: string;
+	    health: string;
+	    episodes: Episode[];
+	
+	    static createFrom(source: any = {}) {
+	        return new MediaSource(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.id = source["id"];
+	        this.name = source["name"];
+	        this.type = source["type"];
+	        this.health = source["health"];
+	        this.episodes = this.convertValues(source["episodes"], Episode);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class ParentalRules {
+	    enabled: boolean;
+	    blocked_categories: string[];
+	    blocked_genres: string[];
+	    blocked_regions: string[];
+	    blocked_keywords: string[];
+	    max_content_rating: string;
+	    block_unrated: boolean;
+	    viewing_from: string;
+	    viewing_until: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new ParentalRules(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.enabled = source["enabled"];
+	        this.blocked_categories = source["blocked_categories"];
+	        this.blocked_genres = source["blocked_genres"];
+	        this.blocked_regions = source["blocked_regions"];
+	        this.blocked_keywords = source["blocked_keywords"];
+	        this.max_content_rating = source["max_content_rating"];
+	        this.block_unrated = source["block_unrated"];
+	        this.viewing_from = source["viewing_from"];
+	        this.viewing_until = source["viewing_until"];
+	    }
+	}
+	export class PermissionInfo {
+	    name: string;
+	    description: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new PermissionInfo(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.name = source["name"];
+	        this.description = source["description"];
+	    }
+	}
+	export class PlaybackErrorReport {
+	    url: string;
+	    details: string;
+	    fatal: boolean;
+	    position: number;
+	    segment_index: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new PlaybackErrorReport(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.url = source["url"];
+	        this.details = source["details"];
+	        this.fatal = source["fatal"];
+	        this.position = source["position"];
+	        this.segment_index = source["segment_index"];
+	    }
+	}
+	export class PlaybackSource {
+	    name: string;
+	    urls: Record<string, string>;
+	
+	    static createFrom(source: any = {}) {
+	        return new PlaybackSource(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.name = source["name"];
+	        this.urls = source["urls"];
+	    }
+	}
+	export class SkipMarkers {
+	    mc_id: string;
+	    intro_start?: number;
+	    intro_end?: number;
+	    outro_start?: number;
+	    outro_end?: number;
+	    auto_skip: boolean;
+	    auto_advance: boolean;
+	
+	    static createFrom(source: any = {}) {
+	        return new SkipMarkers(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.mc_id = source["mc_id"];
+	        this.intro_start = source["intro_start"];
+	        this.intro_end = source["intro_end"];
+	        this.outro_start = source["outro_start"];
+	        this.outro_end = source["outro_end"];
+	        this.auto_skip = source["auto_skip"];
+	        this.auto_advance = source["auto_advance"];
+	    }
+	}
+	export class PlaybackState {
+	    session_id: string;
+	    mc_id: string;
+	    episode: string;
+	    source: string;
+	    url: string;
+	    sources: string[];
+	    markers?: SkipMarkers;
+	
+	    static createFrom(source: any = {}) {
+	        return new PlaybackState(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.session_id = source["session_id"];
+	        this.mc_id = source["mc_id"];
+	        this.episode = source["episode"];
+	        this.source = source["source"];
+	        this.url = source["url"];
+	        this.sources = source["sources"];
+	        this.markers = this.convertValues(source["markers"], SkipMarkers);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class PlaybackSwitch {
+	    switch: boolean;
+	    exhausted: boolean;
+	    source?: string;
+	    url?: string;
+	    resume_at: number;
+	    segment_index: number;
+	    reason?: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new PlaybackSwitch(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.switch = source["switch"];
+	        this.exhausted = source["exhausted"];
+	        this.source = source["source"];
+	        this.url = source["url"];
+	        this.resume_at = source["resume_at"];
+	        this.segment_index = source["segment_index"];
+	        this.reason = source["reason"];
+	    }
+	}
+	export class Profile {
+	    id: number;
+	    user_id: number;
+	    name: string;
+	    avatar: string;
+	    has_pin: boolean;
+	    active: boolean;
+	    created_at: string;
+	    updated_at: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new Profile(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.id = source["id"];
+	        this.user_id = source["user_id"];
+	        this.name = source["name"];
+	        this.avatar = source["avatar"];
+	        this.has_pin = source["has_pin"];
+	        this.active = source["active"];
+	        this.created_at = source["created_at"];
+	        this.updated_at = source["updated_at"];
+	    }
+	}
+	export class ProxyImageResponse {
+	    data: number[];
+	    contentType: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new ProxyImageResponse(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.data = source["data"];
+	        this.contentType = source["contentType"];
+	    }
+	}
+	export class ProxyURLResponse {
+	    data: number[];
+	    contentType: string;
+	    adFilter?: AdFilterReport;
+	    sanitized?: string[];
+	
+	    static createFrom(source: any = {}) {
+	        return new ProxyURLResponse(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.data = source["data"];
+	        this.contentType = source["contentType"];
+	        this.adFilter = this.convertValues(source["adFilter"], AdFilterReport);
+	        this.sanitized = source["sanitized"];
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class Role {
+	    id: number;
+	    name: string;
+	    description: string;
+	    built_in: boolean;
+	    permissions: string[];
+	    user_count: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new Role(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.id = source["id"];
+	        this.name = source["name"];
+	        this.description = source["description"];
+	        this.built_in = source["built_in"];
+	        this.permissions = source["permissions"];
+	        this.user_count = source["user_count"];
+	    }
+	}
+	export class SegmentCacheStats {
+	    hits: number;
+	    misses: number;
+	    prefetched: number;
+	    prefetchErrors: number;
+	    memoryEntries: number;
+	    memoryBytes: number;
+	    diskEntries: number;
+	    diskBytes: number;
+	    activePlaylists: number;
+	    concurrency: number;
+	    throughput: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new SegmentCacheStats(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.hits = source["hits"];
+	        this.misses = source["misses"];
+	        this.prefetched = source["prefetched"];
+	        this.prefetchErrors = source["prefetchErrors"];
+	        this.memoryEntries = source["memoryEntries"];
+	        this.memoryBytes = source["memoryBytes"];
+	        this.diskEntries = source["diskEntries"];
+	        this.diskBytes = source["diskBytes"];
+	        this.activePlaylists = source["activePlaylists"];
+	        this.concurrency = source["concurrency"];
+	        this.throughput = source["throughput"];
+	    }
+	}
+	export class SettingDefinition {
+	    key: string;
+	    type: string;
+	    default: string;
+	    scope: string;
+	    description: string;
+	    options?: string[];
+	    min?: number;
+	    max?: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new SettingDefinition(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.key = source["key"];
+	        this.type = source["type"];
+	        this.default = source["default"];
+	        this.scope = source["scope"];
+	        this.description = source["description"];
+	        this.options = source["options"];
+	        this.min = source["min"];
+	        this.max = source["max"];
+	    }
+	}
+	
+	export class SourceCheck {
+	    mc_id: string;
+	    source: string;
+	    label: string;
+	    url: string;
+	    status: string;
+	    error?: string;
+	    dead_since?: string;
+	    checked_at: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new SourceCheck(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.mc_id = source["mc_id"];
+	        this.source = source["source"];
+	        this.label = source["label"];
+	        this.url = source["url"];
+	        this.status = source["status"];
+	        this.error = source["error"];
+	        this.dead_since = source["dead_since"];
+	        this.checked_at = source["checked_at"];
+	    }
+	}
+	export class SpeedTestResult {
+	    speedMBps: number;
+	    error?: string;
+	    cached?: boolean;
+	
+	    static createFrom(source: any = {}) {
+	        return new SpeedTestResult(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.speedMBps = source["speedMBps"];
+	        this.error = source["error"];
+	        this.cached = source["cached"];
+	    }
+	}
+	
+	export class SubtitleTrack {
+	    id: number;
+	    mc_id: string;
+	    episode: string;
+	    label: string;
+	    file_name: string;
+	    format: string;
+	    charset: string;
+	    offset_ms: number;
+	    cues: number;
+	    user_id: number;
+	    url: string;
+	    created_at: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new SubtitleTrack(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.id = source["id"];
+	        this.mc_id = source["mc_id"];
+	        this.episode = source["episode"];
+	        this.label = source["label"];
+	        this.file_name = source["file_name"];
+	        this.format = source["format"];
+	        this.charset = source["charset"];
+	        this.offset_ms = source["offset_ms"];
+	        this.cues = source["cues"];
+	        this.user_id = source["user_id"];
+	        this.url = source["url"];
+	        this.created_at = source["created_at"];
+	    }
+	}
+	export class TOTPEnrollment {
+	    secret: string;
+	    otpauth_uri: string;
+	    qr_code_png: number[];
+	
+	    static createFrom(source: any = {}) {
+	        return new TOTPEnrollment(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.secret = source["secret"];
+	        this.otpauth_uri = source["otpauth_uri"];
+	        this.qr_code_png = source["qr_code_png"];
+	    }
+	}
+	export class TableColumn {
+	    name: string;
+	    type: string;
+	    not_null: boolean;
+	    default_value?: string;
+	    primary_key: boolean;
+	    redacted: boolean;
+	
+	    static createFrom(source: any = {}) {
+	        return new TableColumn(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.name = source["name"];
+	        this.type = source["type"];
+	        this.not_null = source["not_null"];
+	        this.default_value = source["default_value"];
+	        this.primary_key = source["primary_key"];
+	        this.redacted = source["redacted"];
+	    }
+	}
+	export class TableInfo {
+	    name: string;
+	    columns: TableColumn[];
+	    row_count: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new TableInfo(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.name = source["name"];
+	        this.columns = this.convertValues(source["columns"], TableColumn);
+	        this.row_count = source["row_count"];
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class TablePage {
+	    table: string;
+	    columns: TableColumn[];
+	    rows: any[];
+	    total: number;
+	    page: number;
+	    page_size: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new TablePage(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.table = source["table"];
+	        this.columns = this.convertValues(source["columns"], TableColumn);
+	        this.rows = source["rows"];
+	        this.total = source["total"];
+	        this.page = source["page"];
+	        this.page_size = source["page_size"];
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class TableQuery {
+	    table: string;
+	    page: number;
+	    page_size: number;
+	    sort_by: string;
+	    sort_desc: boolean;
+	    search: string;
+	    filters: Record<string, string>;
+	
+	    static createFrom(source: any = {}) {
+	        return new TableQuery(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.table = source["table"];
+	        this.page = source["page"];
+	        this.page_size = source["page_size"];
+	        this.sort_by = source["sort_by"];
+	        this.sort_desc = source["sort_desc"];
+	        this.search = source["search"];
+	        this.filters = source["filters"];
+	    }
 	}
 	export class User {
 	    id: number;
 	    username: string;
 	    email: string;
 	    user_role: string;
+	    must_change_password: boolean;
+	    two_factor_enabled: boolean;
 	    meta_data?: string;
+	    session_token?: string;
 	    created_at: string;
 	    updated_at: string;
+	    two_factor_required?: boolean;
+	    two_factor_token?: string;
 	
 	    static createFrom(source: any = {}) {
 	        return new User(source);
@@ -180,9 +2185,14 @@ export namespace services {
 	        this.username = source["username"];
 	        this.email = source["email"];
 	        this.user_role = source["user_role"];
+	        this.must_change_password = source["must_change_password"];
+	        this.two_factor_enabled = source["two_factor_enabled"];
 	        this.meta_data = source["meta_data"];
+	        this.session_token = source["session_token"];
 	        this.created_at = source["created_at"];
 	        this.updated_at = source["updated_at"];
+	        this.two_factor_required = source["two_factor_required"];
+	        this.two_factor_token = source["two_factor_token"];
 	    }
 	}
 
@@ -2,8 +2,10 @@
 // This file is automatically generated. DO NOT EDIT
 import {services} from '../models';
 
+export function GetSegmentCacheStats():Promise<services.SegmentCacheStats>;
+
 export function ProxyImage(arg1:string):Promise<services.ProxyImageResponse>;
 
 export function ProxyURL(arg1:string):Promise<services.ProxyURLResponse>;
 
-export function TestMediaSpeed(arg1:string):Promise<services.SpeedTestResult>;
+export function TestMediaSpeed(arg1:string,arg2:string):Promise<services.SpeedTestResult>;
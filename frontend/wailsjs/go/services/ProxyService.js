@@ -2,6 +2,10 @@
 // Cynhyrchwyd y ffeil hon yn awtomatig. PEIDIWCH Â MODIWL
 // This file is automatically generated. DO NOT EDIT
 
+export function GetSegmentCacheStats() {
+  return window['go']['services']['ProxyService']['GetSegmentCacheStats']();
+}
+
 export function ProxyImage(arg1) {
   return window['go']['services']['ProxyService']['ProxyImage'](arg1);
 }
@@ -10,6 +14,6 @@ export function ProxyURL(arg1) {
   return window['go']['services']['ProxyService']['ProxyURL'](arg1);
 }
 
-export function TestMediaSpeed(arg1) {
-  return window['go']['services']['ProxyService']['TestMediaSpeed'](arg1);
+export function TestMediaSpeed(arg1, arg2) {
+  return window['go']['services']['ProxyService']['TestMediaSpeed'](arg1, arg2);
 }
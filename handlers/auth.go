@@ -21,20 +21,168 @@ func (h *AuthHandler) Login(username, password string) (*services.User, error) {
 	return h.authService.Login(req)
 }
 
-// Signup creates a new user account
-// First user gets "admin" role, subsequent users get "member" role
-func (h *AuthHandler) Signup(username, email, password string) (*services.User, error) {
+// Signup creates a new user account with the "member" role or the invite code's role
+func (h *AuthHandler) Signup(username, email, password, inviteCode string) (*services.User, error) {
+	req := services.SignupRequest{
+		Username:   username,
+		Email:      email,
+		Password:   password,
+		InviteCode: inviteCode,
+	}
+	return h.authService.Signup(req)
+}
+
+// SetUserRole promotes or demotes a user
+func (h *AuthHandler) SetUserRole(actorID, targetID int, role string) error {
+	return h.authService.SetUserRole(actorID, targetID, role)
+}
+
+// SetUserDisabled disables or re-enables a user's account
+func (h *AuthHandler) SetUserDisabled(actorID, targetID int, disabled bool) error {
+	return h.authService.SetUserDisabled(actorID, targetID, disabled)
+}
+
+// DeleteUser removes a user and all of their data
+func (h *AuthHandler) DeleteUser(actorID, targetID int) error {
+	return h.authService.DeleteUser(actorID, targetID)
+}
+
+// SetTemporaryPassword generates a password the user must change at next login
+func (h *AuthHandler) SetTemporaryPassword(actorID, targetID int) (string, error) {
+	return h.authService.SetTemporaryPassword(actorID, targetID)
+}
+
+// Logout ends a session
+func (h *AuthHandler) Logout(sessionToken string) error {
+	return h.authService.Logout(sessionToken)
+}
+
+// ValidateSession returns the user a session belongs to
+func (h *AuthHandler) ValidateSession(sessionToken string) (*services.User, error) {
+	return h.authService.ValidateSession(sessionToken)
+}
+
+// ChangePassword updates the user's password and signs out their other sessions
+func (h *AuthHandler) ChangePassword(userID int, sessionToken, oldPassword, newPassword string) error {
+	return h.authService.ChangePassword(userID, sessionToken, oldPassword, newPassword)
+}
+
+// ChangeUsername renames the user after checking their password
+func (h *AuthHandler) ChangeUsername(userID int, password, newUsername string) error {
+	return h.authService.ChangeUsername(userID, password, newUsername)
+}
+
+// ChangeEmail updates the user's email after checking their password
+func (h *AuthHandler) ChangeEmail(userID int, password, newEmail string) error {
+	return h.authService.ChangeEmail(userID, password, newEmail)
+}
+
+// DeleteOwnAccount deletes the user's own account after checking their password
+func (h *AuthHandler) DeleteOwnAccount(userID int, password string) error {
+	return h.authService.DeleteOwnAccount(userID, password)
+}
+
+// UnlockAccount clears the login lockout of a username
+func (h *AuthHandler) UnlockAccount(actorID int, username string) error {
+	return h.authService.UnlockAccount(actorID, username)
+}
+
+// ListLoginLockouts returns usernames with recent failed logins
+func (h *AuthHandler) ListLoginLockouts() ([]services.LoginLockout, error) {
+	return h.authService.ListLoginLockouts()
+}
+
+// ListAuthEvents returns recent auth events
+func (h *AuthHandler) ListAuthEvents(username string, limit, offset int) ([]services.AuthEvent, error) {
+	return h.authService.ListAuthEvents(username, limit, offset)
+}
+
+// LoginWithSecondFactor completes a login that requires a TOTP or recovery code
+func (h *AuthHandler) LoginWithSecondFactor(challengeToken, code string) (*services.User, error) {
+	return h.authService.LoginWithSecondFactor(challengeToken, code)
+}
+
+// BeginTOTPEnrollment generates a TOTP secret and QR code for the user
+func (h *AuthHandler) BeginTOTPEnrollment(userID int) (*services.TOTPEnrollment, error) {
+	return h.authService.BeginTOTPEnrollment(userID)
+}
+
+// ConfirmTOTPEnrollment enables 2FA after the first valid code and returns recovery codes
+func (h *AuthHandler) ConfirmTOTPEnrollment(userID int, code string) ([]string, error) {
+	return h.authService.ConfirmTOTPEnrollment(userID, code)
+}
+
+// DisableTOTP turns off 2FA
+func (h *AuthHandler) DisableTOTP(userID int, password string) error {
+	return h.authService.DisableTOTP(userID, password)
+}
+
+// RegenerateRecoveryCodes issues a fresh set of recovery codes
+func (h *AuthHandler) RegenerateRecoveryCodes(userID int, password string) ([]string, error) {
+	return h.authService.RegenerateRecoveryCodes(userID, password)
+}
+
+// ListProfiles returns the viewer profiles of the user's account
+func (h *AuthHandler) ListProfiles(userID int) ([]services.Profile, error) {
+	return h.authService.ListProfiles(userID)
+}
+
+// CreateProfile adds a viewer profile with an optional PIN
+func (h *AuthHandler) CreateProfile(userID int, name, avatar, pin, parentalPIN string) (*services.Profile, error) {
+	return h.authService.CreateProfile(userID, name, avatar, pin, parentalPIN)
+}
+
+// SwitchProfile changes the active profile
+func (h *AuthHandler) SwitchProfile(userID, profileID int, pin, parentalPIN string) (*services.Profile, error) {
+	return h.authService.SwitchProfile(userID, profileID, pin, parentalPIN)
+}
+
+// DeleteProfile removes a profile and its data
+func (h *AuthHandler) DeleteProfile(userID, profileID int, pin, parentalPIN string) error {
+	return h.authService.DeleteProfile(userID, profileID, pin, parentalPIN)
+}
+
+// GetParentalRules returns a profile's parental controls
+func (h *AuthHandler) GetParentalRules(userID, profileID int) (*services.ParentalRules, error) {
+	return h.authService.GetParentalRules(userID, profileID)
+}
+
+// SetParentalRules replaces a profile's parental controls
+func (h *AuthHandler) SetParentalRules(actorID, profileID int, rules services.ParentalRules, pin string) error {
+	return h.authService.SetParentalRules(actorID, profileID, rules, pin)
+}
+
+// SetParentalPIN sets or clears the account's parental PIN
+func (h *AuthHandler) SetParentalPIN(userID int, password, pin string) error {
+	return h.authService.SetParentalPIN(userID, password, pin)
+}
+
+// CreateInviteCode issues an invite code
+func (h *AuthHandler) CreateInviteCode(adminID int, role string, maxUses, expiresInHours int) (*services.InviteCode, error) {
+	return h.authService.CreateInviteCode(adminID, role, maxUses, expiresInHours)
+}
+
+// ListInviteCodes returns all invite codes
+func (h *AuthHandler) ListInviteCodes() ([]services.InviteCode, error) {
+	return h.authService.ListInviteCodes()
+}
+
+// RevokeInviteCode deletes an invite code
+func (h *AuthHandler) RevokeInviteCode(actorID, codeID int) error {
+	return h.authService.RevokeInviteCode(actorID, codeID)
+}
+
+// NeedsSetup reports whether the first admin account still has to be created
+func (h *AuthHandler) NeedsSetup() (bool, error) {
+	return h.authService.NeedsSetup()
+}
+
+// SetupAdmin creates the first admin account
+func (h *AuthHandler) SetupAdmin(username, email, password string) (*services.User, error) {
 	req := services.SignupRequest{
 		Username: username,
 		Email:    email,
 		Password: password,
 	}
-	return h.authService.Signup(req)
+	return h.authService.SetupAdmin(req)
 }
-
-// Future auth methods can be added here:
-// - Logout
-// - GetCurrentUser
-// - UpdatePassword
-// - ResetPassword
-// - ValidateSession
\ No newline at end of file
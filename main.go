@@ -2,6 +2,7 @@ package main
 
 import (
 	"embed"
+	"net/http"
 
 	"github.com/wailsapp/wails/v2"
 	"github.com/wailsapp/wails/v2/pkg/options"
@@ -18,11 +19,17 @@ var assets embed.FS
 var migrations embed.FS
 
 func main() {
-	// Create an instance of the app structure
-	app := NewApp(migrations)
-
 	// Create service instances
 	proxyService := services.NewProxyService()
+	subtitleService := services.NewSubtitleService()
+
+	// Create an instance of the app structure
+	app := NewApp(migrations, proxyService, subtitleService)
+
+	// Serve proxied HLS and attached subtitles to the player
+	assetHandler := http.NewServeMux()
+	assetHandler.Handle("/proxy/", services.NewProxyHandler(proxyService))
+	assetHandler.Handle("/subtitles/", services.NewSubtitleHandler(subtitleService))
 
 	// Create application with options
 	err := wails.Run(&options.App{
@@ -30,7 +37,8 @@ func main() {
 		Width:  1024,
 		Height: 768,
 		AssetServer: &assetserver.Options{
-			Assets: assets,
+			Assets:  assets,
+			Handler: assetHandler,
 		},
 		BackgroundColour: &options.RGBA{R: 27, G: 38, B: 54, A: 1},
 		OnStartup:        app.startup,
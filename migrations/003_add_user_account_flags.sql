@@ -0,0 +1,6 @@
+-- Migration: 003_add_user_account_flags
+-- Description: Let admins disable accounts and force a password change at next login
+-- Created: 2026-10-18
+
+ALTER TABLE users ADD COLUMN disabled BOOLEAN NOT NULL DEFAULT 0;
+ALTER TABLE users ADD COLUMN must_change_password BOOLEAN NOT NULL DEFAULT 0;
@@ -0,0 +1,15 @@
+-- Migration: 004_create_sessions
+-- Description: Track login sessions so they can be validated and revoked
+-- Created: 2026-10-18
+
+CREATE TABLE IF NOT EXISTS sessions (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id INTEGER NOT NULL,
+    token_hash TEXT NOT NULL UNIQUE,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    last_seen_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    expires_at DATETIME NOT NULL
+);
+
+-- Create index for faster lookups
+CREATE INDEX IF NOT EXISTS idx_sessions_user_id ON sessions(user_id);
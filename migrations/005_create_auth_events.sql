@@ -0,0 +1,25 @@
+-- Migration: 005_create_auth_events
+-- Description: Record authentication events and throttle repeated login failures
+-- Created: 2026-10-18
+
+CREATE TABLE IF NOT EXISTS auth_events (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id INTEGER,
+    username TEXT NOT NULL,
+    event_type TEXT NOT NULL,
+    success BOOLEAN NOT NULL,
+    detail TEXT,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Create indexes for faster lookups
+CREATE INDEX IF NOT EXISTS idx_auth_events_username ON auth_events(username);
+CREATE INDEX IF NOT EXISTS idx_auth_events_created_at ON auth_events(created_at);
+
+
+CREATE TABLE IF NOT EXISTS login_attempts (
+    username TEXT PRIMARY KEY,
+    failed_count INTEGER NOT NULL DEFAULT 0,
+    last_failed_at DATETIME,
+    locked_until DATETIME
+);
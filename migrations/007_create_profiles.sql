@@ -0,0 +1,90 @@
+-- Migration: 007_create_profiles
+-- Description: Viewer profiles inside an account; bookmarks, history and personal settings move to per-profile keys
+-- Created: 2026-10-18
+
+CREATE TABLE IF NOT EXISTS profiles (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id INTEGER NOT NULL,
+    name TEXT NOT NULL,
+    avatar TEXT,
+    pin_hash TEXT,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE(user_id, name)
+);
+
+CREATE INDEX IF NOT EXISTS idx_profiles_user_id ON profiles(user_id);
+
+-- Every existing account gets one profile named after it, which becomes active
+INSERT INTO profiles (user_id, name) SELECT id, username FROM users;
+
+ALTER TABLE users ADD COLUMN active_profile_id INTEGER;
+UPDATE users SET active_profile_id = (SELECT id FROM profiles WHERE profiles.user_id = users.id);
+
+
+-- SQLite cannot alter UNIQUE constraints, so the per-user tables are rebuilt
+CREATE TABLE bookmarks_new (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id INTEGER NOT NULL,
+    profile_id INTEGER NOT NULL,
+    mc_id TEXT NOT NULL,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE(profile_id, mc_id)
+);
+
+INSERT INTO bookmarks_new (id, user_id, profile_id, mc_id, created_at)
+SELECT b.id, b.user_id, p.id, b.mc_id, b.created_at
+FROM bookmarks b JOIN profiles p ON p.user_id = b.user_id;
+
+DROP TABLE bookmarks;
+ALTER TABLE bookmarks_new RENAME TO bookmarks;
+
+CREATE INDEX IF NOT EXISTS idx_bookmarks_user_id ON bookmarks(user_id);
+CREATE INDEX IF NOT EXISTS idx_bookmarks_profile_id ON bookmarks(profile_id);
+CREATE INDEX IF NOT EXISTS idx_bookmarks_mc_id ON bookmarks(mc_id);
+
+
+CREATE TABLE history_new (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id INTEGER NOT NULL,
+    profile_id INTEGER NOT NULL,
+    mc_id TEXT NOT NULL,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE(profile_id, mc_id)
+);
+
+INSERT INTO history_new (id, user_id, profile_id, mc_id, created_at)
+SELECT h.id, h.user_id, p.id, h.mc_id, h.created_at
+FROM history h JOIN profiles p ON p.user_id = h.user_id;
+
+DROP TABLE history;
+ALTER TABLE history_new RENAME TO history;
+
+CREATE INDEX IF NOT EXISTS idx_history_user_id ON history(user_id);
+CREATE INDEX IF NOT EXISTS idx_history_profile_id ON history(profile_id);
+CREATE INDEX IF NOT EXISTS idx_history_mc_id ON history(mc_id);
+
+
+-- Global settings keep NULL user_id and profile_id
+CREATE TABLE settings_new (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id INTEGER,
+    profile_id INTEGER,
+    setting_key TEXT NOT NULL,
+    setting_value TEXT NOT NULL,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE(profile_id, setting_key)
+);
+
+INSERT INTO settings_new (id, user_id, profile_id, setting_key, setting_value, created_at, updated_at)
+SELECT s.id, s.user_id, p.id, s.setting_key, s.setting_value, s.created_at, s.updated_at
+FROM settings s LEFT JOIN profiles p ON p.user_id = s.user_id
+WHERE s.user_id IS NULL OR p.id IS NOT NULL;
+
+DROP TABLE settings;
+ALTER TABLE settings_new RENAME TO settings;
+
+CREATE INDEX IF NOT EXISTS idx_user_settings_user_id ON settings(user_id);
+CREATE INDEX IF NOT EXISTS idx_user_settings_profile_id ON settings(profile_id);
+CREATE INDEX IF NOT EXISTS idx_user_settings_key ON settings(setting_key);
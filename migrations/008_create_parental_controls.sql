@@ -0,0 +1,16 @@
+-- Migration: 008_create_parental_controls
+-- Description: Per-profile viewing restrictions, a parental PIN per account and content ratings on cached media
+-- Created: 2026-10-18
+
+CREATE TABLE IF NOT EXISTS parental_controls (
+    profile_id INTEGER PRIMARY KEY,
+    user_id INTEGER NOT NULL,
+    rules TEXT NOT NULL DEFAULT '{}', -- JSON encoded ParentalRules
+    updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_parental_controls_user_id ON parental_controls(user_id);
+
+ALTER TABLE users ADD COLUMN parental_pin_hash TEXT;
+
+ALTER TABLE medias ADD COLUMN content_rating TEXT;
@@ -0,0 +1,16 @@
+-- Migration: 009_create_invite_codes
+-- Description: Admin-generated invite codes for invite-only registration
+-- Created: 2026-10-18
+
+CREATE TABLE IF NOT EXISTS invite_codes (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    code TEXT NOT NULL UNIQUE,
+    user_role TEXT NOT NULL,
+    max_uses INTEGER NOT NULL DEFAULT 1, -- 0 for unlimited
+    use_count INTEGER NOT NULL DEFAULT 0,
+    expires_at DATETIME,
+    created_by INTEGER,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+ALTER TABLE users ADD COLUMN invite_code_id INTEGER;
@@ -0,0 +1,46 @@
+-- Migration: 010_create_roles_permissions
+-- Description: Roles with named permissions; users.user_role refers to roles.name
+-- Created: 2026-10-18
+
+CREATE TABLE IF NOT EXISTS roles (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    name TEXT NOT NULL UNIQUE,
+    description TEXT,
+    built_in BOOLEAN NOT NULL DEFAULT 0,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS role_permissions (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    role_id INTEGER NOT NULL,
+    permission TEXT NOT NULL,
+    UNIQUE(role_id, permission)
+);
+
+-- Create index for faster lookups
+CREATE INDEX IF NOT EXISTS idx_role_permissions_role_id ON role_permissions(role_id);
+
+INSERT INTO roles (name, description, built_in) VALUES
+    ('admin', 'Full access to every feature', 1),
+    ('member', 'Regular account', 1),
+    ('guest', 'Browsing without signing in', 1);
+
+-- The admin role implicitly holds every permission; its rows are listed for display
+INSERT INTO role_permissions (role_id, permission)
+SELECT r.id, p.permission
+FROM roles r
+JOIN (
+    SELECT 'admin' AS role, 'browse' AS permission
+    UNION ALL SELECT 'admin', 'download'
+    UNION ALL SELECT 'admin', 'post_comments'
+    UNION ALL SELECT 'admin', 'moderate_comments'
+    UNION ALL SELECT 'admin', 'manage_users'
+    UNION ALL SELECT 'admin', 'edit_global_settings'
+    UNION ALL SELECT 'admin', 'view_admin_db'
+    UNION ALL SELECT 'admin', 'manage_backups'
+    UNION ALL SELECT 'member', 'browse'
+    UNION ALL SELECT 'member', 'download'
+    UNION ALL SELECT 'member', 'post_comments'
+    UNION ALL SELECT 'guest', 'browse'
+) p ON p.role = r.name;
@@ -0,0 +1,28 @@
+-- Migration: 012_create_source_stats
+-- Description: Speed tests and playback errors per source host, and a rolling reputation per host
+-- Created: 2026-10-18
+
+CREATE TABLE IF NOT EXISTS source_stats (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    host TEXT NOT NULL,
+    mc_id TEXT NOT NULL DEFAULT '',
+    url TEXT NOT NULL DEFAULT '',
+    kind TEXT NOT NULL, -- speed_test or playback_error
+    speed_mbps REAL, -- NULL unless a speed test succeeded
+    error TEXT,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS host_reputation (
+    host TEXT PRIMARY KEY,
+    score REAL NOT NULL, -- 0 to 100, moving average of recent results
+    speed_tests INTEGER NOT NULL DEFAULT 0,
+    failures INTEGER NOT NULL DEFAULT 0,
+    avg_speed_mbps REAL,
+    updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Create indexes for faster lookups
+CREATE INDEX IF NOT EXISTS idx_source_stats_host ON source_stats(host, created_at);
+CREATE INDEX IF NOT EXISTS idx_source_stats_mc_id ON source_stats(mc_id);
+CREATE INDEX IF NOT EXISTS idx_source_stats_created_at ON source_stats(created_at);
@@ -0,0 +1,18 @@
+-- Migration: 013_create_source_checks
+-- Description: Last known status of each video source of bookmarked media
+-- Created: 2026-10-18
+
+CREATE TABLE IF NOT EXISTS source_checks (
+    mc_id TEXT NOT NULL,
+    label TEXT NOT NULL, -- key in medias.video_urls
+    url TEXT NOT NULL,
+    status TEXT NOT NULL, -- alive or dead
+    error TEXT,
+    dead_since DATETIME, -- first failed check of the current outage
+    checked_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (mc_id, label)
+);
+
+-- Create indexes for faster lookups
+CREATE INDEX IF NOT EXISTS idx_source_checks_status ON source_checks(status);
+CREATE INDEX IF NOT EXISTS idx_source_checks_checked_at ON source_checks(checked_at);
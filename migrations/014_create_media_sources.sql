@@ -0,0 +1,48 @@
+-- Migration: 014_create_media_sources
+-- Description: Structured video sources and episodes of each media, backfilled from medias.video_urls
+-- Created: 2026-10-18
+
+CREATE TABLE IF NOT EXISTS media_sources (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    mc_id TEXT NOT NULL,
+    name TEXT NOT NULL,
+    source_type TEXT NOT NULL DEFAULT 'm3u8',
+    health TEXT NOT NULL DEFAULT 'unknown', -- unknown, alive or dead
+    position INTEGER NOT NULL DEFAULT 0, -- the source at 0 is mirrored into medias.video_urls
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE(mc_id, name)
+);
+
+CREATE TABLE IF NOT EXISTS episodes (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    source_id INTEGER NOT NULL,
+    season INTEGER NOT NULL DEFAULT 1,
+    number INTEGER NOT NULL, -- counts from 1 in source order
+    title TEXT NOT NULL, -- label shown to the user, e.g. 第01集
+    url TEXT NOT NULL,
+    position INTEGER NOT NULL,
+    FOREIGN KEY (source_id) REFERENCES media_sources(id) ON DELETE CASCADE,
+    UNIQUE(source_id, position)
+);
+
+-- Create indexes for faster lookups
+CREATE INDEX IF NOT EXISTS idx_media_sources_mc_id ON media_sources(mc_id, position);
+CREATE INDEX IF NOT EXISTS idx_episodes_source_id ON episodes(source_id, season, number);
+
+-- Every cached media gets a default source holding the episodes of its video_urls
+INSERT OR IGNORE INTO media_sources (mc_id, name, source_type, position, created_at, updated_at)
+SELECT mc_id, 'default', COALESCE(NULLIF(video_url_type, ''), 'm3u8'), 0, created_at, updated_at
+FROM medias
+WHERE json_valid(video_urls) AND json_type(video_urls) = 'object';
+
+INSERT OR IGNORE INTO episodes (source_id, season, number, title, url, position)
+SELECT s.id, 1, e.position + 1, e.title, e.url, e.position
+FROM (
+    SELECT m.mc_id, j.key AS title, j.value AS url,
+        ROW_NUMBER() OVER (PARTITION BY m.mc_id ORDER BY j.id) - 1 AS position
+    FROM medias m, json_each(m.video_urls) j
+    WHERE json_valid(m.video_urls) AND json_type(m.video_urls) = 'object'
+        AND j.type = 'text' AND j.value != ''
+) e
+JOIN media_sources s ON s.mc_id = e.mc_id AND s.name = 'default';
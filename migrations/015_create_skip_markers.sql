@@ -0,0 +1,19 @@
+-- Migration: 015_create_skip_markers
+-- Description: Per-profile intro and outro markers of each series
+-- Created: 2026-10-18
+
+CREATE TABLE IF NOT EXISTS skip_markers (
+    user_id INTEGER NOT NULL,
+    profile_id INTEGER NOT NULL,
+    mc_id TEXT NOT NULL,
+    intro_start REAL, -- seconds from the start of an episode
+    intro_end REAL,
+    outro_start REAL, -- seconds before the end of an episode, so episodes of any length share them
+    outro_end REAL,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (profile_id, mc_id)
+);
+
+-- Create indexes for faster lookups
+CREATE INDEX IF NOT EXISTS idx_skip_markers_user_id ON skip_markers(user_id);
@@ -0,0 +1,23 @@
+-- Migration: 016_create_subtitles
+-- Description: Subtitle files attached to an episode of a media, converted to WebVTT
+-- Created: 2026-10-18
+
+CREATE TABLE IF NOT EXISTS subtitles (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    mc_id TEXT NOT NULL,
+    episode TEXT NOT NULL, -- episode label, as in medias.video_urls
+    label TEXT NOT NULL, -- name shown in the player's subtitle menu
+    file_name TEXT NOT NULL,
+    format TEXT NOT NULL, -- srt, ass or vtt
+    charset TEXT NOT NULL, -- charset the file was decoded from
+    offset_ms INTEGER NOT NULL DEFAULT 0,
+    source TEXT NOT NULL, -- decoded original, converted again when the offset changes
+    vtt TEXT NOT NULL,
+    cues INTEGER NOT NULL DEFAULT 0,
+    user_id INTEGER NOT NULL, -- who attached it
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Create indexes for faster lookups
+CREATE INDEX IF NOT EXISTS idx_subtitles_mc_id ON subtitles(mc_id, episode);
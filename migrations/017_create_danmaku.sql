@@ -0,0 +1,23 @@
+-- Migration: 017_create_danmaku
+-- Description: Bullet comments for an episode of a media, imported from danmaku files or posted locally
+-- Created: 2026-10-18
+
+CREATE TABLE IF NOT EXISTS danmaku (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    mc_id TEXT NOT NULL,
+    episode TEXT NOT NULL, -- episode label, as in medias.video_urls
+    time_ms INTEGER NOT NULL, -- position in the episode
+    mode TEXT NOT NULL DEFAULT 'scroll', -- scroll, top or bottom
+    color INTEGER NOT NULL DEFAULT 16777215, -- 0xRRGGBB
+    size INTEGER NOT NULL DEFAULT 25,
+    content TEXT NOT NULL,
+    author TEXT NOT NULL DEFAULT '', -- sender hash from the imported file, or the poster's username
+    origin_id TEXT, -- id in the imported file so importing it again adds nothing; NULL for local posts
+    user_id INTEGER NOT NULL DEFAULT 0, -- poster of a local comment; 0 for imported ones
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Create indexes for faster lookups
+CREATE INDEX IF NOT EXISTS idx_danmaku_mc_id ON danmaku(mc_id, episode, time_ms);
+CREATE INDEX IF NOT EXISTS idx_danmaku_user_id ON danmaku(user_id);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_danmaku_origin ON danmaku(mc_id, episode, origin_id) WHERE origin_id IS NOT NULL;
@@ -0,0 +1,27 @@
+-- Migration: 018_add_source_to_source_checks
+-- Description: Key link check results by media source, now that every source of a title is checked
+-- Created: 2026-10-18
+
+CREATE TABLE IF NOT EXISTS source_checks_new (
+    mc_id TEXT NOT NULL,
+    source TEXT NOT NULL DEFAULT 'default', -- media_sources.name
+    label TEXT NOT NULL, -- episode title within the source
+    url TEXT NOT NULL,
+    status TEXT NOT NULL, -- alive or dead
+    error TEXT,
+    dead_since DATETIME, -- first failed check of the current outage
+    checked_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (mc_id, source, label)
+);
+
+-- Earlier checks only covered video_urls, which mirrors the default source
+INSERT OR IGNORE INTO source_checks_new (mc_id, source, label, url, status, error, dead_since, checked_at)
+SELECT mc_id, 'default', label, url, status, error, dead_since, checked_at
+FROM source_checks;
+
+DROP TABLE source_checks;
+ALTER TABLE source_checks_new RENAME TO source_checks;
+
+-- Create indexes for faster lookups
+CREATE INDEX IF NOT EXISTS idx_source_checks_status ON source_checks(status);
+CREATE INDEX IF NOT EXISTS idx_source_checks_checked_at ON source_checks(checked_at);
@@ -0,0 +1,18 @@
+-- Migration: 019_create_pin_attempts
+-- Description: Throttle wrong profile and parental PINs apart from login attempts
+-- Created: 2026-10-18
+
+CREATE TABLE IF NOT EXISTS pin_attempts (
+    pin_key TEXT PRIMARY KEY, -- profile:<profile id> or parental:<user id>
+    failed_count INTEGER NOT NULL DEFAULT 0,
+    last_failed_at DATETIME,
+    locked_until DATETIME
+);
+
+-- PIN failures used to share login_attempts
+INSERT OR IGNORE INTO pin_attempts (pin_key, failed_count, last_failed_at, locked_until)
+SELECT username, failed_count, last_failed_at, locked_until
+FROM login_attempts
+WHERE username LIKE 'profile:%' OR username LIKE 'parental:%';
+
+DELETE FROM login_attempts WHERE username LIKE 'profile:%' OR username LIKE 'parental:%';
@@ -0,0 +1,162 @@
+package services
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+)
+
+// ChangePassword replaces the user's password after verifying the old one.
+// Every other session of the user is revoked; the session identified by sessionToken survives.
+func (as *AuthService) ChangePassword(userID int, sessionToken, oldPassword, newPassword string) error {
+	if len(newPassword) < 6 {
+		return fmt.Errorf("password must be at least 6 characters long")
+	}
+
+	if err := as.checkPassword(userID, oldPassword); err != nil {
+		return err
+	}
+
+	hashedPassword, err := as.hashPassword(newPassword)
+	if err != nil {
+		return fmt.Errorf("failed to hash password: %w", err)
+	}
+
+	tx, err := as.db.GetDB().Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(`
+		UPDATE users
+		SET password_hash = ?, must_change_password = 0, updated_at = CURRENT_TIMESTAMP
+		WHERE id = ?
+	`, hashedPassword, userID)
+	if err != nil {
+		return fmt.Errorf("failed to update password: %w", err)
+	}
+
+	if err := revokeOtherSessions(tx, userID, sessionToken); err != nil {
+		return fmt.Errorf("failed to revoke sessions: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	var username string
+	if err := as.db.GetDB().QueryRow("SELECT username FROM users WHERE id = ?", userID).Scan(&username); err == nil {
+		as.recordAuthEvent(&userID, throttleKey(username), AuthEventPasswordChanged, true, "")
+	}
+	return nil
+}
+
+// ChangeUsername renames the user after verifying their password. Usernames and
+// emails share one namespace because either can be used to log in.
+func (as *AuthService) ChangeUsername(userID int, password, newUsername string) error {
+	newUsername = strings.TrimSpace(newUsername)
+	if newUsername == "" {
+		return fmt.Errorf("username is required")
+	}
+	if err := as.checkPassword(userID, password); err != nil {
+		return err
+	}
+
+	taken, err := as.loginNameTaken(userID, newUsername)
+	if err != nil {
+		return err
+	}
+	if taken {
+		return fmt.Errorf("username already exists")
+	}
+
+	return as.updateUserField(userID, "username", newUsername)
+}
+
+// ChangeEmail updates the user's email address after verifying their password
+func (as *AuthService) ChangeEmail(userID int, password, newEmail string) error {
+	newEmail = strings.TrimSpace(newEmail)
+	if newEmail == "" || !strings.Contains(newEmail, "@") {
+		return fmt.Errorf("a valid email is required")
+	}
+	if err := as.checkPassword(userID, password); err != nil {
+		return err
+	}
+
+	taken, err := as.loginNameTaken(userID, newEmail)
+	if err != nil {
+		return err
+	}
+	if taken {
+		return fmt.Errorf("email already registered")
+	}
+
+	return as.updateUserField(userID, "email", newEmail)
+}
+
+// DeleteOwnAccount removes the user and all of their data after verifying their password
+func (as *AuthService) DeleteOwnAccount(userID int, password string) error {
+	if err := as.checkPassword(userID, password); err != nil {
+		return err
+	}
+	return as.DeleteUser(userID, userID)
+}
+
+// checkPassword verifies password against the user's stored hash. Wrong guesses
+// count against the same throttle as logins, so a session cannot be used to brute-force it.
+func (as *AuthService) checkPassword(userID int, password string) error {
+	var username, passwordHash string
+	err := as.db.GetDB().QueryRow("SELECT username, password_hash FROM users WHERE id = ?", userID).Scan(&username, &passwordHash)
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("user not found")
+	} else if err != nil {
+		return fmt.Errorf("failed to query user: %w", err)
+	}
+
+	key := throttleKey(username)
+	if err := as.checkLoginAllowed(key); err != nil {
+		return err
+	}
+
+	valid, err := as.verifyPassword(password, passwordHash)
+	if err != nil {
+		return fmt.Errorf("failed to verify password: %w", err)
+	}
+	if !valid {
+		as.recordLoginFailure(key)
+		return fmt.Errorf("incorrect password")
+	}
+
+	as.clearLoginFailures(key)
+	return nil
+}
+
+// loginNameTaken reports whether another user already uses name as username or email
+func (as *AuthService) loginNameTaken(userID int, name string) (bool, error) {
+	var exists bool
+	err := as.db.GetDB().QueryRow(`
+		SELECT EXISTS(SELECT 1 FROM users WHERE (username = ? OR email = ?) AND id != ?)
+	`, name, name, userID).Scan(&exists)
+	if err != nil {
+		return false, fmt.Errorf("failed to check uniqueness: %w", err)
+	}
+	return exists, nil
+}
+
+// updateUserField sets a single column of the user; column must be a trusted name
+func (as *AuthService) updateUserField(userID int, column, value string) error {
+	result, err := as.db.GetDB().Exec(
+		"UPDATE users SET "+column+" = ?, updated_at = CURRENT_TIMESTAMP WHERE id = ?",
+		value, userID,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update %s: %w", column, friendlyConstraintError(err))
+	}
+	if affected, err := result.RowsAffected(); err != nil {
+		return err
+	} else if affected == 0 {
+		return fmt.Errorf("user not found")
+	}
+	return nil
+}
@@ -0,0 +1,470 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/url"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// defaultMaxAdBlockSeconds is the longest run between discontinuities that is
+// considered an ad unless a rule says otherwise
+const defaultMaxAdBlockSeconds = 120
+
+// adDurationTolerance is how far a segment may be from a duration signature and still match it
+const adDurationTolerance = 0.05
+
+// AdFilterRule tunes ad stripping for playlists served from Host.
+// Host "*" sets the rule for hosts without one of their own.
+type AdFilterRule struct {
+	Host              string    `json:"host"`
+	Disabled          bool      `json:"disabled"`
+	AdHosts           []string  `json:"adHosts"`           // segment hosts that only ever serve ads
+	SegmentPatterns   []string  `json:"segmentPatterns"`   // regular expressions matched against segment URLs
+	AdDurations       []float64 `json:"adDurations"`       // segment durations that mark an inserted block
+	MaxAdBlockSeconds float64   `json:"maxAdBlockSeconds"` // longer blocks are kept unless a pattern matches
+
+	patterns []*regexp.Regexp
+}
+
+// StrippedAdBlock describes one block removed from a playlist
+type StrippedAdBlock struct {
+	FirstSegment int     `json:"firstSegment"`
+	Segments     int     `json:"segments"`
+	Seconds      float64 `json:"seconds"`
+	Reason       string  `json:"reason"`
+	SampleURL    string  `json:"sampleUrl"`
+}
+
+// AdFilterReport tells the player what was stripped from a playlist
+type AdFilterReport struct {
+	Applied         bool              `json:"applied"`
+	Rule            string            `json:"rule"`
+	RemovedSegments int               `json:"removedSegments"`
+	RemovedSeconds  float64           `json:"removedSeconds"`
+	Blocks          []StrippedAdBlock `json:"blocks"`
+	Skipped         string            `json:"skipped,omitempty"`
+}
+
+// hlsSegment is a media segment with the tags that precede its URI
+type hlsSegment struct {
+	tags     []string
+	uri      string
+	duration float64
+}
+
+// hlsRun is a stretch of segments between two discontinuities
+type hlsRun struct {
+	discontinuity bool
+	segments      []hlsSegment
+}
+
+// hlsHeaderTags apply to the whole playlist rather than to the next segment
+var hlsHeaderTags = []string{
+	"#EXTM3U",
+	"#EXT-X-VERSION",
+	"#EXT-X-TARGETDURATION",
+	"#EXT-X-MEDIA-SEQUENCE",
+	"#EXT-X-DISCONTINUITY-SEQUENCE",
+	"#EXT-X-PLAYLIST-TYPE",
+	"#EXT-X-INDEPENDENT-SEGMENTS",
+	"#EXT-X-ALLOW-CACHE",
+	"#EXT-X-START",
+}
+
+// parseAdFilterRules decodes and validates the proxy_ad_filter_rules setting
+func parseAdFilterRules(value string) ([]AdFilterRule, error) {
+	var rules []AdFilterRule
+	if err := json.Unmarshal([]byte(value), &rules); err != nil {
+		return nil, fmt.Errorf("proxy_ad_filter_rules must be a JSON list of rules: %w", err)
+	}
+
+	seen := map[string]bool{}
+	for i := range rules {
+		rule := &rules[i]
+		rule.Host = strings.ToLower(strings.TrimSpace(rule.Host))
+		if rule.Host == "" {
+			return nil, fmt.Errorf("ad filter rule %d has no host", i+1)
+		}
+		if seen[rule.Host] {
+			return nil, fmt.Errorf("ad filter rule for %s is listed twice", rule.Host)
+		}
+		seen[rule.Host] = true
+		if rule.MaxAdBlockSeconds < 0 {
+			return nil, fmt.Errorf("ad filter rule for %s has a negative maxAdBlockSeconds", rule.Host)
+		}
+		for _, pattern := range rule.SegmentPatterns {
+			re, err := regexp.Compile(pattern)
+			if err != nil {
+				return nil, fmt.Errorf("ad filter rule for %s has an invalid pattern %q: %w", rule.Host, pattern, err)
+			}
+			rule.patterns = append(rule.patterns, re)
+		}
+		for j, host := range rule.AdHosts {
+			rule.AdHosts[j] = strings.ToLower(strings.TrimSpace(host))
+		}
+	}
+	return rules, nil
+}
+
+// adFilterRuleFor picks the most specific rule for a playlist host.
+// A rule for example.com also covers cdn.example.com.
+func adFilterRuleFor(rules []AdFilterRule, host string) AdFilterRule {
+	host = strings.ToLower(host)
+	best := AdFilterRule{Host: "*"}
+	bestLen := -1
+	for _, rule := range rules {
+		if rule.Host == "*" {
+			if bestLen < 0 {
+				best = rule
+				bestLen = 0
+			}
+			continue
+		}
+		if hostMatches(host, rule.Host) && len(rule.Host) > bestLen {
+			best = rule
+			bestLen = len(rule.Host)
+		}
+	}
+	return best
+}
+
+// FilterAdSegments removes inserted ad blocks from a media playlist.
+// Blocks are the runs between #EXT-X-DISCONTINUITY markers; a run is stripped when
+// its segments match the rule's hosts or patterns, or when it is short and either
+// comes from a different host or path than the main content or matches a duration
+// signature. Master and live playlists are returned unchanged.
+func FilterAdSegments(playlist, playlistURL string, rule AdFilterRule) (string, *AdFilterReport) {
+	report := &AdFilterReport{Rule: rule.Host, Blocks: []StrippedAdBlock{}}
+	if rule.Disabled {
+		report.Skipped = "disabled for this host"
+		return playlist, report
+	}
+	if strings.Contains(playlist, "#EXT-X-STREAM-INF") {
+		report.Skipped = "master playlist"
+		return playlist, report
+	}
+	if !strings.Contains(playlist, "#EXT-X-ENDLIST") && !strings.Contains(playlist, "#EXT-X-PLAYLIST-TYPE:VOD") {
+		// Dropping discontinuities would break the discontinuity sequence of a live playlist
+		report.Skipped = "live playlist"
+		return playlist, report
+	}
+
+	header, runs, trailer := parseHLSRuns(playlist)
+	if len(runs) < 2 && len(rule.patterns) == 0 && len(rule.AdHosts) == 0 {
+		report.Skipped = "no discontinuities"
+		return playlist, report
+	}
+
+	maxBlock := rule.MaxAdBlockSeconds
+	if maxBlock == 0 {
+		maxBlock = defaultMaxAdBlockSeconds
+	}
+
+	mainHost, mainDir, total := dominantSource(runs, playlistURL)
+
+	remove := make([]string, len(runs))
+	removedSeconds := 0.0
+	segmentIndex := 0
+	for i, run := range runs {
+		seconds := runSeconds(run)
+		if reason := rule.adReason(run, playlistURL, mainHost, mainDir, seconds, maxBlock, len(runs) > 1); reason != "" {
+			remove[i] = reason
+			removedSeconds += seconds
+			report.Blocks = append(report.Blocks, StrippedAdBlock{
+				FirstSegment: segmentIndex,
+				Segments:     len(run.segments),
+				Seconds:      roundSeconds(seconds),
+				Reason:       reason,
+				SampleURL:    resolveURL(playlistURL, run.segments[0].uri),
+			})
+			report.RemovedSegments += len(run.segments)
+		}
+		segmentIndex += len(run.segments)
+	}
+
+	if report.RemovedSegments == 0 {
+		return playlist, report
+	}
+	if removedSeconds*2 > total {
+		// Whatever matched is more likely the content than an ad
+		report.Skipped = fmt.Sprintf("would strip %.0f of %.0f seconds", removedSeconds, total)
+		report.Blocks = []StrippedAdBlock{}
+		report.RemovedSegments = 0
+		return playlist, report
+	}
+
+	report.Applied = true
+	report.RemovedSeconds = roundSeconds(removedSeconds)
+	return renderHLSRuns(header, runs, remove, trailer), report
+}
+
+// adReason explains why a run is an ad, or returns "" to keep it
+func (r AdFilterRule) adReason(run hlsRun, playlistURL, mainHost, mainDir string, seconds, maxBlock float64, hasDiscontinuities bool) string {
+	matched := 0
+	for _, seg := range run.segments {
+		segURL := resolveURL(playlistURL, seg.uri)
+		if r.matchesAdSegment(segURL) {
+			matched++
+		}
+	}
+	if matched == len(run.segments) {
+		return "matches an ad pattern"
+	}
+
+	// The remaining signals need a discontinuity-delimited block short enough to be an ad
+	if !hasDiscontinuities || seconds > maxBlock {
+		return ""
+	}
+
+	host, dir := runSource(run, playlistURL)
+	if mainHost != "" && host != mainHost {
+		return "served from " + host
+	}
+	if mainDir != "" && dir != mainDir {
+		return "served from a different path"
+	}
+	if len(r.AdDurations) > 0 && r.matchesDurationSignature(run) {
+		return "matches an ad duration signature"
+	}
+	return ""
+}
+
+// matchesAdSegment reports whether a segment URL hits the rule's ad hosts or patterns
+func (r AdFilterRule) matchesAdSegment(segURL string) bool {
+	if u, err := url.Parse(segURL); err == nil {
+		for _, adHost := range r.AdHosts {
+			if adHost != "" && hostMatches(strings.ToLower(u.Hostname()), adHost) {
+				return true
+			}
+		}
+	}
+	for _, re := range r.patterns {
+		if re.MatchString(segURL) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchesDurationSignature reports whether every segment of the run has one of the ad durations
+func (r AdFilterRule) matchesDurationSignature(run hlsRun) bool {
+	for _, seg := range run.segments {
+		found := false
+		for _, d := range r.AdDurations {
+			if math.Abs(seg.duration-d) <= adDurationTolerance {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// parseHLSRuns splits a media playlist into header lines, runs of segments and trailing lines
+func parseHLSRuns(playlist string) ([]string, []hlsRun, []string) {
+	lines := strings.Split(strings.ReplaceAll(playlist, "\r\n", "\n"), "\n")
+
+	var header []string
+	i := 0
+	for ; i < len(lines); i++ {
+		line := strings.TrimSpace(lines[i])
+		if line == "" || isHLSHeaderTag(line) {
+			header = append(header, lines[i])
+			continue
+		}
+		break
+	}
+
+	var runs []hlsRun
+	var pending []string
+	discontinuity := false
+	for ; i < len(lines); i++ {
+		line := strings.TrimSpace(lines[i])
+		switch {
+		case line == "":
+			continue
+		case line == "#EXT-X-DISCONTINUITY":
+			discontinuity = true
+		case strings.HasPrefix(line, "#"):
+			pending = append(pending, line)
+		default:
+			seg := hlsSegment{tags: pending, uri: line, duration: extinfDuration(pending)}
+			if discontinuity || len(runs) == 0 {
+				runs = append(runs, hlsRun{discontinuity: discontinuity})
+				discontinuity = false
+			}
+			runs[len(runs)-1].segments = append(runs[len(runs)-1].segments, seg)
+			pending = nil
+		}
+	}
+
+	// Tags after the last segment, such as #EXT-X-ENDLIST
+	return header, runs, pending
+}
+
+// renderHLSRuns writes the playlist back without the removed runs
+func renderHLSRuns(header []string, runs []hlsRun, remove []string, trailer []string) string {
+	var b strings.Builder
+	for _, line := range header {
+		if strings.TrimSpace(line) != "" {
+			b.WriteString(line)
+			b.WriteString("\n")
+		}
+	}
+
+	emitted := false
+	for i, run := range runs {
+		if remove[i] != "" {
+			// The key and init section in effect after the block still apply to what follows it
+			for _, tag := range lastStateTags(run) {
+				b.WriteString(tag)
+				b.WriteString("\n")
+			}
+			continue
+		}
+		if run.discontinuity && emitted {
+			b.WriteString("#EXT-X-DISCONTINUITY\n")
+		}
+		for _, seg := range run.segments {
+			for _, tag := range seg.tags {
+				b.WriteString(tag)
+				b.WriteString("\n")
+			}
+			b.WriteString(seg.uri)
+			b.WriteString("\n")
+		}
+		emitted = true
+	}
+
+	for _, line := range trailer {
+		b.WriteString(line)
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+// lastStateTags returns the last #EXT-X-KEY and #EXT-X-MAP of a run
+func lastStateTags(run hlsRun) []string {
+	var key, initMap string
+	for _, seg := range run.segments {
+		for _, tag := range seg.tags {
+			if strings.HasPrefix(tag, "#EXT-X-KEY:") {
+				key = tag
+			} else if strings.HasPrefix(tag, "#EXT-X-MAP:") {
+				initMap = tag
+			}
+		}
+	}
+	var tags []string
+	if key != "" {
+		tags = append(tags, key)
+	}
+	if initMap != "" {
+		tags = append(tags, initMap)
+	}
+	return tags
+}
+
+// dominantSource returns the host and directory that serve most of the playlist by duration, and its length
+func dominantSource(runs []hlsRun, playlistURL string) (string, string, float64) {
+	hostSeconds := map[string]float64{}
+	dirSeconds := map[string]float64{}
+	total := 0.0
+	for _, run := range runs {
+		for _, seg := range run.segments {
+			host, dir := segmentSource(resolveURL(playlistURL, seg.uri))
+			hostSeconds[host] += seg.duration
+			dirSeconds[host+dir] += seg.duration
+			total += seg.duration
+		}
+	}
+
+	mainHost := heaviest(hostSeconds)
+	mainDir := heaviest(dirSeconds)
+	return mainHost, strings.TrimPrefix(mainDir, mainHost), total
+}
+
+// runSource returns the host and directory that serve most of a run
+func runSource(run hlsRun, playlistURL string) (string, string) {
+	hostSeconds := map[string]float64{}
+	dirSeconds := map[string]float64{}
+	for _, seg := range run.segments {
+		host, dir := segmentSource(resolveURL(playlistURL, seg.uri))
+		hostSeconds[host] += seg.duration
+		dirSeconds[host+dir] += seg.duration
+	}
+	host := heaviest(hostSeconds)
+	return host, strings.TrimPrefix(heaviest(dirSeconds), host)
+}
+
+func segmentSource(segURL string) (string, string) {
+	u, err := url.Parse(segURL)
+	if err != nil {
+		return "", ""
+	}
+	return strings.ToLower(u.Host), path.Dir(u.Path)
+}
+
+// heaviest returns the key with the largest weight, breaking ties by key for stable results
+func heaviest(weights map[string]float64) string {
+	best := ""
+	bestWeight := -1.0
+	for key, weight := range weights {
+		if weight > bestWeight || (weight == bestWeight && key < best) {
+			best = key
+			bestWeight = weight
+		}
+	}
+	return best
+}
+
+func runSeconds(run hlsRun) float64 {
+	total := 0.0
+	for _, seg := range run.segments {
+		total += seg.duration
+	}
+	return total
+}
+
+// extinfDuration reads the duration from a segment's #EXTINF tag
+func extinfDuration(tags []string) float64 {
+	for _, tag := range tags {
+		if !strings.HasPrefix(tag, "#EXTINF:") {
+			continue
+		}
+		value := strings.TrimPrefix(tag, "#EXTINF:")
+		if comma := strings.Index(value, ","); comma >= 0 {
+			value = value[:comma]
+		}
+		if d, err := strconv.ParseFloat(strings.TrimSpace(value), 64); err == nil {
+			return d
+		}
+	}
+	return 0
+}
+
+func isHLSHeaderTag(line string) bool {
+	for _, tag := range hlsHeaderTags {
+		if line == tag || strings.HasPrefix(line, tag+":") {
+			return true
+		}
+	}
+	return false
+}
+
+// hostMatches reports whether host is domain or one of its subdomains
+func hostMatches(host, domain string) bool {
+	return host == domain || strings.HasSuffix(host, "."+domain)
+}
+
+func roundSeconds(s float64) float64 {
+	return math.Round(s*1000) / 1000
+}
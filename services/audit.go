@@ -0,0 +1,231 @@
+package services
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// Actions recorded in audit_log
+const (
+	AuditSettingUpdated  = "setting_updated"
+	AuditSettingDeleted  = "setting_deleted"
+	AuditMediaDeleted    = "media_deleted"
+	AuditMediaRated      = "media_rating_changed"
+	AuditUserRoleChanged = "user_role_changed"
+	AuditUserDisabled    = "user_disabled_changed"
+	AuditUserDeleted     = "user_deleted"
+	AuditPasswordReset   = "password_reset"
+	AuditAccountUnlocked = "account_unlocked"
+	AuditInviteCreated   = "invite_created"
+	AuditInviteRevoked   = "invite_revoked"
+	AuditRoleSaved       = "role_saved"
+	AuditRoleDeleted     = "role_deleted"
+	AuditParentalRules   = "parental_rules_changed"
+	AuditBackupRestored  = "backup_restored"
+	AuditUserDataImport  = "user_data_imported"
+)
+
+const (
+	defaultAuditPageSize = 50
+	maxAuditPageSize     = 500
+)
+
+// Target types recorded in audit_log
+const (
+	AuditTargetSetting = "setting"
+	AuditTargetMedia   = "media"
+	AuditTargetUser    = "user"
+	AuditTargetInvite  = "invite"
+	AuditTargetRole    = "role"
+	AuditTargetProfile = "profile"
+	AuditTargetBackup  = "backup"
+)
+
+// AuditEntry is a row of the admin audit log. Before and After hold JSON, or
+// are empty when there was nothing before (a create) or after (a delete).
+type AuditEntry struct {
+	ID         int    `json:"id"`
+	ActorID    *int   `json:"actor_id"`
+	ActorName  string `json:"actor_name"`
+	Action     string `json:"action"`
+	TargetType string `json:"target_type"`
+	TargetID   string `json:"target_id"`
+	Before     string `json:"before,omitempty"`
+	After      string `json:"after,omitempty"`
+	CreatedAt  string `json:"created_at"`
+}
+
+// AuditQuery selects a page of the audit log. Zero values match everything;
+// Since and Until are "YYYY-MM-DD" or "YYYY-MM-DD HH:MM:SS" in UTC.
+type AuditQuery struct {
+	ActorID    int    `json:"actor_id"`
+	Action     string `json:"action"`
+	TargetType string `json:"target_type"`
+	TargetID   string `json:"target_id"`
+	Since      string `json:"since"`
+	Until      string `json:"until"`
+	Page       int    `json:"page"`
+	PageSize   int    `json:"page_size"`
+}
+
+// AuditPage is one page of audit entries, newest first
+type AuditPage struct {
+	Entries  []AuditEntry `json:"entries"`
+	Total    int          `json:"total"`
+	Page     int          `json:"page"`
+	PageSize int          `json:"page_size"`
+}
+
+// execer is satisfied by both *sql.DB and *sql.Tx
+type execer interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+}
+
+// recordAudit appends to the audit log. It is called with the transaction that
+// made the change so the change and its record are committed together.
+func recordAudit(e execer, actorID int, action, targetType, targetID string, before, after interface{}) error {
+	return recordAuditAs(e, actorID, "", action, targetType, targetID, before, after)
+}
+
+// recordAuditAs is recordAudit for a database the actor's row may not be in,
+// such as a staged restore; an empty actorName falls back to looking it up.
+func recordAuditAs(e execer, actorID int, actorName, action, targetType, targetID string, before, after interface{}) error {
+	beforeJSON, err := auditValue(before)
+	if err != nil {
+		return err
+	}
+	afterJSON, err := auditValue(after)
+	if err != nil {
+		return err
+	}
+
+	// The actor's name is copied so entries stay readable after the account is deleted
+	_, err = e.Exec(`
+		INSERT INTO audit_log (actor_id, actor_name, action, target_type, target_id, before_value, after_value, created_at)
+		VALUES (NULLIF(?, 0), COALESCE(NULLIF(?, ''), (SELECT username FROM users WHERE id = ?), ''), ?, ?, ?, ?, ?, CURRENT_TIMESTAMP)
+	`, actorID, actorName, actorID, action, targetType, targetID, beforeJSON, afterJSON)
+	if err != nil {
+		return fmt.Errorf("failed to record audit entry: %w", err)
+	}
+	return nil
+}
+
+// QueryAuditLog returns a filtered page of the audit log, newest first
+func (ds *DatabaseService) QueryAuditLog(q AuditQuery) (*AuditPage, error) {
+	if q.Page < 1 {
+		q.Page = 1
+	}
+	if q.PageSize <= 0 {
+		q.PageSize = defaultAuditPageSize
+	}
+	if q.PageSize > maxAuditPageSize {
+		q.PageSize = maxAuditPageSize
+	}
+
+	var conditions []string
+	var args []interface{}
+	if q.ActorID > 0 {
+		conditions = append(conditions, "actor_id = ?")
+		args = append(args, q.ActorID)
+	}
+	if q.Action != "" {
+		conditions = append(conditions, "action = ?")
+		args = append(args, q.Action)
+	}
+	if q.TargetType != "" {
+		conditions = append(conditions, "target_type = ?")
+		args = append(args, q.TargetType)
+	}
+	if q.TargetID != "" {
+		conditions = append(conditions, "target_id = ?")
+		args = append(args, q.TargetID)
+	}
+	if q.Since != "" {
+		since, err := normalizeAuditTime(q.Since, false)
+		if err != nil {
+			return nil, err
+		}
+		conditions = append(conditions, "created_at >= ?")
+		args = append(args, since)
+	}
+	if q.Until != "" {
+		until, err := normalizeAuditTime(q.Until, true)
+		if err != nil {
+			return nil, err
+		}
+		conditions = append(conditions, "created_at <= ?")
+		args = append(args, until)
+	}
+
+	where := ""
+	if len(conditions) > 0 {
+		where = " WHERE " + strings.Join(conditions, " AND ")
+	}
+
+	page := &AuditPage{Entries: []AuditEntry{}, Page: q.Page, PageSize: q.PageSize}
+	if err := ds.db.QueryRow("SELECT COUNT(*) FROM audit_log"+where, args...).Scan(&page.Total); err != nil {
+		return nil, fmt.Errorf("failed to count audit entries: %w", err)
+	}
+
+	rows, err := ds.db.Query(`
+		SELECT id, actor_id, actor_name, action, target_type, target_id,
+			COALESCE(before_value, ''), COALESCE(after_value, ''), created_at
+		FROM audit_log`+where+`
+		ORDER BY id DESC LIMIT ? OFFSET ?
+	`, append(args, q.PageSize, (q.Page-1)*q.PageSize)...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query audit log: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var e AuditEntry
+		var actorID sql.NullInt64
+		if err := rows.Scan(&e.ID, &actorID, &e.ActorName, &e.Action, &e.TargetType, &e.TargetID,
+			&e.Before, &e.After, &e.CreatedAt); err != nil {
+			return nil, err
+		}
+		if actorID.Valid {
+			id := int(actorID.Int64)
+			e.ActorID = &id
+		}
+		page.Entries = append(page.Entries, e)
+	}
+	return page, rows.Err()
+}
+
+// auditValue encodes a before or after value as JSON; nil stays NULL
+func auditValue(v interface{}) (interface{}, error) {
+	if v == nil {
+		return nil, nil
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode audit value: %w", err)
+	}
+	// Typed nils such as a nil map also mean "nothing"
+	if string(data) == "null" {
+		return nil, nil
+	}
+	return string(data), nil
+}
+
+// normalizeAuditTime turns a date or timestamp filter into the created_at format.
+// A bare date used as an upper bound covers the whole day.
+func normalizeAuditTime(value string, endOfDay bool) (string, error) {
+	value = strings.TrimSpace(value)
+	if len(value) == len("2006-01-02") {
+		if endOfDay {
+			value += " 23:59:59"
+		} else {
+			value += " 00:00:00"
+		}
+	}
+	t, err := parseDBTime(value)
+	if err != nil {
+		return "", fmt.Errorf("invalid date: %s", value)
+	}
+	return t.UTC().Format(dbTimeLayout), nil
+}
@@ -5,24 +5,38 @@ import (
 	"crypto/subtle"
 	"database/sql"
 	"encoding/base64"
+	"errors"
 	"fmt"
+	"strings"
+	"sync"
 	"time"
 
+	"github.com/mattn/go-sqlite3"
 	"golang.org/x/crypto/argon2"
 )
 
 type AuthService struct {
 	db *DatabaseService
+
+	mu         sync.Mutex
+	challenges map[string]twoFactorChallenge
 }
 
 type User struct {
-	ID        int     `json:"id"`
-	Username  string  `json:"username"`
-	Email     string  `json:"email"`
-	UserRole  string  `json:"user_role"`
-	MetaData  *string `json:"meta_data,omitempty"`
-	CreatedAt string  `json:"created_at"`
-	UpdatedAt string  `json:"updated_at"`
+	ID                 int     `json:"id"`
+	Username           string  `json:"username"`
+	Email              string  `json:"email"`
+	UserRole           string  `json:"user_role"`
+	MustChangePassword bool    `json:"must_change_password"`
+	TwoFactorEnabled   bool    `json:"two_factor_enabled"`
+	MetaData           *string `json:"meta_data,omitempty"`
+	SessionToken       string  `json:"session_token,omitempty"`
+	CreatedAt          string  `json:"created_at"`
+	UpdatedAt          string  `json:"updated_at"`
+
+	// Set instead of SessionToken when the password was right but a second factor is needed
+	TwoFactorRequired bool   `json:"two_factor_required,omitempty"`
+	TwoFactorToken    string `json:"two_factor_token,omitempty"`
 }
 
 type LoginRequest struct {
@@ -31,14 +45,33 @@ type LoginRequest struct {
 }
 
 type SignupRequest struct {
-	Username string `json:"username"`
-	Email    string `json:"email"`
-	Password string `json:"password"`
+	Username   string `json:"username"`
+	Email      string `json:"email"`
+	Password   string `json:"password"`
+	InviteCode string `json:"invite_code"`
 }
 
-
 func NewAuthService(db *DatabaseService) *AuthService {
-	return &AuthService{db: db}
+	return &AuthService{
+		db:         db,
+		challenges: make(map[string]twoFactorChallenge),
+	}
+}
+
+// getUser loads a user by ID
+func (as *AuthService) getUser(userID int) (*User, error) {
+	var user User
+	err := as.db.GetDB().QueryRow(`
+		SELECT id, username, email, user_role, must_change_password, totp_enabled, meta_data, created_at, updated_at
+		FROM users
+		WHERE id = ?
+	`, userID).Scan(&user.ID, &user.Username, &user.Email, &user.UserRole, &user.MustChangePassword, &user.TwoFactorEnabled, &user.MetaData, &user.CreatedAt, &user.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("user not found")
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to query user: %w", err)
+	}
+	return &user, nil
 }
 
 // hashPassword generates a secure hash of the password using Argon2
@@ -65,12 +98,16 @@ func (as *AuthService) hashPassword(password string) (string, error) {
 
 // verifyPassword checks if the provided password matches the hash
 func (as *AuthService) verifyPassword(password, encodedHash string) (bool, error) {
-	// Parse the encoded hash
-	var salt, hash string
-	_, err := fmt.Sscanf(encodedHash, "$argon2id$v=19$m=65536,t=1,p=4$%s$%s", &salt, &hash)
-	if err != nil {
-		return false, fmt.Errorf("failed to parse hash: %w", err)
+	// Parse the encoded hash. Sscanf's %s would swallow the "$" separator, so split instead.
+	const prefix = "$argon2id$v=19$m=65536,t=1,p=4$"
+	if !strings.HasPrefix(encodedHash, prefix) {
+		return false, fmt.Errorf("failed to parse hash: unsupported format")
 	}
+	parts := strings.Split(strings.TrimPrefix(encodedHash, prefix), "$")
+	if len(parts) != 2 {
+		return false, fmt.Errorf("failed to parse hash: malformed")
+	}
+	salt, hash := parts[0], parts[1]
 
 	// Decode salt and hash from base64
 	saltBytes, err := base64.RawStdEncoding.DecodeString(salt)
@@ -90,62 +127,173 @@ func (as *AuthService) verifyPassword(password, encodedHash string) (bool, error
 	return subtle.ConstantTimeCompare(actualHash, expectedHash) == 1, nil
 }
 
-// Signup creates a new user account
+// Signup creates a new member account, or one with the role of the given invite code.
+// Accounts can only be created once SetupAdmin has run.
 func (as *AuthService) Signup(req SignupRequest) (*User, error) {
-	// Validate input
-	if req.Username == "" || req.Email == "" || req.Password == "" {
-		return nil, fmt.Errorf("username, email and password are required")
+	if err := normalizeSignupRequest(&req); err != nil {
+		return nil, err
 	}
 
-	if len(req.Password) < 6 {
-		return nil, fmt.Errorf("password must be at least 6 characters long")
+	mode := as.db.GetEffectiveString(0, "registration_mode")
+	inviteCode := strings.TrimSpace(req.InviteCode)
+	if mode == RegistrationClosed {
+		return nil, fmt.Errorf("registration is closed")
+	}
+	if mode == RegistrationInviteOnly && inviteCode == "" {
+		return nil, fmt.Errorf("an invite code is required to sign up")
 	}
 
-	// Check if username already exists
-	var exists bool
-	err := as.db.GetDB().QueryRow("SELECT EXISTS(SELECT 1 FROM users WHERE username = ? OR email = ?)", req.Username, req.Username).Scan(&exists)
+	// Hash the password before the transaction so the write lock is held briefly
+	hashedPassword, err := as.hashPassword(req.Password)
 	if err != nil {
-		return nil, fmt.Errorf("failed to check username existence: %w", err)
+		return nil, fmt.Errorf("failed to hash password: %w", err)
 	}
-	if exists {
-		return nil, fmt.Errorf("username already exists")
+
+	tx, err := as.db.GetDB().Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
 	}
+	defer tx.Rollback()
 
-	// Check if email already exists
-	err = as.db.GetDB().QueryRow("SELECT EXISTS(SELECT 1 FROM users WHERE email = ?)", req.Email).Scan(&exists)
+	hasAdmin, err := adminExists(tx)
 	if err != nil {
-		return nil, fmt.Errorf("failed to check email existence: %w", err)
+		return nil, err
 	}
-	if exists {
-		return nil, fmt.Errorf("email already registered")
+	if !hasAdmin {
+		return nil, fmt.Errorf("setup required: create the admin account first")
+	}
+
+	// Invite codes carry their role in every mode that accepts them
+	userRole := RoleMember
+	var inviteCodeID interface{}
+	if inviteCode != "" {
+		codeID, role, err := claimInviteCode(tx, inviteCode)
+		if err != nil {
+			return nil, err
+		}
+		inviteCodeID = codeID
+		userRole = role
+	}
+
+	user, err := insertUser(tx, req, hashedPassword, userRole, inviteCodeID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to create user: %w", friendlyConstraintError(err))
+	}
+
+	if user.SessionToken, err = as.createSession(user.ID); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// NeedsSetup reports whether the first admin account still has to be created
+func (as *AuthService) NeedsSetup() (bool, error) {
+	hasAdmin, err := adminExists(as.db.GetDB())
+	return !hasAdmin, err
+}
+
+// SetupAdmin creates the first admin account. It fails once any admin exists,
+// so concurrent first-run attempts produce exactly one admin.
+func (as *AuthService) SetupAdmin(req SignupRequest) (*User, error) {
+	if err := normalizeSignupRequest(&req); err != nil {
+		return nil, err
 	}
 
-	// Hash the password
 	hashedPassword, err := as.hashPassword(req.Password)
 	if err != nil {
 		return nil, fmt.Errorf("failed to hash password: %w", err)
 	}
 
-	// Check if this is the first user (for admin role assignment)
-	var userCount int
-	err = as.db.GetDB().QueryRow("SELECT COUNT(*) FROM users").Scan(&userCount)
+	tx, err := as.db.GetDB().Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	hasAdmin, err := adminExists(tx)
+	if err != nil {
+		return nil, err
+	}
+	if hasAdmin {
+		return nil, fmt.Errorf("setup has already been completed")
+	}
+
+	user, err := insertUser(tx, req, hashedPassword, RoleAdmin, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to create user: %w", friendlyConstraintError(err))
+	}
+
+	if user.SessionToken, err = as.createSession(user.ID); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// normalizeSignupRequest trims and validates the fields of a signup
+func normalizeSignupRequest(req *SignupRequest) error {
+	req.Username = strings.TrimSpace(req.Username)
+	req.Email = strings.TrimSpace(req.Email)
+
+	if req.Username == "" || req.Email == "" || req.Password == "" {
+		return fmt.Errorf("username, email and password are required")
+	}
+	if !strings.Contains(req.Email, "@") {
+		return fmt.Errorf("a valid email is required")
+	}
+	if len(req.Password) < 6 {
+		return fmt.Errorf("password must be at least 6 characters long")
+	}
+	return nil
+}
+
+// queryRower is satisfied by both *sql.DB and *sql.Tx
+type queryRower interface {
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
+
+// adminExists reports whether any account has the admin role
+func adminExists(q queryRower) (bool, error) {
+	var exists bool
+	if err := q.QueryRow("SELECT EXISTS(SELECT 1 FROM users WHERE user_role = ?)", RoleAdmin).Scan(&exists); err != nil {
+		return false, fmt.Errorf("failed to check for admin: %w", err)
+	}
+	return exists, nil
+}
+
+// insertUser checks that the username and email are free and inserts the user.
+// Either can be used to log in, so each is checked against both columns.
+func insertUser(tx *sql.Tx, req SignupRequest, hashedPassword, userRole string, inviteCodeID interface{}) (*User, error) {
+	var exists bool
+	err := tx.QueryRow("SELECT EXISTS(SELECT 1 FROM users WHERE username = ? OR email = ?)", req.Username, req.Username).Scan(&exists)
 	if err != nil {
-		return nil, fmt.Errorf("failed to count users: %w", err)
+		return nil, fmt.Errorf("failed to check username existence: %w", err)
+	}
+	if exists {
+		return nil, fmt.Errorf("username already exists")
 	}
 
-	// Assign role based on whether this is the first user
-	userRole := "member"
-	if userCount == 0 {
-		userRole = "admin"
+	err = tx.QueryRow("SELECT EXISTS(SELECT 1 FROM users WHERE email = ? OR username = ?)", req.Email, req.Email).Scan(&exists)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check email existence: %w", err)
+	}
+	if exists {
+		return nil, fmt.Errorf("email already registered")
 	}
 
-	// Insert the new user
-	result, err := as.db.GetDB().Exec(`
-		INSERT INTO users (username, email, password_hash, user_role, created_at, updated_at)
-		VALUES (?, ?, ?, ?, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP)
-	`, req.Username, req.Email, hashedPassword, userRole)
+	result, err := tx.Exec(`
+		INSERT INTO users (username, email, password_hash, user_role, invite_code_id, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP)
+	`, req.Username, req.Email, hashedPassword, userRole, inviteCodeID)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create user: %w", err)
+		return nil, fmt.Errorf("failed to create user: %w", friendlyConstraintError(err))
 	}
 
 	userID, err := result.LastInsertId()
@@ -153,18 +301,37 @@ func (as *AuthService) Signup(req SignupRequest) (*User, error) {
 		return nil, fmt.Errorf("failed to get user ID: %w", err)
 	}
 
-	// Create the user object
-	user := &User{
+	now := time.Now().Format(time.RFC3339)
+	return &User{
 		ID:        int(userID),
 		Username:  req.Username,
 		Email:     req.Email,
 		UserRole:  userRole,
-		MetaData:  nil,
-		CreatedAt: time.Now().Format(time.RFC3339),
-		UpdatedAt: time.Now().Format(time.RFC3339),
+		CreatedAt: now,
+		UpdatedAt: now,
+	}, nil
+}
+
+// friendlyConstraintError turns SQLite constraint violations on users into messages fit for the UI
+func friendlyConstraintError(err error) error {
+	var sqliteErr sqlite3.Error
+	if !errors.As(err, &sqliteErr) {
+		return err
 	}
 
-	return user, nil
+	if sqliteErr.ExtendedCode == sqlite3.ErrConstraintUnique {
+		switch {
+		case strings.Contains(sqliteErr.Error(), "users.username"):
+			return fmt.Errorf("username already exists")
+		case strings.Contains(sqliteErr.Error(), "users.email"):
+			return fmt.Errorf("email already registered")
+		}
+		return fmt.Errorf("value already in use")
+	}
+	if sqliteErr.Code == sqlite3.ErrBusy || sqliteErr.Code == sqlite3.ErrLocked {
+		return fmt.Errorf("the database is busy, please try again")
+	}
+	return err
 }
 
 // Login authenticates a user
@@ -177,17 +344,36 @@ func (as *AuthService) Login(req LoginRequest) (*User, error) {
 	// Get user from database (allow login with username or email)
 	var user User
 	var passwordHash string
+	var disabled bool
 	err := as.db.GetDB().QueryRow(`
-		SELECT id, username, email, password_hash, user_role, meta_data, created_at, updated_at
+		SELECT id, username, email, password_hash, user_role, disabled, must_change_password, totp_enabled, meta_data, created_at, updated_at
 		FROM users
 		WHERE username = ? OR email = ?
-	`, req.Username, req.Username).Scan(&user.ID, &user.Username, &user.Email, &passwordHash, &user.UserRole, &user.MetaData, &user.CreatedAt, &user.UpdatedAt)
+	`, req.Username, req.Username).Scan(&user.ID, &user.Username, &user.Email, &passwordHash, &user.UserRole, &disabled, &user.MustChangePassword, &user.TwoFactorEnabled, &user.MetaData, &user.CreatedAt, &user.UpdatedAt)
 
-	if err == sql.ErrNoRows {
-		return nil, fmt.Errorf("invalid username or password")
-	} else if err != nil {
+	if err != nil && err != sql.ErrNoRows {
 		return nil, fmt.Errorf("failed to query user: %w", err)
 	}
+	found := err == nil
+
+	// Track attempts by the canonical username so username and email logins share one counter
+	key := throttleKey(req.Username)
+	var userID *int
+	if found {
+		key = throttleKey(user.Username)
+		userID = &user.ID
+	}
+
+	if err := as.checkLoginAllowed(key); err != nil {
+		as.recordAuthEvent(userID, key, AuthEventLoginBlocked, false, err.Error())
+		return nil, err
+	}
+
+	if !found {
+		as.recordLoginFailure(key)
+		as.recordAuthEvent(nil, key, AuthEventLoginFailed, false, "unknown user")
+		return nil, fmt.Errorf("invalid username or password")
+	}
 
 	// Verify password
 	valid, err := as.verifyPassword(req.Password, passwordHash)
@@ -196,8 +382,38 @@ func (as *AuthService) Login(req LoginRequest) (*User, error) {
 	}
 
 	if !valid {
+		as.recordLoginFailure(key)
+		as.recordAuthEvent(userID, key, AuthEventLoginFailed, false, "wrong password")
 		return nil, fmt.Errorf("invalid username or password")
 	}
 
+	// Only reveal that the account is disabled once the password is known to be right
+	if disabled {
+		as.recordAuthEvent(userID, key, AuthEventLoginFailed, false, "account disabled")
+		return nil, fmt.Errorf("account is disabled")
+	}
+
+	// With 2FA on, the password alone only earns a short-lived challenge
+	if user.TwoFactorEnabled {
+		token, err := as.startTwoFactorChallenge(user.ID)
+		if err != nil {
+			return nil, err
+		}
+		return &User{
+			Username:          user.Username,
+			TwoFactorEnabled:  true,
+			TwoFactorRequired: true,
+			TwoFactorToken:    token,
+		}, nil
+	}
+
+	as.clearLoginFailures(key)
+
+	user.SessionToken, err = as.createSession(user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	as.recordAuthEvent(userID, key, AuthEventLoginSuccess, true, "")
 	return &user, nil
-}
\ No newline at end of file
+}
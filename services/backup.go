@@ -0,0 +1,349 @@
+package services
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	backupFilePrefix = "mooncaketv-"
+	backupFileSuffix = ".db"
+	backupTimeLayout = "20060102-150405"
+)
+
+// BackupService writes consistent snapshots of the live database,
+// prunes old ones and stages restores for the next startup
+type BackupService struct {
+	db        *DatabaseService
+	backupDir string
+
+	mu   sync.Mutex
+	stop chan struct{}
+}
+
+// BackupSnapshot describes a snapshot file in the backup directory
+type BackupSnapshot struct {
+	Name      string `json:"name"`
+	Size      int64  `json:"size"`
+	CreatedAt string `json:"created_at"`
+}
+
+// NewBackupService creates a new BackupService writing into backupDir
+func NewBackupService(db *DatabaseService, backupDir string) (*BackupService, error) {
+	if err := os.MkdirAll(backupDir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create backup directory: %w", err)
+	}
+	return &BackupService{
+		db:        db,
+		backupDir: backupDir,
+	}, nil
+}
+
+// pendingRestorePath returns the path a staged restore is written to
+func pendingRestorePath(dbPath string) string {
+	return dbPath + ".restore"
+}
+
+// ApplyPendingRestore replaces the database at dbPath with a staged restore, if any.
+// It must run before the database is opened.
+func ApplyPendingRestore(dbPath string) error {
+	pending := pendingRestorePath(dbPath)
+	if _, err := os.Stat(pending); os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	// Keep the replaced database around in case the restore was a mistake
+	if _, err := os.Stat(dbPath); err == nil {
+		previous := fmt.Sprintf("%s.pre-restore-%s", dbPath, time.Now().Format(backupTimeLayout))
+		if err := os.Rename(dbPath, previous); err != nil {
+			return fmt.Errorf("failed to move current database aside: %w", err)
+		}
+	}
+
+	// Stale WAL and shared-memory files belong to the old database
+	for _, suffix := range []string{"-wal", "-shm", "-journal"} {
+		if err := os.Remove(dbPath + suffix); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to remove %s file: %w", suffix, err)
+		}
+	}
+
+	if err := os.Rename(pending, dbPath); err != nil {
+		return fmt.Errorf("failed to move restored database into place: %w", err)
+	}
+
+	log.Printf("Restored database from staged snapshot")
+	return nil
+}
+
+// CreateSnapshot writes a consistent copy of the live database and applies the retention policy
+func (bs *BackupService) CreateSnapshot() (*BackupSnapshot, error) {
+	bs.mu.Lock()
+	defer bs.mu.Unlock()
+
+	name := backupFilePrefix + time.Now().Format(backupTimeLayout) + backupFileSuffix
+	path := filepath.Join(bs.backupDir, name)
+	if _, err := os.Stat(path); err == nil {
+		return nil, fmt.Errorf("a snapshot was already taken this second")
+	}
+
+	// VACUUM INTO produces a transactionally consistent copy while the database stays open
+	if _, err := bs.db.GetDB().Exec("VACUUM INTO ?", path); err != nil {
+		return nil, fmt.Errorf("failed to write snapshot: %w", err)
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to stat snapshot: %w", err)
+	}
+
+	if err := bs.pruneLocked(bs.retention()); err != nil {
+		log.Printf("Failed to prune old snapshots: %v", err)
+	}
+
+	return &BackupSnapshot{
+		Name:      name,
+		Size:      info.Size(),
+		CreatedAt: info.ModTime().Format(time.RFC3339),
+	}, nil
+}
+
+// ListSnapshots returns all snapshots, newest first
+func (bs *BackupService) ListSnapshots() ([]BackupSnapshot, error) {
+	entries, err := os.ReadDir(bs.backupDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read backup directory: %w", err)
+	}
+
+	snapshots := []BackupSnapshot{}
+	for _, entry := range entries {
+		if entry.IsDir() || !isSnapshotName(entry.Name()) {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return nil, err
+		}
+		snapshots = append(snapshots, BackupSnapshot{
+			Name:      entry.Name(),
+			Size:      info.Size(),
+			CreatedAt: info.ModTime().Format(time.RFC3339),
+		})
+	}
+
+	// Names embed the timestamp, so they sort chronologically
+	sort.Slice(snapshots, func(i, j int) bool {
+		return snapshots[i].Name > snapshots[j].Name
+	})
+
+	return snapshots, nil
+}
+
+// RestoreSnapshot stages a snapshot to replace the live database on next startup.
+// The audit entry is written into the staged copy so it survives the swap.
+func (bs *BackupService) RestoreSnapshot(actorID int, name string) error {
+	bs.mu.Lock()
+	defer bs.mu.Unlock()
+
+	if !isSnapshotName(name) || filepath.Base(name) != name {
+		return fmt.Errorf("invalid snapshot name")
+	}
+
+	source := filepath.Join(bs.backupDir, name)
+	if _, err := os.Stat(source); err != nil {
+		if os.IsNotExist(err) {
+			return fmt.Errorf("snapshot not found")
+		}
+		return err
+	}
+
+	if err := checkSnapshotIntegrity(source); err != nil {
+		return err
+	}
+
+	// Copy rather than move so the snapshot stays available
+	pending := pendingRestorePath(bs.db.GetPath())
+	data, err := os.ReadFile(source)
+	if err != nil {
+		return fmt.Errorf("failed to read snapshot: %w", err)
+	}
+	if err := os.WriteFile(pending+".tmp", data, 0644); err != nil {
+		return fmt.Errorf("failed to stage snapshot: %w", err)
+	}
+	if err := bs.auditRestore(pending+".tmp", actorID, name); err != nil {
+		os.Remove(pending + ".tmp")
+		return err
+	}
+	if err := os.Rename(pending+".tmp", pending); err != nil {
+		return fmt.Errorf("failed to stage snapshot: %w", err)
+	}
+
+	return nil
+}
+
+// auditRestore records the restore in the staged database, naming the actor as the live database knows them
+func (bs *BackupService) auditRestore(path string, actorID int, name string) error {
+	var actorName string
+	if err := bs.db.GetDB().QueryRow("SELECT username FROM users WHERE id = ?", actorID).Scan(&actorName); err != nil && err != sql.ErrNoRows {
+		return fmt.Errorf("failed to query user: %w", err)
+	}
+
+	staged, err := sql.Open("sqlite3", path)
+	if err != nil {
+		return fmt.Errorf("failed to open staged snapshot: %w", err)
+	}
+	defer staged.Close()
+
+	tx, err := staged.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := recordAuditAs(tx, actorID, actorName, AuditBackupRestored, AuditTargetBackup, name, nil, nil); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// CancelPendingRestore discards a staged restore
+func (bs *BackupService) CancelPendingRestore() error {
+	err := os.Remove(pendingRestorePath(bs.db.GetPath()))
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// HasPendingRestore reports whether a restore is staged for the next startup
+func (bs *BackupService) HasPendingRestore() bool {
+	_, err := os.Stat(pendingRestorePath(bs.db.GetPath()))
+	return err == nil
+}
+
+// Start runs scheduled snapshots in the background until Stop is called
+func (bs *BackupService) Start() {
+	bs.mu.Lock()
+	if bs.stop != nil {
+		bs.mu.Unlock()
+		return
+	}
+	stop := make(chan struct{})
+	bs.stop = stop
+	bs.mu.Unlock()
+
+	go bs.run(stop)
+}
+
+// Stop ends the background scheduler
+func (bs *BackupService) Stop() {
+	bs.mu.Lock()
+	defer bs.mu.Unlock()
+	if bs.stop != nil {
+		close(bs.stop)
+		bs.stop = nil
+	}
+}
+
+func (bs *BackupService) run(stop chan struct{}) {
+	// Check once a minute so interval changes take effect without a restart
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+
+	for {
+		bs.snapshotIfDue()
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (bs *BackupService) snapshotIfDue() {
+	interval := bs.interval()
+	if interval <= 0 {
+		return
+	}
+
+	snapshots, err := bs.ListSnapshots()
+	if err != nil {
+		log.Printf("Failed to list snapshots: %v", err)
+		return
+	}
+	if len(snapshots) > 0 {
+		if last, err := snapshotTime(snapshots[0].Name); err == nil && time.Since(last) < interval {
+			return
+		}
+	}
+
+	if _, err := bs.CreateSnapshot(); err != nil {
+		log.Printf("Scheduled snapshot failed: %v", err)
+	}
+}
+
+// pruneLocked deletes all but the newest keep snapshots. Callers must hold bs.mu.
+func (bs *BackupService) pruneLocked(keep int) error {
+	if keep <= 0 {
+		return nil
+	}
+	snapshots, err := bs.ListSnapshots()
+	if err != nil {
+		return err
+	}
+	for i := keep; i < len(snapshots); i++ {
+		if err := os.Remove(filepath.Join(bs.backupDir, snapshots[i].Name)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// interval reads backup_interval_hours; zero disables scheduled snapshots
+func (bs *BackupService) interval() time.Duration {
+	return time.Duration(bs.db.GetEffectiveInt(0, "backup_interval_hours")) * time.Hour
+}
+
+// retention reads backup_retention; zero keeps every snapshot
+func (bs *BackupService) retention() int {
+	return bs.db.GetEffectiveInt(0, "backup_retention")
+}
+
+func isSnapshotName(name string) bool {
+	if !strings.HasPrefix(name, backupFilePrefix) || !strings.HasSuffix(name, backupFileSuffix) {
+		return false
+	}
+	_, err := snapshotTime(name)
+	return err == nil
+}
+
+func snapshotTime(name string) (time.Time, error) {
+	stamp := strings.TrimSuffix(strings.TrimPrefix(name, backupFilePrefix), backupFileSuffix)
+	return time.ParseInLocation(backupTimeLayout, stamp, time.Local)
+}
+
+// checkSnapshotIntegrity opens a snapshot and runs SQLite's quick check on it
+func checkSnapshotIntegrity(path string) error {
+	snapshot, err := sql.Open("sqlite3", path)
+	if err != nil {
+		return fmt.Errorf("failed to open snapshot: %w", err)
+	}
+	defer snapshot.Close()
+
+	var result string
+	if err := snapshot.QueryRow("PRAGMA quick_check").Scan(&result); err != nil {
+		return fmt.Errorf("failed to check snapshot: %w", err)
+	}
+	if result != "ok" {
+		return fmt.Errorf("snapshot is corrupt: %s", result)
+	}
+	return nil
+}
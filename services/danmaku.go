@@ -0,0 +1,308 @@
+package services
+
+import (
+	"crypto/sha1"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"mooncaketv/danmaku"
+)
+
+const (
+	// maxDanmakuBytes is the largest danmaku file accepted; a busy episode's
+	// full Bilibili history is a few MB
+	maxDanmakuBytes = 20 << 20
+
+	// maxDanmakuWindow is the longest stretch of an episode fetched at once
+	maxDanmakuWindow = 5 * 60 * time.Second
+
+	// maxDanmakuPerWindow caps the comments returned for a window; the player
+	// could not draw more than this legibly anyway
+	maxDanmakuPerWindow = 3000
+
+	// maxDanmakuFilterEntries bounds each list of a filter, which becomes
+	// query parameters
+	maxDanmakuFilterEntries = 200
+)
+
+// DanmakuComment is a bullet comment as the player draws it
+type DanmakuComment struct {
+	ID     int64   `json:"id"`
+	Time   float64 `json:"time"` // seconds into the episode
+	Mode   string  `json:"mode"` // scroll, top or bottom
+	Color  int     `json:"color"`
+	Size   int     `json:"size"`
+	Text   string  `json:"text"`
+	Author string  `json:"author"`
+	Local  bool    `json:"local"` // posted here rather than imported
+	Own    bool    `json:"own"`   // posted by the requesting user
+}
+
+// DanmakuFilter hides comments. It is combined with the profile's
+// danmaku_filter setting.
+type DanmakuFilter struct {
+	Keywords []string `json:"keywords"` // hide comments containing any of these, ignoring ASCII case
+	Users    []string `json:"users"`    // hide comments by these authors
+}
+
+// DanmakuImportResult reports what an import added
+type DanmakuImportResult struct {
+	Format string `json:"format"`
+	Parsed int    `json:"parsed"` // comments read from the file
+	Added  int    `json:"added"`  // new ones, the rest were already imported
+}
+
+// ImportDanmaku stores the comments of a Bilibili XML or JSON, or DPlayer
+// JSON, danmaku file, sent base64 encoded, for an episode of a title.
+// Comments already imported from the same source are skipped.
+func (ds *DatabaseService) ImportDanmaku(mcID, episode, fileName, contentBase64 string) (*DanmakuImportResult, error) {
+	if mcID == "" || episode == "" {
+		return nil, fmt.Errorf("media and episode are required")
+	}
+	data, err := base64.StdEncoding.DecodeString(contentBase64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid danmaku data: %w", err)
+	}
+	if len(data) == 0 {
+		return nil, fmt.Errorf("danmaku file is empty")
+	}
+	if len(data) > maxDanmakuBytes {
+		return nil, fmt.Errorf("danmaku file is larger than %d MB", maxDanmakuBytes>>20)
+	}
+
+	format, comments, err := danmaku.Parse(fileName, data)
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := ds.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.Prepare(`
+		INSERT OR IGNORE INTO danmaku (mc_id, episode, time_ms, mode, color, size, content, author, origin_id, user_id, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, 0, CURRENT_TIMESTAMP)
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to prepare danmaku insert: %w", err)
+	}
+	defer stmt.Close()
+
+	result := &DanmakuImportResult{Format: string(format), Parsed: len(comments)}
+	for _, c := range comments {
+		res, err := stmt.Exec(mcID, episode, c.Time.Milliseconds(), string(c.Mode), c.Color, c.Size,
+			c.Text, c.Author, danmakuOriginID(c))
+		if err != nil {
+			return nil, fmt.Errorf("failed to save danmaku: %w", err)
+		}
+		if n, _ := res.RowsAffected(); n > 0 {
+			result.Added++
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit danmaku: %w", err)
+	}
+	return result, nil
+}
+
+// PostDanmaku adds a user's own comment at a position in an episode
+func (ds *DatabaseService) PostDanmaku(userID int, mcID, episode string, seconds float64, text, mode string, color int) (*DanmakuComment, error) {
+	if mcID == "" || episode == "" {
+		return nil, fmt.Errorf("media and episode are required")
+	}
+	if seconds < 0 {
+		return nil, fmt.Errorf("position must not be negative")
+	}
+	if utf8.RuneCountInString(strings.TrimSpace(text)) > danmaku.MaxTextLength {
+		return nil, fmt.Errorf("danmaku must be at most %d characters", danmaku.MaxTextLength)
+	}
+	text = danmaku.CleanText(text)
+	if text == "" {
+		return nil, fmt.Errorf("danmaku text is required")
+	}
+	if mode == "" {
+		mode = string(danmaku.ModeScroll)
+	}
+	if !danmaku.ValidMode(danmaku.Mode(mode)) {
+		return nil, fmt.Errorf("unknown danmaku mode: %s", mode)
+	}
+	if color < 0 || color > 0xFFFFFF {
+		return nil, fmt.Errorf("invalid danmaku color")
+	}
+
+	var id int64
+	err := ds.db.QueryRow(`
+		INSERT INTO danmaku (mc_id, episode, time_ms, mode, color, size, content, author, user_id, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, (SELECT username FROM users WHERE id = ?), ?, CURRENT_TIMESTAMP)
+		RETURNING id
+	`, mcID, episode, int64(seconds*1000), mode, color, danmaku.DefaultSize, text, userID, userID).Scan(&id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to save danmaku: %w", err)
+	}
+
+	row := ds.db.QueryRow("SELECT "+danmakuColumns+" FROM danmaku WHERE id = ?", id)
+	return scanDanmakuComment(row, userID)
+}
+
+// GetDanmaku returns an episode's comments from one position to another, in
+// seconds, ordered by time. Comments matched by filter or the profile's saved
+// filter are left out.
+func (ds *DatabaseService) GetDanmaku(userID int, mcID, episode string, from, to float64, filter DanmakuFilter) ([]DanmakuComment, error) {
+	if from < 0 {
+		from = 0
+	}
+	if to <= from {
+		return nil, fmt.Errorf("window end must be after its start")
+	}
+	if time.Duration((to-from)*float64(time.Second)) > maxDanmakuWindow {
+		return nil, fmt.Errorf("window must be at most %d minutes", int(maxDanmakuWindow.Minutes()))
+	}
+
+	if userID > 0 {
+		saved, err := parseDanmakuFilter(ds.GetEffectiveString(userID, "danmaku_filter"))
+		if err == nil {
+			filter.Keywords = append(filter.Keywords, saved.Keywords...)
+			filter.Users = append(filter.Users, saved.Users...)
+		}
+	}
+	filter = normalizeDanmakuFilter(filter)
+
+	query := "SELECT " + danmakuColumns + " FROM danmaku WHERE mc_id = ? AND episode = ? AND time_ms >= ? AND time_ms < ?"
+	args := []interface{}{mcID, episode, int64(from * 1000), int64(to * 1000)}
+	for _, keyword := range filter.Keywords {
+		query += " AND instr(lower(content), ?) = 0"
+		args = append(args, keyword)
+	}
+	if len(filter.Users) > 0 {
+		query += " AND author NOT IN (" + strings.TrimSuffix(strings.Repeat("?, ", len(filter.Users)), ", ") + ")"
+		for _, user := range filter.Users {
+			args = append(args, user)
+		}
+	}
+	query += " ORDER BY time_ms, id LIMIT ?"
+	args = append(args, maxDanmakuPerWindow)
+
+	rows, err := ds.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query danmaku: %w", err)
+	}
+	defer rows.Close()
+
+	comments := []DanmakuComment{}
+	for rows.Next() {
+		c, err := scanDanmakuComment(rows, userID)
+		if err != nil {
+			return nil, err
+		}
+		comments = append(comments, *c)
+	}
+	return comments, rows.Err()
+}
+
+// DeleteDanmaku removes a comment. Users may delete their own posts; anything
+// else needs the moderate_comments permission.
+func (ds *DatabaseService) DeleteDanmaku(userID int, id int64) error {
+	var owner int
+	err := ds.db.QueryRow("SELECT user_id FROM danmaku WHERE id = ?", id).Scan(&owner)
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("danmaku not found")
+	} else if err != nil {
+		return fmt.Errorf("failed to query danmaku: %w", err)
+	}
+	if owner == 0 || owner != userID {
+		if err := ds.Authorize(userID, PermModerateComments); err != nil {
+			return err
+		}
+	}
+	_, err = ds.db.Exec("DELETE FROM danmaku WHERE id = ?", id)
+	return err
+}
+
+// ClearImportedDanmaku removes an episode's imported comments, keeping local
+// posts, and returns how many went
+func (ds *DatabaseService) ClearImportedDanmaku(mcID, episode string) (int64, error) {
+	res, err := ds.db.Exec("DELETE FROM danmaku WHERE mc_id = ? AND episode = ? AND origin_id IS NOT NULL", mcID, episode)
+	if err != nil {
+		return 0, fmt.Errorf("failed to clear danmaku: %w", err)
+	}
+	return res.RowsAffected()
+}
+
+// danmakuOriginID identifies an imported comment. Files without ids, such as
+// DPlayer's, fall back to a hash of the comment so re-imports still dedupe.
+func danmakuOriginID(c danmaku.Comment) string {
+	if c.ID != "" {
+		return c.ID
+	}
+	sum := sha1.Sum([]byte(fmt.Sprintf("%d\x00%s\x00%d\x00%s\x00%s", c.Time.Milliseconds(), c.Mode, c.Color, c.Author, c.Text)))
+	return "h:" + hex.EncodeToString(sum[:10])
+}
+
+// parseDanmakuFilter reads the danmaku_filter setting
+func parseDanmakuFilter(value string) (DanmakuFilter, error) {
+	var filter DanmakuFilter
+	if err := json.Unmarshal([]byte(value), &filter); err != nil {
+		return DanmakuFilter{}, fmt.Errorf("danmaku_filter must be {\"keywords\": [...], \"users\": [...]}: %w", err)
+	}
+	if len(filter.Keywords) > maxDanmakuFilterEntries || len(filter.Users) > maxDanmakuFilterEntries {
+		return DanmakuFilter{}, fmt.Errorf("danmaku_filter lists are limited to %d entries", maxDanmakuFilterEntries)
+	}
+	return filter, nil
+}
+
+// normalizeDanmakuFilter drops blank and repeated entries, lowercases
+// keywords to match lower(content) and caps the list lengths
+func normalizeDanmakuFilter(filter DanmakuFilter) DanmakuFilter {
+	clean := func(values []string, lower bool) []string {
+		seen := map[string]bool{}
+		out := []string{}
+		for _, v := range values {
+			v = strings.TrimSpace(v)
+			if lower {
+				// SQLite's lower() only folds ASCII, so the keyword must match that
+				v = strings.Map(func(r rune) rune {
+					if r >= 'A' && r <= 'Z' {
+						return r + 'a' - 'A'
+					}
+					return r
+				}, v)
+			}
+			if v == "" || seen[v] {
+				continue
+			}
+			seen[v] = true
+			out = append(out, v)
+			if len(out) == maxDanmakuFilterEntries {
+				break
+			}
+		}
+		return out
+	}
+	return DanmakuFilter{
+		Keywords: clean(filter.Keywords, true),
+		Users:    clean(filter.Users, false),
+	}
+}
+
+const danmakuColumns = "id, time_ms, mode, color, size, content, author, origin_id IS NULL, user_id"
+
+func scanDanmakuComment(row interface{ Scan(...interface{}) error }, userID int) (*DanmakuComment, error) {
+	var c DanmakuComment
+	var timeMS int64
+	var owner int
+	if err := row.Scan(&c.ID, &timeMS, &c.Mode, &c.Color, &c.Size, &c.Text, &c.Author, &c.Local, &owner); err != nil {
+		return nil, err
+	}
+	c.Time = float64(timeMS) / 1000
+	c.Own = owner != 0 && owner == userID
+	return &c, nil
+}
@@ -5,12 +5,18 @@ import (
 	"embed"
 	"fmt"
 	"path/filepath"
+	"sync"
 
 	_ "github.com/mattn/go-sqlite3"
 )
 
 type DatabaseService struct {
-	db *sql.DB
+	db     *sql.DB
+	path   string
+	events *EventBus
+
+	keyMu     sync.Mutex
+	secretKey []byte
 }
 
 func NewDatabaseService(dbPath string, migrationsFS embed.FS) (*DatabaseService, error) {
@@ -19,7 +25,14 @@ func NewDatabaseService(dbPath string, migrationsFS embed.FS) (*DatabaseService,
 		dbPath += ".db"
 	}
 
-	db, err := sql.Open("sqlite3", dbPath)
+	// Swap in a restored snapshot before anything holds the file open
+	if err := ApplyPendingRestore(dbPath); err != nil {
+		return nil, fmt.Errorf("failed to apply pending restore: %w", err)
+	}
+
+	// Immediate transactions take the write lock up front, so read-then-write
+	// checks such as "is this the last admin" cannot interleave
+	db, err := sql.Open("sqlite3", dbPath+"?_txlock=immediate")
 	if err != nil {
 		return nil, fmt.Errorf("failed to open database: %w", err)
 	}
@@ -29,7 +42,7 @@ func NewDatabaseService(dbPath string, migrationsFS embed.FS) (*DatabaseService,
 		return nil, fmt.Errorf("failed to ping database: %w", err)
 	}
 
-	service := &DatabaseService{db: db}
+	service := &DatabaseService{db: db, path: dbPath, events: NewEventBus()}
 
 	// Run migrations
 	migrationService := NewMigrationService(db)
@@ -52,6 +65,42 @@ func (ds *DatabaseService) GetDB() *sql.DB {
 	return ds.db
 }
 
+// Events returns the bus that database writes publish change events on
+func (ds *DatabaseService) Events() *EventBus {
+	return ds.events
+}
+
+// publishSettingChanged announces a settings write; settingUserID is invalid for global settings
+func (ds *DatabaseService) publishSettingChanged(key, value string, settingUserID sql.NullInt64, deleted bool) {
+	event := SettingChangedEvent{Key: key, Value: value, Deleted: deleted}
+	if settingUserID.Valid {
+		id := int(settingUserID.Int64)
+		event.UserID = &id
+	}
+	ds.events.Publish(EventSettingChanged, event)
+}
+
+// GetPath returns the file path of the live database
+func (ds *DatabaseService) GetPath() string {
+	return ds.path
+}
+
+// GetGlobalSettingValue returns the value of a global setting and whether it exists
+func (ds *DatabaseService) GetGlobalSettingValue(key string) (string, bool, error) {
+	var value string
+	err := ds.db.QueryRow(`
+		SELECT setting_value FROM settings
+		WHERE user_id IS NULL AND setting_key = ?
+	`, key).Scan(&value)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return "", false, nil
+		}
+		return "", false, err
+	}
+	return value, true, nil
+}
+
 // GetAllTables returns a list of all tables in the database
 func (ds *DatabaseService) GetAllTables() ([]string, error) {
 	query := `SELECT name FROM sqlite_master WHERE type='table' ORDER BY name;`
@@ -106,7 +155,7 @@ func (ds *DatabaseService) GetMigrations() ([]map[string]interface{}, error) {
 
 // GetAllUsers returns all users in the database
 func (ds *DatabaseService) GetAllUsers() ([]map[string]interface{}, error) {
-	query := `SELECT id, username, email, user_role, created_at, updated_at FROM users ORDER BY created_at DESC;`
+	query := `SELECT id, username, email, user_role, disabled, must_change_password, created_at, updated_at FROM users ORDER BY created_at DESC;`
 	rows, err := ds.db.Query(query)
 	if err != nil {
 		return nil, err
@@ -118,8 +167,9 @@ func (ds *DatabaseService) GetAllUsers() ([]map[string]interface{}, error) {
 		user := make(map[string]interface{})
 		var id int
 		var username, email, userRole, createdAt, updatedAt string
+		var disabled, mustChangePassword bool
 
-		if err := rows.Scan(&id, &username, &email, &userRole, &createdAt, &updatedAt); err != nil {
+		if err := rows.Scan(&id, &username, &email, &userRole, &disabled, &mustChangePassword, &createdAt, &updatedAt); err != nil {
 			return nil, err
 		}
 
@@ -127,6 +177,8 @@ func (ds *DatabaseService) GetAllUsers() ([]map[string]interface{}, error) {
 		user["username"] = username
 		user["email"] = email
 		user["user_role"] = userRole
+		user["disabled"] = disabled
+		user["must_change_password"] = mustChangePassword
 		user["created_at"] = createdAt
 		user["updated_at"] = updatedAt
 
@@ -209,15 +261,20 @@ func (ds *DatabaseService) GetUserByID(userID int) (map[string]interface{}, erro
 	return user, nil
 }
 
-// GetUserSettings returns all settings for a specific user (including global settings)
+// GetUserSettings returns the personal settings of the user's active profile and all global settings
 func (ds *DatabaseService) GetUserSettings(userID int) ([]map[string]interface{}, error) {
-	// Get both user-specific settings and global settings (where user_id is NULL)
+	profileID, err := ds.ActiveProfileID(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Get both profile-specific settings and global settings (where user_id is NULL)
 	query := `SELECT id, user_id, setting_key, setting_value, created_at, updated_at
 			  FROM settings
-			  WHERE user_id = ? OR user_id IS NULL
+			  WHERE profile_id = ? OR user_id IS NULL
 			  ORDER BY user_id IS NULL DESC, setting_key`
 
-	rows, err := ds.db.Query(query, userID)
+	rows, err := ds.db.Query(query, profileID)
 	if err != nil {
 		return nil, err
 	}
@@ -256,10 +313,11 @@ func (ds *DatabaseService) GetUserSettings(userID int) ([]map[string]interface{}
 }
 
 // UpdateSetting updates a setting value
-func (ds *DatabaseService) UpdateSetting(settingID int, newValue string, userID int, isAdmin bool) error {
+func (ds *DatabaseService) UpdateSetting(settingID int, newValue string, userID int) error {
 	// First, check if the setting exists and get its owner
-	var settingUserID sql.NullInt64
-	err := ds.db.QueryRow("SELECT user_id FROM settings WHERE id = ?", settingID).Scan(&settingUserID)
+	var settingUserID, settingProfileID sql.NullInt64
+	var settingKey, oldValue string
+	err := ds.db.QueryRow("SELECT user_id, profile_id, setting_key, setting_value FROM settings WHERE id = ?", settingID).Scan(&settingUserID, &settingProfileID, &settingKey, &oldValue)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return fmt.Errorf("setting not found")
@@ -273,28 +331,65 @@ func (ds *DatabaseService) UpdateSetting(settingID int, newValue string, userID
 		if int(settingUserID.Int64) != userID {
 			return fmt.Errorf("permission denied: cannot edit other user's settings")
 		}
+		// ...and only from the profile it belongs to
+		profileID, err := ds.ActiveProfileID(userID)
+		if err != nil {
+			return err
+		}
+		if !settingProfileID.Valid || int(settingProfileID.Int64) != profileID {
+			return fmt.Errorf("permission denied: setting belongs to another profile")
+		}
 	} else {
-		// Global setting - can only be edited by admin
-		if !isAdmin {
-			return fmt.Errorf("permission denied: only admin can edit global settings")
+		// Global setting - needs the edit_global_settings permission
+		if err := ds.Authorize(userID, PermEditGlobalSettings); err != nil {
+			return err
 		}
 	}
 
+	// Registered settings must pass validation; unknown keys stay free-form
+	if def, ok := LookupSetting(settingKey); ok {
+		newValue, err = def.Normalize(newValue)
+		if err != nil {
+			return err
+		}
+	}
+
+	tx, err := ds.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
 	// Update the setting
-	_, err = ds.db.Exec(`
+	_, err = tx.Exec(`
 		UPDATE settings
 		SET setting_value = ?, updated_at = CURRENT_TIMESTAMP
 		WHERE id = ?
 	`, newValue, settingID)
+	if err != nil {
+		return err
+	}
 
-	return err
+	// Changes to global settings are audited; personal ones are the user's own business
+	if !settingUserID.Valid {
+		if err := recordAudit(tx, userID, AuditSettingUpdated, AuditTargetSetting, settingKey, oldValue, newValue); err != nil {
+			return err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	ds.publishSettingChanged(settingKey, newValue, settingUserID, false)
+	return nil
 }
 
 // DeleteSetting deletes a setting
-func (ds *DatabaseService) DeleteSetting(settingID int, userID int, isAdmin bool) error {
+func (ds *DatabaseService) DeleteSetting(settingID int, userID int) error {
 	// First, check if the setting exists and get its owner
-	var settingUserID sql.NullInt64
-	err := ds.db.QueryRow("SELECT user_id FROM settings WHERE id = ?", settingID).Scan(&settingUserID)
+	var settingUserID, settingProfileID sql.NullInt64
+	var settingKey, oldValue string
+	err := ds.db.QueryRow("SELECT user_id, profile_id, setting_key, setting_value FROM settings WHERE id = ?", settingID).Scan(&settingUserID, &settingProfileID, &settingKey, &oldValue)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return fmt.Errorf("setting not found")
@@ -308,58 +403,110 @@ func (ds *DatabaseService) DeleteSetting(settingID int, userID int, isAdmin bool
 		if int(settingUserID.Int64) != userID {
 			return fmt.Errorf("permission denied: cannot delete other user's settings")
 		}
+		// ...and only from the profile it belongs to
+		profileID, err := ds.ActiveProfileID(userID)
+		if err != nil {
+			return err
+		}
+		if !settingProfileID.Valid || int(settingProfileID.Int64) != profileID {
+			return fmt.Errorf("permission denied: setting belongs to another profile")
+		}
 	} else {
-		// Global setting - can only be deleted by admin
-		if !isAdmin {
-			return fmt.Errorf("permission denied: only admin can delete global settings")
+		// Global setting - needs the edit_global_settings permission
+		if err := ds.Authorize(userID, PermEditGlobalSettings); err != nil {
+			return err
 		}
 	}
 
+	tx, err := ds.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
 	// Delete the setting
-	_, err = ds.db.Exec("DELETE FROM settings WHERE id = ?", settingID)
+	_, err = tx.Exec("DELETE FROM settings WHERE id = ?", settingID)
+	if err != nil {
+		return err
+	}
 
-	return err
+	if !settingUserID.Valid {
+		if err := recordAudit(tx, userID, AuditSettingDeleted, AuditTargetSetting, settingKey, oldValue, nil); err != nil {
+			return err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	// Subscribers fall back to the effective value once the row is gone
+	value := ""
+	if _, ok := LookupSetting(settingKey); ok {
+		userID := 0
+		if settingUserID.Valid {
+			userID = int(settingUserID.Int64)
+		}
+		value = ds.GetEffectiveString(userID, settingKey)
+	}
+	ds.publishSettingChanged(settingKey, value, settingUserID, true)
+	return nil
 }
 
-// AddBookmark adds a bookmark for a user
+// AddBookmark adds a bookmark for the user's active profile
 func (ds *DatabaseService) AddBookmark(userID int, mcID string) error {
-	_, err := ds.db.Exec(`
-		INSERT INTO bookmarks (user_id, mc_id)
-		VALUES (?, ?)
-		ON CONFLICT(user_id, mc_id) DO NOTHING
-	`, userID, mcID)
+	profileID, err := ds.ActiveProfileID(userID)
+	if err != nil {
+		return err
+	}
+	_, err = ds.db.Exec(`
+		INSERT INTO bookmarks (user_id, profile_id, mc_id)
+		VALUES (?, ?, ?)
+		ON CONFLICT(profile_id, mc_id) DO NOTHING
+	`, userID, profileID, mcID)
 	return err
 }
 
-// RemoveBookmark removes a bookmark for a user
+// RemoveBookmark removes a bookmark from the user's active profile
 func (ds *DatabaseService) RemoveBookmark(userID int, mcID string) error {
-	_, err := ds.db.Exec(`
+	profileID, err := ds.ActiveProfileID(userID)
+	if err != nil {
+		return err
+	}
+	_, err = ds.db.Exec(`
 		DELETE FROM bookmarks
-		WHERE user_id = ? AND mc_id = ?
-	`, userID, mcID)
+		WHERE profile_id = ? AND mc_id = ?
+	`, profileID, mcID)
 	return err
 }
 
-// IsBookmarked checks if a user has bookmarked a specific media
+// IsBookmarked checks if the user's active profile has bookmarked a specific media
 func (ds *DatabaseService) IsBookmarked(userID int, mcID string) (bool, error) {
+	profileID, err := ds.ActiveProfileID(userID)
+	if err != nil {
+		return false, err
+	}
 	var count int
-	err := ds.db.QueryRow(`
+	err = ds.db.QueryRow(`
 		SELECT COUNT(*) FROM bookmarks
-		WHERE user_id = ? AND mc_id = ?
-	`, userID, mcID).Scan(&count)
+		WHERE profile_id = ? AND mc_id = ?
+	`, profileID, mcID).Scan(&count)
 	if err != nil {
 		return false, err
 	}
 	return count > 0, nil
 }
 
-// GetUserBookmarks returns all bookmarked mc_ids for a user
+// GetUserBookmarks returns all bookmarked mc_ids of the user's active profile
 func (ds *DatabaseService) GetUserBookmarks(userID int) ([]string, error) {
+	profileID, err := ds.ActiveProfileID(userID)
+	if err != nil {
+		return nil, err
+	}
 	rows, err := ds.db.Query(`
 		SELECT mc_id FROM bookmarks
-		WHERE user_id = ?
+		WHERE profile_id = ?
 		ORDER BY created_at DESC
-	`, userID)
+	`, profileID)
 	if err != nil {
 		return nil, err
 	}
@@ -376,8 +523,17 @@ func (ds *DatabaseService) GetUserBookmarks(userID int) ([]string, error) {
 	return bookmarks, nil
 }
 
-// GetBookmarkedMediaDetails returns full media details for user's bookmarks
+// GetBookmarkedMediaDetails returns full media details for the active profile's bookmarks
 func (ds *DatabaseService) GetBookmarkedMediaDetails(userID int) ([]map[string]interface{}, error) {
+	profileID, err := ds.ActiveProfileID(userID)
+	if err != nil {
+		return nil, err
+	}
+	sources, err := ds.queryMediaSources("s.mc_id IN (SELECT mc_id FROM bookmarks WHERE profile_id = ?)", profileID)
+	if err != nil {
+		return nil, err
+	}
+
 	rows, err := ds.db.Query(`
 		SELECT
 			b.mc_id,
@@ -390,12 +546,13 @@ func (ds *DatabaseService) GetBookmarkedMediaDetails(userID int) ([]map[string]i
 			m.category,
 			m.poster_url,
 			m.video_urls,
-			COALESCE(m.douban_rating, m.imdb_rating, m.tmdb_rating, 0) as rating
+			COALESCE(m.douban_rating, m.imdb_rating, m.tmdb_rating, 0) as rating,
+			m.content_rating
 		FROM bookmarks b
 		LEFT JOIN medias m ON b.mc_id = m.mc_id
-		WHERE b.user_id = ?
+		WHERE b.profile_id = ?
 		ORDER BY b.created_at DESC
-	`, userID)
+	`, profileID)
 	if err != nil {
 		return nil, err
 	}
@@ -409,8 +566,9 @@ func (ds *DatabaseService) GetBookmarkedMediaDetails(userID int) ([]map[string]i
 		var year sql.NullInt64
 		var genre, region, category sql.NullString
 		var rating sql.NullFloat64
+		var contentRating sql.NullString
 
-		if err := rows.Scan(&mcID, &bookmarkedAt, &title, &description, &year, &genre, &region, &category, &posterURL, &videoURLs, &rating); err != nil {
+		if err := rows.Scan(&mcID, &bookmarkedAt, &title, &description, &year, &genre, &region, &category, &posterURL, &videoURLs, &rating, &contentRating); err != nil {
 			return nil, err
 		}
 
@@ -437,23 +595,41 @@ func (ds *DatabaseService) GetBookmarkedMediaDetails(userID int) ([]map[string]i
 		if posterURL.Valid {
 			bookmark["poster"] = posterURL.String
 		}
-		if videoURLs.Valid {
+		if list := sources[mcID]; len(list) > 0 {
+			bookmark["sources"] = list
+			bookmark["m3u8_urls"] = encodeVideoURLs(list[0].Episodes)
+		} else if videoURLs.Valid {
 			bookmark["m3u8_urls"] = videoURLs.String
 		}
 		if rating.Valid {
 			bookmark["rating"] = rating.Float64
 		}
+		if contentRating.Valid {
+			bookmark["content_rating"] = contentRating.String
+		}
 
 		bookmarks = append(bookmarks, bookmark)
 	}
-	return bookmarks, nil
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	// Bookmarks made before restrictions were set are hidden as well
+	return ds.FilterMediaList(userID, bookmarks)
 }
 
-// SaveOrUpdateMedia saves or updates media information
-func (ds *DatabaseService) SaveOrUpdateMedia(mcID, title, description string, year int, genre, region, category, posterURL, videoURLs string, rating float64) error {
-	_, err := ds.db.Exec(`
-		INSERT INTO medias (mc_id, title, description, year, genre, region, category, poster_url, video_urls, douban_rating, created_at, updated_at)
-		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP)
+// SaveOrUpdateMedia saves or updates media information along with its
+// sources and episodes
+func (ds *DatabaseService) SaveOrUpdateMedia(mcID, title, description string, year int, genre, region, category, posterURL string, sources []MediaSource, rating float64) error {
+	tx, err := ds.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(`
+		INSERT INTO medias (mc_id, title, description, year, genre, region, category, poster_url, douban_rating, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP)
 		ON CONFLICT(mc_id) DO UPDATE SET
 			title = excluded.title,
 			description = excluded.description,
@@ -462,18 +638,72 @@ func (ds *DatabaseService) SaveOrUpdateMedia(mcID, title, description string, ye
 			region = excluded.region,
 			category = excluded.category,
 			poster_url = excluded.poster_url,
-			video_urls = excluded.video_urls,
 			douban_rating = excluded.douban_rating,
 			updated_at = CURRENT_TIMESTAMP
-	`, mcID, title, description, year, genre, region, category, posterURL, videoURLs, rating)
-	return err
+	`, mcID, title, description, year, genre, region, category, posterURL, rating)
+	if err != nil {
+		return err
+	}
+	if err := saveMediaSources(tx, mcID, sources); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// CacheMedia stores a media item the first time it is seen. A title that is
+// already cached, with its sources, is left as it is.
+func (ds *DatabaseService) CacheMedia(mcID, title, description string, year int, genre, region, category, posterURL string, sources []MediaSource, rating float64) error {
+	tx, err := ds.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	res, err := tx.Exec(`
+		INSERT INTO medias (mc_id, title, description, year, genre, region, category, poster_url, douban_rating, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP)
+		ON CONFLICT(mc_id) DO NOTHING
+	`, mcID, title, description, year, genre, region, category, posterURL, rating)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return nil
+	}
+	if err := saveMediaSources(tx, mcID, sources); err != nil {
+		return err
+	}
+	return tx.Commit()
 }
 
 // DeleteMedia deletes a media item from the database
-func (ds *DatabaseService) DeleteMedia(mcID string) error {
-	_, err := ds.db.Exec(`
+func (ds *DatabaseService) DeleteMedia(actorID int, mcID string) error {
+	tx, err := ds.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var title string
+	var year sql.NullInt64
+	err = tx.QueryRow(`
 		DELETE FROM medias
 		WHERE mc_id = ?
-	`, mcID)
-	return err
+		RETURNING title, year
+	`, mcID).Scan(&title, &year)
+	if err == sql.ErrNoRows {
+		// Nothing cached, nothing to audit
+		return nil
+	} else if err != nil {
+		return err
+	}
+	if err := deleteMediaSources(tx, mcID); err != nil {
+		return err
+	}
+
+	before := map[string]interface{}{"title": title, "year": year.Int64}
+	if err := recordAudit(tx, actorID, AuditMediaDeleted, AuditTargetMedia, mcID, before, nil); err != nil {
+		return err
+	}
+	return tx.Commit()
 }
\ No newline at end of file
@@ -0,0 +1,81 @@
+package services
+
+import "sync"
+
+// Event names published on the EventBus. The App bridges every event to the
+// frontend through runtime.EventsEmit under the same name.
+const (
+	EventSettingChanged = "settings:changed"
+	EventDeadLinks      = "media:dead-links"
+)
+
+// Event is a message published on the EventBus
+type Event struct {
+	Name    string
+	Payload interface{}
+}
+
+// SettingChangedEvent is the payload of EventSettingChanged.
+// UserID is nil for global settings.
+type SettingChangedEvent struct {
+	Key     string `json:"key"`
+	Value   string `json:"value"`
+	UserID  *int   `json:"user_id"`
+	Deleted bool   `json:"deleted"`
+}
+
+// DeadLinksEvent is the payload of EventDeadLinks, published after a link
+// check that found bookmarked titles with no working source
+type DeadLinksEvent struct {
+	Titles    []DeadTitle `json:"titles"`
+	CheckedAt string      `json:"checked_at"`
+}
+
+// DeadTitle is a bookmarked title whose sources all failed their last check
+type DeadTitle struct {
+	MCID    string `json:"mc_id"`
+	Title   string `json:"title"`
+	Sources int    `json:"sources"`
+}
+
+// EventBus is a small synchronous publish/subscribe hub for in-process events
+type EventBus struct {
+	mu       sync.RWMutex
+	handlers map[string][]func(Event)
+	all      []func(Event)
+}
+
+// NewEventBus creates a new EventBus instance
+func NewEventBus() *EventBus {
+	return &EventBus{
+		handlers: make(map[string][]func(Event)),
+	}
+}
+
+// Subscribe registers a handler for events with the given name
+func (b *EventBus) Subscribe(name string, handler func(Event)) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.handlers[name] = append(b.handlers[name], handler)
+}
+
+// SubscribeAll registers a handler for every event
+func (b *EventBus) SubscribeAll(handler func(Event)) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.all = append(b.all, handler)
+}
+
+// Publish delivers an event to its subscribers on the calling goroutine
+func (b *EventBus) Publish(name string, payload interface{}) {
+	b.mu.RLock()
+	handlers := make([]func(Event), 0, len(b.handlers[name])+len(b.all))
+	handlers = append(handlers, b.handlers[name]...)
+	handlers = append(handlers, b.all...)
+	b.mu.RUnlock()
+
+	event := Event{Name: name, Payload: payload}
+	for _, handler := range handlers {
+		handler(event)
+	}
+}
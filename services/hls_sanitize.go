@@ -0,0 +1,163 @@
+package services
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// hlsPlaylistContentType is returned for every playlist, whatever the source claimed
+const hlsPlaylistContentType = "application/vnd.apple.mpegurl"
+
+const (
+	tsPacketSize = 188
+	tsSyncByte   = 0x47
+
+	// tsMinPackets sync bytes in a row are required before data is taken to be MPEG-TS
+	tsMinPackets = 7
+
+	// vodMinSeconds is the length above which a playlist without an end tag is
+	// assumed to be a VOD with the tag missing; most live windows are far shorter
+	vodMinSeconds = 15 * 60
+)
+
+var utf8BOM = []byte{0xEF, 0xBB, 0xBF}
+
+// imageSignatures are the headers segments are disguised behind
+var imageSignatures = []struct {
+	name  string
+	magic []byte
+}{
+	{"PNG", []byte{0x89, 'P', 'N', 'G', '\r', '\n', 0x1A, '\n'}},
+	{"JPEG", []byte{0xFF, 0xD8, 0xFF}},
+	{"GIF", []byte("GIF8")},
+	{"BMP", []byte("BM")},
+	{"WebP", []byte("RIFF")},
+}
+
+var extinfPattern = regexp.MustCompile(`(?m)^#EXTINF:\s*([0-9.]+)`)
+
+// mediaSequencePattern finds the sequence number of a playlist's first segment
+var mediaSequencePattern = regexp.MustCompile(`(?m)^#EXT-X-MEDIA-SEQUENCE:\s*(\d+)`)
+
+// isHLSPlaylist reports whether a response body is an HLS playlist, whatever its content type
+func isHLSPlaylist(data []byte) bool {
+	data = bytes.TrimPrefix(data, utf8BOM)
+	return bytes.HasPrefix(bytes.TrimSpace(data), []byte("#EXTM3U"))
+}
+
+// SanitizePlaylist normalizes a playlist so strict players accept it: the byte
+// order mark and stray whitespace are removed, line endings become LF, and a
+// long media playlist without #EXT-X-ENDLIST gets one. It returns the cleaned
+// playlist and a description of each fix applied.
+func SanitizePlaylist(data []byte) ([]byte, []string) {
+	fixes := []string{}
+
+	if bytes.HasPrefix(data, utf8BOM) {
+		data = data[len(utf8BOM):]
+		fixes = append(fixes, "removed byte order mark")
+	}
+	if bytes.ContainsRune(data, '\r') {
+		data = bytes.ReplaceAll(data, []byte("\r\n"), []byte("\n"))
+		data = bytes.ReplaceAll(data, []byte("\r"), []byte("\n"))
+		fixes = append(fixes, "normalized line endings")
+	}
+
+	text := string(data)
+	if trimmed := strings.TrimLeft(text, " \t\n"); trimmed != text {
+		text = trimmed
+		fixes = append(fixes, "removed leading whitespace")
+	}
+
+	lines := strings.Split(strings.TrimRight(text, " \t\n"), "\n")
+	trailing := false
+	for i, line := range lines {
+		if trimmed := strings.TrimRight(line, " \t"); trimmed != line {
+			lines[i] = trimmed
+			trailing = true
+		}
+	}
+	if trailing {
+		fixes = append(fixes, "removed trailing whitespace")
+	}
+	text = strings.Join(lines, "\n") + "\n"
+
+	if needsEndList(text) {
+		text += "#EXT-X-ENDLIST\n"
+		fixes = append(fixes, "added #EXT-X-ENDLIST")
+	}
+
+	return []byte(text), fixes
+}
+
+// needsEndList reports whether a media playlist is a VOD that is missing its end tag
+func needsEndList(text string) bool {
+	if strings.Contains(text, "#EXT-X-ENDLIST") || strings.Contains(text, "#EXT-X-STREAM-INF") {
+		return false
+	}
+	if strings.Contains(text, "#EXT-X-PLAYLIST-TYPE:VOD") {
+		return true
+	}
+	if strings.Contains(text, "#EXT-X-PLAYLIST-TYPE:EVENT") {
+		return false
+	}
+	// A window that no longer starts at the first segment is a live or DVR stream, however long
+	if m := mediaSequencePattern.FindStringSubmatch(text); m != nil && strings.TrimLeft(m[1], "0") != "" {
+		return false
+	}
+
+	total := 0.0
+	for _, m := range extinfPattern.FindAllStringSubmatch(text, -1) {
+		if d, err := strconv.ParseFloat(m[1], 64); err == nil {
+			total += d
+		}
+	}
+	return total >= vodMinSeconds
+}
+
+// SanitizeSegment undoes segments disguised as images: MPEG-TS data hidden
+// behind a fake PNG, JPEG, GIF, BMP or WebP header is returned without the
+// header. Anything else is returned unchanged with an empty description.
+func SanitizeSegment(data []byte) ([]byte, string) {
+	for _, sig := range imageSignatures {
+		if !bytes.HasPrefix(data, sig.magic) {
+			continue
+		}
+		offset := findTSStart(data, len(sig.magic))
+		if offset < 0 {
+			return data, ""
+		}
+		return data[offset:], fmt.Sprintf("stripped %d-byte %s header", offset, sig.name)
+	}
+	return data, ""
+}
+
+// findTSStart returns the first offset from which data reads as MPEG-TS, or -1
+func findTSStart(data []byte, from int) int {
+	for i := from; i < len(data); i++ {
+		next := bytes.IndexByte(data[i:], tsSyncByte)
+		if next < 0 {
+			return -1
+		}
+		i += next
+		if isTSAt(data, i) {
+			return i
+		}
+	}
+	return -1
+}
+
+// isTSAt reports whether the tsMinPackets packets starting at offset all carry the sync byte
+func isTSAt(data []byte, offset int) bool {
+	if len(data)-offset < tsMinPackets*tsPacketSize {
+		return false
+	}
+	for p := 0; p < tsMinPackets; p++ {
+		if data[offset+p*tsPacketSize] != tsSyncByte {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,138 @@
+package services
+
+import (
+	"bytes"
+	"flag"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+var updateGolden = flag.Bool("update", false, "rewrite the golden files in testdata")
+
+func readFixture(t *testing.T, name string) []byte {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join("testdata", "hls", name))
+	if err != nil {
+		t.Fatalf("failed to read fixture: %v", err)
+	}
+	return data
+}
+
+func TestSanitizePlaylist(t *testing.T) {
+	tests := []struct {
+		fixture string
+		fixes   []string
+	}{
+		{"vod_crlf_bom.m3u8", []string{"removed byte order mark", "normalized line endings", "removed trailing whitespace", "added #EXT-X-ENDLIST"}},
+		{"vod_missing_endlist.m3u8", []string{"added #EXT-X-ENDLIST"}},
+		{"live_window.m3u8", []string{}},
+		{"live_dvr_window.m3u8", []string{}},
+		{"master_crlf.m3u8", []string{"normalized line endings", "removed leading whitespace"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.fixture, func(t *testing.T) {
+			input := readFixture(t, tt.fixture)
+			if !isHLSPlaylist(input) {
+				t.Fatalf("fixture not recognized as a playlist")
+			}
+
+			got, fixes := SanitizePlaylist(input)
+			if !reflect.DeepEqual(fixes, tt.fixes) {
+				t.Errorf("fixes = %q, want %q", fixes, tt.fixes)
+			}
+
+			golden := filepath.Join("testdata", "hls", tt.fixture+".golden")
+			if *updateGolden {
+				if err := os.WriteFile(golden, got, 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			want, err := os.ReadFile(golden)
+			if err != nil {
+				t.Fatalf("failed to read golden file: %v", err)
+			}
+			if !bytes.Equal(got, want) {
+				t.Errorf("sanitized playlist differs from %s:\n%s", golden, got)
+			}
+
+			// Sanitizing is idempotent
+			again, fixes := SanitizePlaylist(got)
+			if !bytes.Equal(again, got) || len(fixes) != 0 {
+				t.Errorf("second pass changed the playlist: %q", fixes)
+			}
+		})
+	}
+}
+
+func TestSanitizeSegment(t *testing.T) {
+	plain := readFixture(t, "segment_plain.ts")
+
+	tests := []struct {
+		fixture string
+		want    []byte
+		fix     string
+	}{
+		{"segment_plain.ts", plain, ""},
+		{"segment_png.ts", plain, "stripped 68-byte PNG header"},
+		{"segment_jpeg.ts", plain, "stripped 62-byte JPEG header"},
+		{"image_real.png", readFixture(t, "image_real.png"), ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.fixture, func(t *testing.T) {
+			got, fix := SanitizeSegment(readFixture(t, tt.fixture))
+			if fix != tt.fix {
+				t.Errorf("fix = %q, want %q", fix, tt.fix)
+			}
+			if !bytes.Equal(got, tt.want) {
+				t.Errorf("got %d bytes starting % x, want %d bytes", len(got), got[:8], len(tt.want))
+			}
+		})
+	}
+}
+
+func TestProxyURLSanitizes(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/index.m3u8":
+			w.Header().Set("Content-Type", "text/plain")
+			w.Write(readFixture(t, "vod_crlf_bom.m3u8"))
+		case "/0000.png":
+			w.Header().Set("Content-Type", "image/png")
+			w.Write(readFixture(t, "segment_png.ts"))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	p := NewProxyService()
+
+	playlist, err := p.ProxyURL(server.URL + "/index.m3u8")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if playlist.ContentType != hlsPlaylistContentType {
+		t.Errorf("playlist content type = %q", playlist.ContentType)
+	}
+	want, _ := SanitizePlaylist(readFixture(t, "vod_crlf_bom.m3u8"))
+	if !bytes.Equal(playlist.Data, want) {
+		t.Errorf("playlist was not sanitized:\n%s", playlist.Data)
+	}
+
+	segment, err := p.ProxyURL(server.URL + "/0000.png")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if segment.ContentType != "video/MP2T" {
+		t.Errorf("segment content type = %q", segment.ContentType)
+	}
+	if !bytes.Equal(segment.Data, readFixture(t, "segment_plain.ts")) {
+		t.Errorf("segment header was not stripped")
+	}
+}
@@ -0,0 +1,231 @@
+package services
+
+import (
+	"crypto/rand"
+	"database/sql"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Registration modes for the registration_mode setting
+const (
+	RegistrationOpen       = "open"
+	RegistrationInviteOnly = "invite_only"
+	RegistrationClosed     = "closed"
+)
+
+const (
+	// inviteCodeAlphabet is upper case only so codes survive being read aloud
+	inviteCodeAlphabet = "ABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+	inviteCodeGroups   = 3
+	inviteCodeGroupLen = 4
+)
+
+// InviteCode is an admin-issued code that allows signing up with a preset role
+type InviteCode struct {
+	ID        int    `json:"id"`
+	Code      string `json:"code"`
+	UserRole  string `json:"user_role"`
+	MaxUses   int    `json:"max_uses"`
+	UseCount  int    `json:"use_count"`
+	ExpiresAt string `json:"expires_at,omitempty"`
+	CreatedBy *int   `json:"created_by"`
+	CreatedAt string `json:"created_at"`
+	Usable    bool   `json:"usable"`
+}
+
+// CreateInviteCode issues a code granting role. maxUses of 0 means unlimited;
+// expiresInHours of 0 means the code never expires. Only admins may invite admins.
+func (as *AuthService) CreateInviteCode(adminID int, role string, maxUses, expiresInHours int) (*InviteCode, error) {
+	if err := checkAssignableRole(as.db.GetDB(), role); err != nil {
+		return nil, err
+	}
+	if role == RoleAdmin {
+		if err := checkActorIsAdmin(as.db.GetDB(), adminID); err != nil {
+			return nil, err
+		}
+	}
+	if maxUses < 0 {
+		return nil, fmt.Errorf("max uses cannot be negative")
+	}
+	if expiresInHours < 0 {
+		return nil, fmt.Errorf("expiry cannot be negative")
+	}
+
+	code, err := generateInviteCode()
+	if err != nil {
+		return nil, err
+	}
+
+	var expiresAt interface{}
+	if expiresInHours > 0 {
+		expiresAt = time.Now().UTC().Add(time.Duration(expiresInHours) * time.Hour).Format(dbTimeLayout)
+	}
+
+	tx, err := as.db.GetDB().Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(`
+		INSERT INTO invite_codes (code, user_role, max_uses, expires_at, created_by, created_at)
+		VALUES (?, ?, ?, ?, ?, CURRENT_TIMESTAMP)
+	`, code, role, maxUses, expiresAt, adminID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create invite code: %w", err)
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return nil, err
+	}
+
+	after := map[string]interface{}{"code": code, "user_role": role, "max_uses": maxUses, "expires_at": expiresAt}
+	if err := recordAudit(tx, adminID, AuditInviteCreated, AuditTargetInvite, strconv.FormatInt(id, 10), nil, after); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	invites, err := as.queryInviteCodes("WHERE id = ?", id)
+	if err != nil {
+		return nil, err
+	}
+	if len(invites) == 0 {
+		return nil, fmt.Errorf("invite code not found")
+	}
+	return &invites[0], nil
+}
+
+// ListInviteCodes returns every invite code, newest first
+func (as *AuthService) ListInviteCodes() ([]InviteCode, error) {
+	return as.queryInviteCodes("ORDER BY id DESC")
+}
+
+// RevokeInviteCode deletes an invite code so it can no longer be used
+func (as *AuthService) RevokeInviteCode(actorID, codeID int) error {
+	tx, err := as.db.GetDB().Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var code, role string
+	var useCount int
+	err = tx.QueryRow("DELETE FROM invite_codes WHERE id = ? RETURNING code, user_role, use_count", codeID).Scan(&code, &role, &useCount)
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("invite code not found")
+	} else if err != nil {
+		return fmt.Errorf("failed to revoke invite code: %w", err)
+	}
+
+	before := map[string]interface{}{"code": code, "user_role": role, "use_count": useCount}
+	if err := recordAudit(tx, actorID, AuditInviteRevoked, AuditTargetInvite, strconv.Itoa(codeID), before, nil); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// claimInviteCode uses up one redemption of code inside the signup transaction
+// and returns its ID and role. The use is counted atomically so a single-use
+// code cannot be redeemed twice.
+func claimInviteCode(tx *sql.Tx, code string) (int, string, error) {
+	code = normalizeInviteCode(code)
+	now := time.Now().UTC().Format(dbTimeLayout)
+
+	var id int
+	var role string
+	err := tx.QueryRow(`
+		UPDATE invite_codes
+		SET use_count = use_count + 1
+		WHERE code = ?
+			AND (max_uses = 0 OR use_count < max_uses)
+			AND (expires_at IS NULL OR expires_at > ?)
+		RETURNING id, user_role
+	`, code, now).Scan(&id, &role)
+	if err == sql.ErrNoRows {
+		return 0, "", fmt.Errorf("invalid or expired invite code")
+	} else if err != nil {
+		return 0, "", fmt.Errorf("failed to redeem invite code: %w", err)
+	}
+	return id, role, nil
+}
+
+func (as *AuthService) queryInviteCodes(clause string, args ...interface{}) ([]InviteCode, error) {
+	rows, err := as.db.GetDB().Query(`
+		SELECT id, code, user_role, max_uses, use_count, expires_at, created_by, created_at
+		FROM invite_codes
+	`+clause, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query invite codes: %w", err)
+	}
+	defer rows.Close()
+
+	now := time.Now()
+	invites := []InviteCode{}
+	for rows.Next() {
+		var invite InviteCode
+		var expiresAt sql.NullString
+		var createdBy sql.NullInt64
+		if err := rows.Scan(&invite.ID, &invite.Code, &invite.UserRole, &invite.MaxUses, &invite.UseCount,
+			&expiresAt, &createdBy, &invite.CreatedAt); err != nil {
+			return nil, err
+		}
+
+		invite.Usable = invite.MaxUses == 0 || invite.UseCount < invite.MaxUses
+		if expiresAt.Valid {
+			invite.ExpiresAt = expiresAt.String
+			if t, err := parseDBTime(expiresAt.String); err == nil && !now.Before(t) {
+				invite.Usable = false
+			}
+		}
+		if createdBy.Valid {
+			id := int(createdBy.Int64)
+			invite.CreatedBy = &id
+		}
+		invites = append(invites, invite)
+	}
+	return invites, rows.Err()
+}
+
+// generateInviteCode returns a code such as "K7QM-3ZTP-WX9A"
+func generateInviteCode() (string, error) {
+	max := big.NewInt(int64(len(inviteCodeAlphabet)))
+	groups := make([]string, inviteCodeGroups)
+	for g := range groups {
+		group := make([]byte, inviteCodeGroupLen)
+		for i := range group {
+			n, err := rand.Int(rand.Reader, max)
+			if err != nil {
+				return "", fmt.Errorf("failed to generate invite code: %w", err)
+			}
+			group[i] = inviteCodeAlphabet[n.Int64()]
+		}
+		groups[g] = string(group)
+	}
+	return strings.Join(groups, "-"), nil
+}
+
+// normalizeInviteCode accepts codes typed in lower case, with spaces or without dashes
+func normalizeInviteCode(code string) string {
+	var b strings.Builder
+	for _, r := range strings.ToUpper(code) {
+		if strings.ContainsRune(inviteCodeAlphabet, r) {
+			b.WriteRune(r)
+		}
+	}
+	compact := b.String()
+	if len(compact) != inviteCodeGroups*inviteCodeGroupLen {
+		return compact
+	}
+
+	groups := make([]string, inviteCodeGroups)
+	for g := range groups {
+		groups[g] = compact[g*inviteCodeGroupLen : (g+1)*inviteCodeGroupLen]
+	}
+	return strings.Join(groups, "-")
+}
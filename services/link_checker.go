@@ -0,0 +1,403 @@
+package services
+
+import (
+	"database/sql"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Values of source_checks.status
+const (
+	SourceAlive = "alive"
+	SourceDead  = "dead"
+)
+
+// linkProbeLimit bytes of a manifest are read, enough to tell it is a playlist
+const linkProbeLimit = 64 * 1024
+
+// LinkCheckerService periodically probes the video sources of bookmarked media
+type LinkCheckerService struct {
+	db    *DatabaseService
+	proxy *ProxyService
+
+	mu      sync.Mutex
+	running bool
+	lastRun time.Time // covers passes that found nothing to store
+	stop    chan struct{}
+}
+
+// SourceCheck is the last known status of one episode of one video source of a title
+type SourceCheck struct {
+	MCID      string `json:"mc_id"`
+	Source    string `json:"source"`
+	Label     string `json:"label"`
+	URL       string `json:"url"`
+	Status    string `json:"status"`
+	Error     string `json:"error,omitempty"`
+	DeadSince string `json:"dead_since,omitempty"`
+	CheckedAt string `json:"checked_at"`
+}
+
+// LinkCheckReport summarizes one pass of the link checker
+type LinkCheckReport struct {
+	Titles      int         `json:"titles"`
+	Sources     int         `json:"sources"`
+	DeadSources int         `json:"dead_sources"`
+	DeadTitles  []DeadTitle `json:"dead_titles"`
+	CheckedAt   string      `json:"checked_at"`
+}
+
+type linkCheckJob struct {
+	mcID     string
+	sourceID int64
+	source   string
+	label    string
+	url      string
+	err      error
+}
+
+type linkCheckTitle struct {
+	mcID  string
+	title string
+	jobs  []*linkCheckJob
+}
+
+// NewLinkCheckerService creates a new LinkCheckerService instance
+func NewLinkCheckerService(db *DatabaseService, proxy *ProxyService) *LinkCheckerService {
+	return &LinkCheckerService{
+		db:    db,
+		proxy: proxy,
+	}
+}
+
+// CheckNow probes every source of every bookmarked title, stores the results
+// and publishes EventDeadLinks if any title has no working source left
+func (lc *LinkCheckerService) CheckNow() (*LinkCheckReport, error) {
+	lc.mu.Lock()
+	if lc.running {
+		lc.mu.Unlock()
+		return nil, fmt.Errorf("a link check is already running")
+	}
+	lc.running = true
+	lc.mu.Unlock()
+	defer func() {
+		lc.mu.Lock()
+		lc.running = false
+		lc.lastRun = time.Now()
+		lc.mu.Unlock()
+	}()
+
+	titles, err := lc.bookmarkedTitles()
+	if err != nil {
+		return nil, err
+	}
+
+	var jobs []*linkCheckJob
+	for _, t := range titles {
+		jobs = append(jobs, t.jobs...)
+	}
+	lc.probeAll(jobs, lc.db.GetEffectiveInt(0, "link_check_concurrency"))
+
+	report := &LinkCheckReport{
+		Titles:     len(titles),
+		Sources:    len(jobs),
+		DeadTitles: []DeadTitle{},
+		CheckedAt:  time.Now().UTC().Format(time.RFC3339),
+	}
+	for _, t := range titles {
+		if err := lc.saveResults(t); err != nil {
+			return nil, err
+		}
+		dead := 0
+		for _, job := range t.jobs {
+			if job.err != nil {
+				dead++
+			}
+		}
+		report.DeadSources += dead
+		if dead > 0 && dead == len(t.jobs) {
+			report.DeadTitles = append(report.DeadTitles, DeadTitle{MCID: t.mcID, Title: t.title, Sources: dead})
+		}
+	}
+
+	log.Printf("Link check: %d titles, %d sources, %d dead", report.Titles, report.Sources, report.DeadSources)
+	if len(report.DeadTitles) > 0 {
+		lc.db.Events().Publish(EventDeadLinks, DeadLinksEvent{Titles: report.DeadTitles, CheckedAt: report.CheckedAt})
+	}
+	return report, nil
+}
+
+// GetSourceChecks returns the last known status of each source of a title
+func (lc *LinkCheckerService) GetSourceChecks(mcID string) ([]SourceCheck, error) {
+	rows, err := lc.db.GetDB().Query(`
+		SELECT mc_id, source, label, url, status, COALESCE(error, ''), dead_since, checked_at
+		FROM source_checks WHERE mc_id = ? ORDER BY source, label
+	`, mcID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query source checks: %w", err)
+	}
+	defer rows.Close()
+
+	checks := []SourceCheck{}
+	for rows.Next() {
+		var c SourceCheck
+		var deadSince sql.NullString
+		if err := rows.Scan(&c.MCID, &c.Source, &c.Label, &c.URL, &c.Status, &c.Error, &deadSince, &c.CheckedAt); err != nil {
+			return nil, err
+		}
+		c.DeadSince = deadSince.String
+		checks = append(checks, c)
+	}
+	return checks, rows.Err()
+}
+
+// Start runs scheduled link checks in the background until Stop is called
+func (lc *LinkCheckerService) Start() {
+	lc.mu.Lock()
+	if lc.stop != nil {
+		lc.mu.Unlock()
+		return
+	}
+	stop := make(chan struct{})
+	lc.stop = stop
+	lc.mu.Unlock()
+
+	go lc.run(stop)
+}
+
+// Stop ends the background scheduler
+func (lc *LinkCheckerService) Stop() {
+	lc.mu.Lock()
+	defer lc.mu.Unlock()
+	if lc.stop != nil {
+		close(lc.stop)
+		lc.stop = nil
+	}
+}
+
+func (lc *LinkCheckerService) run(stop chan struct{}) {
+	// Check once a minute so interval changes take effect without a restart
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+
+	for {
+		lc.checkIfDue()
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (lc *LinkCheckerService) checkIfDue() {
+	interval := time.Duration(lc.db.GetEffectiveInt(0, "link_check_interval_hours")) * time.Hour
+	if interval <= 0 {
+		return
+	}
+
+	lc.mu.Lock()
+	lastRun := lc.lastRun
+	lc.mu.Unlock()
+	if time.Since(lastRun) < interval {
+		return
+	}
+
+	// Stored results carry the last check across restarts
+	var last sql.NullString
+	if err := lc.db.GetDB().QueryRow("SELECT MAX(checked_at) FROM source_checks").Scan(&last); err != nil {
+		log.Printf("Failed to read last link check: %v", err)
+		return
+	}
+	if last.Valid {
+		if t, err := parseDBTime(last.String); err == nil && time.Since(t) < interval {
+			return
+		}
+	}
+
+	if _, err := lc.CheckNow(); err != nil {
+		log.Printf("Scheduled link check failed: %v", err)
+	}
+}
+
+// bookmarkedTitles loads every title bookmarked by any profile with the episodes of all its sources
+func (lc *LinkCheckerService) bookmarkedTitles() ([]*linkCheckTitle, error) {
+	rows, err := lc.db.GetDB().Query(`
+		SELECT m.mc_id, m.title
+		FROM medias m
+		WHERE m.mc_id IN (SELECT mc_id FROM bookmarks)
+		ORDER BY m.mc_id
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query bookmarked media: %w", err)
+	}
+	defer rows.Close()
+
+	var titles []*linkCheckTitle
+	for rows.Next() {
+		var t linkCheckTitle
+		if err := rows.Scan(&t.mcID, &t.title); err != nil {
+			return nil, err
+		}
+		titles = append(titles, &t)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	sources, err := lc.db.queryMediaSources("s.mc_id IN (SELECT mc_id FROM bookmarks)")
+	if err != nil {
+		return nil, err
+	}
+
+	checked := titles[:0]
+	for _, t := range titles {
+		for _, source := range sources[t.mcID] {
+			for _, e := range source.Episodes {
+				if e.URL == "" {
+					continue
+				}
+				t.jobs = append(t.jobs, &linkCheckJob{
+					mcID:     t.mcID,
+					sourceID: source.ID,
+					source:   source.Name,
+					label:    e.Title,
+					url:      normalizePlaylistURL(e.URL),
+				})
+			}
+		}
+		if len(t.jobs) > 0 {
+			checked = append(checked, t)
+		}
+	}
+	return checked, nil
+}
+
+// probeAll probes jobs with at most concurrency requests in flight
+func (lc *LinkCheckerService) probeAll(jobs []*linkCheckJob, concurrency int) {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	sem := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	for _, job := range jobs {
+		sem <- struct{}{}
+		wg.Add(1)
+		go func(job *linkCheckJob) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			job.err = lc.probe(job.url)
+		}(job)
+	}
+	wg.Wait()
+}
+
+// probe fetches the start of a manifest and checks that it is a playlist.
+// It does not go through ProxyURL so checks are not mistaken for playback.
+func (lc *LinkCheckerService) probe(manifestURL string) error {
+	userAgent, timeout := lc.proxy.config()
+	client := &http.Client{Timeout: timeout}
+
+	req, err := http.NewRequest("GET", manifestURL, nil)
+	if err != nil {
+		return fmt.Errorf("invalid url: %w", err)
+	}
+	req.Header.Set("User-Agent", userAgent)
+	req.Header.Set("Accept", "*/*")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("status %d", resp.StatusCode)
+	}
+	head, err := io.ReadAll(io.LimitReader(resp.Body, linkProbeLimit))
+	if err != nil {
+		return fmt.Errorf("failed to read manifest: %w", err)
+	}
+	if !isHLSPlaylist(head) {
+		return fmt.Errorf("not an HLS playlist")
+	}
+	return nil
+}
+
+// saveResults replaces a title's stored checks with the results of this pass.
+// dead_since is kept while a source stays dead.
+func (lc *LinkCheckerService) saveResults(t *linkCheckTitle) error {
+	tx, err := lc.db.GetDB().Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	type checkKey struct{ source, label string }
+	previous := map[checkKey]sql.NullString{}
+	rows, err := tx.Query("SELECT source, label, dead_since FROM source_checks WHERE mc_id = ?", t.mcID)
+	if err != nil {
+		return fmt.Errorf("failed to query source checks: %w", err)
+	}
+	for rows.Next() {
+		var key checkKey
+		var deadSince sql.NullString
+		if err := rows.Scan(&key.source, &key.label, &deadSince); err != nil {
+			rows.Close()
+			return err
+		}
+		previous[key] = deadSince
+	}
+	err = rows.Err()
+	rows.Close()
+	if err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec("DELETE FROM source_checks WHERE mc_id = ?", t.mcID); err != nil {
+		return fmt.Errorf("failed to clear source checks: %w", err)
+	}
+
+	for _, job := range t.jobs {
+		status, errText, deadSince := SourceAlive, interface{}(nil), interface{}(nil)
+		if job.err != nil {
+			status, errText = SourceDead, job.err.Error()
+			deadSince = time.Now().UTC().Format(dbTimeLayout)
+			if prev := previous[checkKey{job.source, job.label}]; prev.Valid {
+				deadSince = normalizeDBTime(prev.String)
+			}
+		}
+		if _, err := tx.Exec(`
+			INSERT OR REPLACE INTO source_checks (mc_id, source, label, url, status, error, dead_since, checked_at)
+			VALUES (?, ?, ?, ?, ?, ?, ?, CURRENT_TIMESTAMP)
+		`, t.mcID, job.source, job.label, job.url, status, errText, deadSince); err != nil {
+			return fmt.Errorf("failed to save source check: %w", err)
+		}
+	}
+
+	// A source is alive while any of its episodes plays
+	health := map[int64]string{}
+	for _, job := range t.jobs {
+		if job.err == nil {
+			health[job.sourceID] = SourceAlive
+		} else if health[job.sourceID] == "" {
+			health[job.sourceID] = SourceDead
+		}
+	}
+	for sourceID, status := range health {
+		if _, err := tx.Exec(`
+			UPDATE media_sources SET health = ?, updated_at = CURRENT_TIMESTAMP
+			WHERE id = ?
+		`, status, sourceID); err != nil {
+			return fmt.Errorf("failed to update source health: %w", err)
+		}
+	}
+
+	return tx.Commit()
+}
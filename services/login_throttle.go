@@ -0,0 +1,301 @@
+package services
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"math"
+	"strings"
+	"time"
+)
+
+// Auth event types recorded in auth_events
+const (
+	AuthEventLoginSuccess    = "login_success"
+	AuthEventLoginFailed     = "login_failed"
+	AuthEventLoginBlocked    = "login_blocked"
+	AuthEventLogout          = "logout"
+	AuthEventPasswordChanged = "password_changed"
+	AuthEventUnlocked        = "account_unlocked"
+)
+
+const (
+	// loginFreeFailures is how many failures are allowed before backoff starts
+	loginFreeFailures = 3
+	// loginMaxBackoff caps the delay between attempts before the lockout threshold
+	loginMaxBackoff = 5 * time.Minute
+)
+
+// AuthEvent is a row of the auth audit log
+type AuthEvent struct {
+	ID        int    `json:"id"`
+	UserID    *int   `json:"user_id"`
+	Username  string `json:"username"`
+	EventType string `json:"event_type"`
+	Success   bool   `json:"success"`
+	Detail    string `json:"detail"`
+	CreatedAt string `json:"created_at"`
+}
+
+// LoginLockout describes a username with recent failed logins
+type LoginLockout struct {
+	Username     string `json:"username"`
+	FailedCount  int    `json:"failed_count"`
+	LastFailedAt string `json:"last_failed_at"`
+	LockedUntil  string `json:"locked_until,omitempty"`
+}
+
+// attemptThrottle names the table and key column failed attempts are counted in.
+// PINs are throttled apart from logins so a username can never share a PIN's counter.
+type attemptThrottle struct {
+	table  string
+	column string
+}
+
+var (
+	loginThrottle = attemptThrottle{table: "login_attempts", column: "username"}
+	pinThrottle   = attemptThrottle{table: "pin_attempts", column: "pin_key"}
+)
+
+// throttleKey normalizes the name a login attempt is tracked under
+func throttleKey(username string) string {
+	return strings.ToLower(strings.TrimSpace(username))
+}
+
+// checkLoginAllowed returns an error if a username is locked out or still backing off
+func (as *AuthService) checkLoginAllowed(key string) error {
+	return as.checkAttemptAllowed(loginThrottle, key)
+}
+
+// recordLoginFailure counts a failed login for a username
+func (as *AuthService) recordLoginFailure(key string) {
+	as.recordAttemptFailure(loginThrottle, key)
+}
+
+// clearLoginFailures resets the failure count of a username after a successful login
+func (as *AuthService) clearLoginFailures(key string) {
+	as.clearAttemptFailures(loginThrottle, key)
+}
+
+// checkPINAllowed returns an error if a profile or parental PIN is locked out or still backing off
+func (as *AuthService) checkPINAllowed(key string) error {
+	return as.checkAttemptAllowed(pinThrottle, key)
+}
+
+// recordPINFailure counts a wrong profile or parental PIN
+func (as *AuthService) recordPINFailure(key string) {
+	as.recordAttemptFailure(pinThrottle, key)
+}
+
+// clearPINFailures resets the failure count of a PIN after it was entered correctly
+func (as *AuthService) clearPINFailures(key string) {
+	as.clearAttemptFailures(pinThrottle, key)
+}
+
+// checkAttemptAllowed returns an error if key is locked out or still backing off
+func (as *AuthService) checkAttemptAllowed(t attemptThrottle, key string) error {
+	var failedCount int
+	var lastFailedAt, lockedUntil sql.NullString
+	err := as.db.GetDB().QueryRow(
+		"SELECT failed_count, last_failed_at, locked_until FROM "+t.table+" WHERE "+t.column+" = ?",
+		key).Scan(&failedCount, &lastFailedAt, &lockedUntil)
+	if err == sql.ErrNoRows {
+		return nil
+	} else if err != nil {
+		return fmt.Errorf("failed to check login attempts: %w", err)
+	}
+
+	now := time.Now()
+
+	if lockedUntil.Valid {
+		if until, err := parseDBTime(lockedUntil.String); err == nil && now.Before(until) {
+			return fmt.Errorf("account temporarily locked, try again in %s", formatWait(until.Sub(now)))
+		}
+	}
+
+	if lastFailedAt.Valid {
+		if last, err := parseDBTime(lastFailedAt.String); err == nil {
+			if wait := last.Add(loginBackoff(failedCount)).Sub(now); wait > 0 {
+				return fmt.Errorf("too many failed attempts, try again in %s", formatWait(wait))
+			}
+		}
+	}
+
+	return nil
+}
+
+// recordAttemptFailure counts a failed attempt and locks the key once the threshold is reached
+func (as *AuthService) recordAttemptFailure(t attemptThrottle, key string) {
+	threshold := as.db.GetEffectiveInt(0, "login_lockout_threshold")
+	lockout := time.Duration(as.db.GetEffectiveInt(0, "login_lockout_minutes")) * time.Minute
+	now := time.Now().UTC()
+
+	tx, err := as.db.GetDB().Begin()
+	if err != nil {
+		log.Printf("Failed to record login failure: %v", err)
+		return
+	}
+	defer tx.Rollback()
+
+	var failedCount int
+	err = tx.QueryRow("SELECT failed_count FROM "+t.table+" WHERE "+t.column+" = ?", key).Scan(&failedCount)
+	if err != nil && err != sql.ErrNoRows {
+		log.Printf("Failed to record login failure: %v", err)
+		return
+	}
+	failedCount++
+
+	var lockedUntil interface{}
+	if threshold > 0 && failedCount >= threshold {
+		lockedUntil = now.Add(lockout).Format(dbTimeLayout)
+		// Start counting afresh once the lockout expires
+		failedCount = 0
+	}
+
+	_, err = tx.Exec(`
+		INSERT INTO `+t.table+` (`+t.column+`, failed_count, last_failed_at, locked_until)
+		VALUES (?, ?, ?, ?)
+		ON CONFLICT(`+t.column+`) DO UPDATE SET
+			failed_count = excluded.failed_count,
+			last_failed_at = excluded.last_failed_at,
+			locked_until = COALESCE(excluded.locked_until, `+t.table+`.locked_until)
+	`, key, failedCount, now.Format(dbTimeLayout), lockedUntil)
+	if err != nil {
+		log.Printf("Failed to record login failure: %v", err)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		log.Printf("Failed to record login failure: %v", err)
+	}
+}
+
+// clearAttemptFailures resets the failure count of key after a successful attempt
+func (as *AuthService) clearAttemptFailures(t attemptThrottle, key string) {
+	if _, err := as.db.GetDB().Exec("DELETE FROM "+t.table+" WHERE "+t.column+" = ?", key); err != nil {
+		log.Printf("Failed to clear login attempts: %v", err)
+	}
+}
+
+// UnlockAccount clears failed attempts and any lockout for a username
+func (as *AuthService) UnlockAccount(actorID int, username string) error {
+	key := throttleKey(username)
+	tx, err := as.db.GetDB().Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("DELETE FROM login_attempts WHERE username = ?", key); err != nil {
+		return fmt.Errorf("failed to unlock account: %w", err)
+	}
+	if err := recordAudit(tx, actorID, AuditAccountUnlocked, AuditTargetUser, key, nil, nil); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	as.recordAuthEvent(nil, key, AuthEventUnlocked, true, "")
+	return nil
+}
+
+// ListLoginLockouts returns usernames with recent failures or an active lockout
+func (as *AuthService) ListLoginLockouts() ([]LoginLockout, error) {
+	rows, err := as.db.GetDB().Query(`
+		SELECT username, failed_count, COALESCE(last_failed_at, ''), COALESCE(locked_until, '')
+		FROM login_attempts
+		ORDER BY last_failed_at DESC
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	lockouts := []LoginLockout{}
+	for rows.Next() {
+		var l LoginLockout
+		if err := rows.Scan(&l.Username, &l.FailedCount, &l.LastFailedAt, &l.LockedUntil); err != nil {
+			return nil, err
+		}
+		lockouts = append(lockouts, l)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return lockouts, nil
+}
+
+// recordAuthEvent appends to the auth audit log. Failures are logged, not returned,
+// so auditing never blocks authentication.
+func (as *AuthService) recordAuthEvent(userID *int, username, eventType string, success bool, detail string) {
+	_, err := as.db.GetDB().Exec(`
+		INSERT INTO auth_events (user_id, username, event_type, success, detail, created_at)
+		VALUES (?, ?, ?, ?, ?, CURRENT_TIMESTAMP)
+	`, userID, username, eventType, success, detail)
+	if err != nil {
+		log.Printf("Failed to record auth event: %v", err)
+	}
+}
+
+// ListAuthEvents returns recent auth events, newest first, optionally filtered by username
+func (as *AuthService) ListAuthEvents(username string, limit, offset int) ([]AuthEvent, error) {
+	if limit <= 0 || limit > 500 {
+		limit = 100
+	}
+	if offset < 0 {
+		offset = 0
+	}
+
+	query := `SELECT id, user_id, username, event_type, success, COALESCE(detail, ''), created_at FROM auth_events`
+	var args []interface{}
+	if username != "" {
+		query += ` WHERE username = ?`
+		args = append(args, throttleKey(username))
+	}
+	query += ` ORDER BY id DESC LIMIT ? OFFSET ?`
+	args = append(args, limit, offset)
+
+	rows, err := as.db.GetDB().Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	events := []AuthEvent{}
+	for rows.Next() {
+		var e AuthEvent
+		var userID sql.NullInt64
+		if err := rows.Scan(&e.ID, &userID, &e.Username, &e.EventType, &e.Success, &e.Detail, &e.CreatedAt); err != nil {
+			return nil, err
+		}
+		if userID.Valid {
+			id := int(userID.Int64)
+			e.UserID = &id
+		}
+		events = append(events, e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return events, nil
+}
+
+// loginBackoff is the wait required after failedCount consecutive failures:
+// none for the first few, then doubling from one second
+func loginBackoff(failedCount int) time.Duration {
+	if failedCount < loginFreeFailures {
+		return 0
+	}
+	delay := time.Duration(math.Pow(2, float64(failedCount-loginFreeFailures))) * time.Second
+	if delay > loginMaxBackoff {
+		return loginMaxBackoff
+	}
+	return delay
+}
+
+func formatWait(d time.Duration) string {
+	if d < time.Second {
+		d = time.Second
+	}
+	return d.Round(time.Second).String()
+}
@@ -0,0 +1,293 @@
+package services
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Values of media_sources.health; SourceAlive and SourceDead are shared with source_checks
+const SourceHealthUnknown = "unknown"
+
+// defaultSourceName names the source built from a legacy video_urls blob
+const defaultSourceName = "default"
+
+// MediaSource is one provider of a media's episodes
+type MediaSource struct {
+	ID       int64     `json:"id"`
+	Name     string    `json:"name"`
+	Type     string    `json:"type"`
+	Health   string    `json:"health"`
+	Episodes []Episode `json:"episodes"`
+}
+
+// Episode is one playable entry of a source
+type Episode struct {
+	Season int    `json:"season"`
+	Number int    `json:"number"`
+	Title  string `json:"title"`
+	URL    string `json:"url"`
+}
+
+// SourcesFromVideoURLs turns a video_urls blob, a JSON object of episode label
+// to playlist URL, into a single default source. Episodes keep the blob's order.
+func SourcesFromVideoURLs(videoURLs, sourceType string) ([]MediaSource, error) {
+	episodes, err := parseVideoURLs(videoURLs)
+	if err != nil {
+		return nil, err
+	}
+	if len(episodes) == 0 {
+		return nil, nil
+	}
+	return []MediaSource{{Name: defaultSourceName, Type: sourceType, Episodes: episodes}}, nil
+}
+
+// parseVideoURLs decodes a video_urls blob in document order, which a map would lose
+func parseVideoURLs(videoURLs string) ([]Episode, error) {
+	if strings.TrimSpace(videoURLs) == "" {
+		return nil, nil
+	}
+
+	dec := json.NewDecoder(strings.NewReader(videoURLs))
+	if tok, err := dec.Token(); err != nil || tok != json.Delim('{') {
+		return nil, fmt.Errorf("video urls must be a JSON object")
+	}
+
+	var episodes []Episode
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode video urls: %w", err)
+		}
+		label, _ := tok.(string)
+		var value interface{}
+		if err := dec.Decode(&value); err != nil {
+			return nil, fmt.Errorf("failed to decode video urls: %w", err)
+		}
+		u, ok := value.(string)
+		if !ok || u == "" {
+			continue
+		}
+		episodes = append(episodes, Episode{Season: 1, Number: len(episodes) + 1, Title: label, URL: u})
+	}
+	if _, err := dec.Token(); err != nil {
+		return nil, fmt.Errorf("failed to decode video urls: %w", err)
+	}
+	return episodes, nil
+}
+
+// encodeVideoURLs builds the video_urls blob of a source, keeping episode order
+func encodeVideoURLs(episodes []Episode) string {
+	// URLs are written unescaped, the way the frontend's JSON.stringify does
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	quote := func(s string) []byte {
+		buf.Reset()
+		enc.Encode(s)
+		return bytes.TrimRight(buf.Bytes(), "\n")
+	}
+
+	out := []byte{'{'}
+	for i, e := range episodes {
+		if i > 0 {
+			out = append(out, ',')
+		}
+		out = append(out, quote(e.Title)...)
+		out = append(out, ':')
+		out = append(out, quote(e.URL)...)
+	}
+	return string(append(out, '}'))
+}
+
+// normalizeSources fills in defaults and rejects sources that cannot be stored
+func normalizeSources(sources []MediaSource) ([]MediaSource, error) {
+	seen := make(map[string]bool, len(sources))
+	normalized := make([]MediaSource, 0, len(sources))
+	for _, s := range sources {
+		s.Name = strings.TrimSpace(s.Name)
+		if s.Name == "" {
+			return nil, fmt.Errorf("source name is required")
+		}
+		if seen[s.Name] {
+			return nil, fmt.Errorf("duplicate source %q", s.Name)
+		}
+		seen[s.Name] = true
+		if s.Type == "" {
+			s.Type = "m3u8"
+		}
+
+		episodes := make([]Episode, 0, len(s.Episodes))
+		for _, e := range s.Episodes {
+			if e.URL == "" {
+				return nil, fmt.Errorf("episode %q of source %q has no url", e.Title, s.Name)
+			}
+			if e.Season <= 0 {
+				e.Season = 1
+			}
+			if e.Number <= 0 {
+				e.Number = len(episodes) + 1
+			}
+			if e.Title == "" {
+				e.Title = strconv.Itoa(e.Number)
+			}
+			episodes = append(episodes, e)
+		}
+		s.Episodes = episodes
+		normalized = append(normalized, s)
+	}
+	return normalized, nil
+}
+
+// saveMediaSources adds or replaces the given sources and episodes of a media.
+// Sources the caller does not mention are kept, after the given ones, so
+// saving a single provider never drops the others. The first source is mirrored
+// into medias.video_urls for readers of the old blob. A source that keeps its
+// name keeps its health.
+func saveMediaSources(tx *sql.Tx, mcID string, sources []MediaSource) error {
+	sources, err := normalizeSources(sources)
+	if err != nil {
+		return err
+	}
+	if len(sources) == 0 {
+		return nil
+	}
+
+	given := make(map[string]bool, len(sources))
+	for _, s := range sources {
+		given[s.Name] = true
+	}
+	rows, err := tx.Query("SELECT id, name FROM media_sources WHERE mc_id = ? ORDER BY position, id", mcID)
+	if err != nil {
+		return fmt.Errorf("failed to query sources: %w", err)
+	}
+	var others []int64
+	for rows.Next() {
+		var id int64
+		var name string
+		if err := rows.Scan(&id, &name); err != nil {
+			rows.Close()
+			return err
+		}
+		if !given[name] {
+			others = append(others, id)
+		}
+	}
+	err = rows.Err()
+	rows.Close()
+	if err != nil {
+		return err
+	}
+	for i, id := range others {
+		if _, err := tx.Exec("UPDATE media_sources SET position = ? WHERE id = ?", len(sources)+i, id); err != nil {
+			return fmt.Errorf("failed to reorder sources: %w", err)
+		}
+	}
+
+	for position, s := range sources {
+		var sourceID int64
+		err := tx.QueryRow(`
+			INSERT INTO media_sources (mc_id, name, source_type, position, created_at, updated_at)
+			VALUES (?, ?, ?, ?, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP)
+			ON CONFLICT(mc_id, name) DO UPDATE SET
+				source_type = excluded.source_type,
+				position = excluded.position,
+				updated_at = CURRENT_TIMESTAMP
+			RETURNING id
+		`, mcID, s.Name, s.Type, position).Scan(&sourceID)
+		if err != nil {
+			return fmt.Errorf("failed to save source: %w", err)
+		}
+
+		if _, err := tx.Exec("DELETE FROM episodes WHERE source_id = ?", sourceID); err != nil {
+			return fmt.Errorf("failed to clear episodes: %w", err)
+		}
+		for i, e := range s.Episodes {
+			if _, err := tx.Exec(`
+				INSERT INTO episodes (source_id, season, number, title, url, position)
+				VALUES (?, ?, ?, ?, ?, ?)
+			`, sourceID, e.Season, e.Number, e.Title, e.URL, i); err != nil {
+				return fmt.Errorf("failed to save episode: %w", err)
+			}
+		}
+	}
+
+	if _, err := tx.Exec(
+		"UPDATE medias SET video_urls = ?, video_url_type = ? WHERE mc_id = ?",
+		encodeVideoURLs(sources[0].Episodes), sources[0].Type, mcID,
+	); err != nil {
+		return fmt.Errorf("failed to update video urls: %w", err)
+	}
+	return nil
+}
+
+// deleteMediaSources removes the sources and episodes of a media
+func deleteMediaSources(tx *sql.Tx, mcID string) error {
+	if _, err := tx.Exec(
+		"DELETE FROM episodes WHERE source_id IN (SELECT id FROM media_sources WHERE mc_id = ?)", mcID,
+	); err != nil {
+		return fmt.Errorf("failed to remove episodes: %w", err)
+	}
+	if _, err := tx.Exec("DELETE FROM media_sources WHERE mc_id = ?", mcID); err != nil {
+		return fmt.Errorf("failed to remove sources: %w", err)
+	}
+	return nil
+}
+
+// GetMediaSources returns the sources of a media in order, each with its episodes
+func (ds *DatabaseService) GetMediaSources(mcID string) ([]MediaSource, error) {
+	sources, err := ds.queryMediaSources("s.mc_id = ?", mcID)
+	if err != nil {
+		return nil, err
+	}
+	if sources[mcID] == nil {
+		return []MediaSource{}, nil
+	}
+	return sources[mcID], nil
+}
+
+// queryMediaSources loads the sources matching where, grouped by mc_id
+func (ds *DatabaseService) queryMediaSources(where string, args ...interface{}) (map[string][]MediaSource, error) {
+	rows, err := ds.db.Query(`
+		SELECT s.mc_id, s.id, s.name, s.source_type, s.health, e.season, e.number, e.title, e.url
+		FROM media_sources s
+		LEFT JOIN episodes e ON e.source_id = s.id
+		WHERE `+where+`
+		ORDER BY s.mc_id, s.position, s.id, e.position
+	`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query media sources: %w", err)
+	}
+	defer rows.Close()
+
+	sources := make(map[string][]MediaSource)
+	for rows.Next() {
+		var mcID string
+		var s MediaSource
+		var season, number sql.NullInt64
+		var title, url sql.NullString
+		if err := rows.Scan(&mcID, &s.ID, &s.Name, &s.Type, &s.Health, &season, &number, &title, &url); err != nil {
+			return nil, err
+		}
+
+		list := sources[mcID]
+		if len(list) == 0 || list[len(list)-1].ID != s.ID {
+			s.Episodes = []Episode{}
+			list = append(list, s)
+		}
+		if url.Valid {
+			last := &list[len(list)-1]
+			last.Episodes = append(last.Episodes, Episode{
+				Season: int(season.Int64),
+				Number: int(number.Int64),
+				Title:  title.String,
+				URL:    url.String,
+			})
+		}
+		sources[mcID] = list
+	}
+	return sources, rows.Err()
+}
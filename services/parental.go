@@ -0,0 +1,506 @@
+package services
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// contentRatingRanks orders the content ratings a ceiling can be set at
+var contentRatingRanks = map[string]int{
+	"G":     0,
+	"PG":    1,
+	"PG-13": 2,
+	"R":     3,
+	"NC-17": 4,
+}
+
+// ParentalRules restricts what a profile can browse and play
+type ParentalRules struct {
+	Enabled           bool     `json:"enabled"`
+	BlockedCategories []string `json:"blocked_categories"`
+	BlockedGenres     []string `json:"blocked_genres"`
+	BlockedRegions    []string `json:"blocked_regions"`
+	BlockedKeywords   []string `json:"blocked_keywords"`
+	MaxContentRating  string   `json:"max_content_rating"` // empty for no ceiling
+	BlockUnrated      bool     `json:"block_unrated"`      // block media without a content rating when a ceiling is set
+	ViewingFrom       string   `json:"viewing_from"`       // HH:MM local time, empty for no limit
+	ViewingUntil      string   `json:"viewing_until"`      // HH:MM local time, may wrap past midnight
+}
+
+// normalize trims and validates the rules in place
+func (r *ParentalRules) normalize() error {
+	r.BlockedCategories = normalizeRuleList(r.BlockedCategories)
+	r.BlockedGenres = normalizeRuleList(r.BlockedGenres)
+	r.BlockedRegions = normalizeRuleList(r.BlockedRegions)
+	r.BlockedKeywords = normalizeRuleList(r.BlockedKeywords)
+
+	r.MaxContentRating = strings.ToUpper(strings.TrimSpace(r.MaxContentRating))
+	if r.MaxContentRating != "" {
+		if _, ok := contentRatingRanks[r.MaxContentRating]; !ok {
+			return fmt.Errorf("unknown content rating: %s", r.MaxContentRating)
+		}
+	}
+
+	r.ViewingFrom = strings.TrimSpace(r.ViewingFrom)
+	r.ViewingUntil = strings.TrimSpace(r.ViewingUntil)
+	if (r.ViewingFrom == "") != (r.ViewingUntil == "") {
+		return fmt.Errorf("viewing hours need both a start and an end")
+	}
+	if r.ViewingFrom != "" {
+		if _, err := parseClock(r.ViewingFrom); err != nil {
+			return err
+		}
+		if _, err := parseClock(r.ViewingUntil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// blockReason explains why a media item is hidden, or returns "" if it is allowed.
+// item uses the same keys as bookmark and catalog results.
+func (r *ParentalRules) blockReason(item map[string]interface{}) string {
+	field := func(key string) string {
+		if v, ok := item[key]; ok && v != nil {
+			return strings.TrimSpace(fmt.Sprint(v))
+		}
+		return ""
+	}
+
+	if category := field("category"); category != "" && containsFold(r.BlockedCategories, category) {
+		return "category " + category + " is blocked"
+	}
+	for _, genre := range splitTags(field("genre")) {
+		if containsFold(r.BlockedGenres, genre) {
+			return "genre " + genre + " is blocked"
+		}
+	}
+	for _, region := range splitTags(field("region")) {
+		if containsFold(r.BlockedRegions, region) {
+			return "region " + region + " is blocked"
+		}
+	}
+
+	if r.MaxContentRating != "" {
+		rating := field("content_rating")
+		rank, ok := contentRatingRank(rating)
+		if !ok && r.BlockUnrated {
+			return "unrated content is blocked"
+		}
+		if ok && rank > contentRatingRanks[r.MaxContentRating] {
+			return "rated " + rating + ", above " + r.MaxContentRating
+		}
+	}
+
+	// Catalog results call the description summary
+	text := strings.ToLower(field("title") + "\n" + field("description") + "\n" + field("summary"))
+	for _, keyword := range r.BlockedKeywords {
+		if strings.Contains(text, strings.ToLower(keyword)) {
+			return "contains a blocked keyword"
+		}
+	}
+
+	return ""
+}
+
+// checkViewingHours returns an error if now is outside the allowed viewing window
+func (r *ParentalRules) checkViewingHours(now time.Time) error {
+	if r.ViewingFrom == "" {
+		return nil
+	}
+	from, err := parseClock(r.ViewingFrom)
+	if err != nil {
+		return err
+	}
+	until, err := parseClock(r.ViewingUntil)
+	if err != nil {
+		return err
+	}
+
+	minute := now.Hour()*60 + now.Minute()
+	allowed := from <= minute && minute < until
+	if from > until {
+		// The window wraps past midnight
+		allowed = minute >= from || minute < until
+	}
+	if !allowed {
+		return fmt.Errorf("viewing is only allowed between %s and %s", r.ViewingFrom, r.ViewingUntil)
+	}
+	return nil
+}
+
+// GetProfileParentalRules returns the rules of a profile, disabled rules if none were set
+func (ds *DatabaseService) GetProfileParentalRules(profileID int) (*ParentalRules, error) {
+	rules := &ParentalRules{}
+	var raw string
+	err := ds.db.QueryRow("SELECT rules FROM parental_controls WHERE profile_id = ?", profileID).Scan(&raw)
+	if err == sql.ErrNoRows {
+		return rules, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to query parental controls: %w", err)
+	}
+	if err := json.Unmarshal([]byte(raw), rules); err != nil {
+		return nil, fmt.Errorf("failed to decode parental controls: %w", err)
+	}
+	return rules, nil
+}
+
+// activeParentalRules returns the enabled rules of the user's active profile, or nil.
+// Guests have no profile and are governed by the guest role alone.
+func (ds *DatabaseService) activeParentalRules(userID int) (*ParentalRules, error) {
+	if userID <= 0 {
+		return nil, nil
+	}
+	profileID, err := ds.ActiveProfileID(userID)
+	if err != nil {
+		return nil, err
+	}
+	rules, err := ds.GetProfileParentalRules(profileID)
+	if err != nil {
+		return nil, err
+	}
+	if !rules.Enabled {
+		return nil, nil
+	}
+	return rules, nil
+}
+
+// FilterMediaList drops catalog or search results the user's active profile may not see
+func (ds *DatabaseService) FilterMediaList(userID int, items []map[string]interface{}) ([]map[string]interface{}, error) {
+	rules, err := ds.activeParentalRules(userID)
+	if err != nil {
+		return nil, err
+	}
+	if rules == nil {
+		return items, nil
+	}
+
+	filtered := make([]map[string]interface{}, 0, len(items))
+	for _, item := range items {
+		known, err := ds.withCachedMediaFields(item)
+		if err != nil {
+			return nil, err
+		}
+		if rules.blockReason(known) == "" {
+			filtered = append(filtered, item)
+		}
+	}
+	return filtered, nil
+}
+
+// withCachedMediaFields fills in what a catalog or search result leaves out, such as
+// its genre and content rating, from the cached media row with the same mc_id
+func (ds *DatabaseService) withCachedMediaFields(item map[string]interface{}) (map[string]interface{}, error) {
+	mcID, _ := item["mc_id"].(string)
+	if mcID == "" {
+		return item, nil
+	}
+
+	var description, genre, region, category, contentRating sql.NullString
+	err := ds.db.QueryRow(`
+		SELECT description, genre, region, category, content_rating
+		FROM medias WHERE mc_id = ?
+	`, mcID).Scan(&description, &genre, &region, &category, &contentRating)
+	if err == sql.ErrNoRows {
+		return item, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to query media: %w", err)
+	}
+
+	known := make(map[string]interface{}, len(item)+5)
+	for k, v := range item {
+		known[k] = v
+	}
+	for key, value := range map[string]sql.NullString{
+		"description":    description,
+		"genre":          genre,
+		"region":         region,
+		"category":       category,
+		"content_rating": contentRating,
+	} {
+		if v, ok := known[key]; (!ok || v == nil || v == "") && value.Valid {
+			known[key] = value.String
+		}
+	}
+	return known, nil
+}
+
+// CheckPlayback returns an error if the user's active profile may not play mcID right now
+func (ds *DatabaseService) CheckPlayback(userID int, mcID string) error {
+	rules, err := ds.activeParentalRules(userID)
+	if err != nil {
+		return err
+	}
+	if rules == nil {
+		return nil
+	}
+
+	if err := rules.checkViewingHours(time.Now()); err != nil {
+		return err
+	}
+
+	var title string
+	var description, genre, region, category, contentRating sql.NullString
+	err = ds.db.QueryRow(`
+		SELECT title, description, genre, region, category, content_rating
+		FROM medias WHERE mc_id = ?
+	`, mcID).Scan(&title, &description, &genre, &region, &category, &contentRating)
+	if err != nil && err != sql.ErrNoRows {
+		return fmt.Errorf("failed to query media: %w", err)
+	}
+
+	// Media that was never cached is judged on what little is known about it
+	item := map[string]interface{}{
+		"mc_id":          mcID,
+		"title":          title,
+		"description":    description.String,
+		"genre":          genre.String,
+		"region":         region.String,
+		"category":       category.String,
+		"content_rating": contentRating.String,
+	}
+	if reason := rules.blockReason(item); reason != "" {
+		return fmt.Errorf("blocked by parental controls: %s", reason)
+	}
+	return nil
+}
+
+// SetMediaContentRating records the content rating of a cached media item
+func (ds *DatabaseService) SetMediaContentRating(actorID int, mcID, rating string) error {
+	rating = strings.ToUpper(strings.TrimSpace(rating))
+	if rating != "" {
+		if _, ok := contentRatingRank(rating); !ok {
+			return fmt.Errorf("unknown content rating: %s", rating)
+		}
+	}
+
+	tx, err := ds.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var previous sql.NullString
+	err = tx.QueryRow("SELECT content_rating FROM medias WHERE mc_id = ?", mcID).Scan(&previous)
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("media not found")
+	} else if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(`
+		UPDATE medias SET content_rating = NULLIF(?, ''), updated_at = CURRENT_TIMESTAMP
+		WHERE mc_id = ?
+	`, rating, mcID)
+	if err != nil {
+		return err
+	}
+	if err := recordAudit(tx, actorID, AuditMediaRated, AuditTargetMedia, mcID, previous.String, rating); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// GetParentalRules returns a profile's rules to its account owner or a user manager
+func (as *AuthService) GetParentalRules(userID, profileID int) (*ParentalRules, error) {
+	if _, err := as.authorizeParentalAccess(userID, profileID); err != nil {
+		return nil, err
+	}
+	return as.db.GetProfileParentalRules(profileID)
+}
+
+// SetParentalRules replaces a profile's rules. Users who can manage users may always
+// change them; the account owner must give the account's parental PIN.
+func (as *AuthService) SetParentalRules(actorID, profileID int, rules ParentalRules, pin string) error {
+	isManager, err := as.authorizeParentalAccess(actorID, profileID)
+	if err != nil {
+		return err
+	}
+	if !isManager {
+		if err := as.checkParentalPIN(actorID, pin); err != nil {
+			return err
+		}
+	}
+
+	if err := rules.normalize(); err != nil {
+		return err
+	}
+	encoded, err := json.Marshal(rules)
+	if err != nil {
+		return err
+	}
+
+	tx, err := as.db.GetDB().Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var before sql.NullString
+	err = tx.QueryRow("SELECT rules FROM parental_controls WHERE profile_id = ?", profileID).Scan(&before)
+	if err != nil && err != sql.ErrNoRows {
+		return fmt.Errorf("failed to query parental controls: %w", err)
+	}
+
+	_, err = tx.Exec(`
+		INSERT INTO parental_controls (profile_id, user_id, rules, updated_at)
+		SELECT id, user_id, ?, CURRENT_TIMESTAMP FROM profiles WHERE id = ?
+		ON CONFLICT(profile_id) DO UPDATE SET
+			rules = excluded.rules,
+			updated_at = CURRENT_TIMESTAMP
+	`, string(encoded), profileID)
+	if err != nil {
+		return fmt.Errorf("failed to save parental controls: %w", err)
+	}
+
+	var previous interface{}
+	if before.Valid {
+		previous = json.RawMessage(before.String)
+	}
+	if err := recordAudit(tx, actorID, AuditParentalRules, AuditTargetProfile, strconv.Itoa(profileID), previous, rules); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// SetParentalPIN sets or, with an empty pin, clears the account's parental PIN
+func (as *AuthService) SetParentalPIN(userID int, password, pin string) error {
+	if err := as.checkPassword(userID, password); err != nil {
+		return err
+	}
+
+	var pinHash interface{}
+	if pin != "" {
+		if err := validateProfilePIN(pin); err != nil {
+			return err
+		}
+		hashed, err := as.hashPassword(pin)
+		if err != nil {
+			return fmt.Errorf("failed to hash PIN: %w", err)
+		}
+		pinHash = hashed
+	}
+
+	_, err := as.db.GetDB().Exec("UPDATE users SET parental_pin_hash = ?, updated_at = CURRENT_TIMESTAMP WHERE id = ?", pinHash, userID)
+	if err != nil {
+		return fmt.Errorf("failed to set parental PIN: %w", err)
+	}
+	return nil
+}
+
+// authorizeParentalAccess allows user managers and the owner of the profile's account, reporting which one applied
+func (as *AuthService) authorizeParentalAccess(userID, profileID int) (bool, error) {
+	isManager, err := as.db.HasPermission(userID, PermManageUsers)
+	if err != nil {
+		return false, err
+	}
+
+	var ownerID int
+	err = as.db.GetDB().QueryRow("SELECT user_id FROM profiles WHERE id = ?", profileID).Scan(&ownerID)
+	if err == sql.ErrNoRows {
+		return false, fmt.Errorf("profile not found")
+	} else if err != nil {
+		return false, fmt.Errorf("failed to query profile: %w", err)
+	}
+
+	if !isManager && ownerID != userID {
+		return false, fmt.Errorf("permission denied: profile belongs to another user")
+	}
+	return isManager, nil
+}
+
+// checkParentalPIN verifies the account's parental PIN, throttling wrong guesses in pin_attempts
+func (as *AuthService) checkParentalPIN(userID int, pin string) error {
+	var pinHash sql.NullString
+	if err := as.db.GetDB().QueryRow("SELECT parental_pin_hash FROM users WHERE id = ?", userID).Scan(&pinHash); err != nil {
+		return fmt.Errorf("failed to query user: %w", err)
+	}
+	if !pinHash.Valid {
+		return fmt.Errorf("set a parental PIN before changing parental controls")
+	}
+
+	key := fmt.Sprintf("parental:%d", userID)
+	if err := as.checkPINAllowed(key); err != nil {
+		return err
+	}
+
+	valid, err := as.verifyPassword(pin, pinHash.String)
+	if err != nil {
+		return fmt.Errorf("failed to verify PIN: %w", err)
+	}
+	if !valid {
+		as.recordPINFailure(key)
+		return fmt.Errorf("incorrect PIN")
+	}
+
+	as.clearPINFailures(key)
+	return nil
+}
+
+// contentRatingRank maps MPAA ratings and age labels such as "12+" onto one scale
+func contentRatingRank(rating string) (int, bool) {
+	rating = strings.ToUpper(strings.TrimSpace(rating))
+	if rank, ok := contentRatingRanks[rating]; ok {
+		return rank, true
+	}
+
+	age, err := strconv.Atoi(strings.TrimSuffix(rating, "+"))
+	if err != nil || age < 0 {
+		return 0, false
+	}
+	switch {
+	case age < 6:
+		return contentRatingRanks["G"], true
+	case age < 13:
+		return contentRatingRanks["PG"], true
+	case age < 17:
+		return contentRatingRanks["PG-13"], true
+	case age < 18:
+		return contentRatingRanks["R"], true
+	default:
+		return contentRatingRanks["NC-17"], true
+	}
+}
+
+// parseClock parses HH:MM into minutes after midnight
+func parseClock(value string) (int, error) {
+	t, err := time.Parse("15:04", value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid time %q, expected HH:MM", value)
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+// splitTags splits multi-valued fields such as "剧情/喜剧" or "Drama, Comedy"
+func splitTags(value string) []string {
+	return strings.FieldsFunc(value, func(r rune) bool {
+		return r == '/' || r == ',' || r == '，' || r == '、' || r == '|'
+	})
+}
+
+// containsFold reports whether list contains value, ignoring case and surrounding space
+func containsFold(list []string, value string) bool {
+	value = strings.TrimSpace(value)
+	for _, item := range list {
+		if strings.EqualFold(item, value) {
+			return true
+		}
+	}
+	return false
+}
+
+// normalizeRuleList trims entries and drops blanks and duplicates
+func normalizeRuleList(list []string) []string {
+	out := []string{}
+	for _, item := range list {
+		item = strings.TrimSpace(item)
+		if item != "" && !containsFold(out, item) {
+			out = append(out, item)
+		}
+	}
+	return out
+}
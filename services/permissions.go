@@ -0,0 +1,419 @@
+package services
+
+import (
+	"database/sql"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// Built-in roles. Admin implicitly holds every permission; guest applies to
+// callers that are not signed in.
+const (
+	RoleAdmin  = "admin"
+	RoleMember = "member"
+	RoleGuest  = "guest"
+)
+
+// Permissions checked by Authorize
+const (
+	PermBrowse             = "browse"
+	PermDownload           = "download"
+	PermPostComments       = "post_comments"
+	PermModerateComments   = "moderate_comments"
+	PermManageUsers        = "manage_users"
+	PermEditGlobalSettings = "edit_global_settings"
+	PermViewAdminDB        = "view_admin_db"
+	PermManageBackups      = "manage_backups"
+	PermManageMedia        = "manage_media"
+)
+
+// PermissionInfo describes a permission for the role editor
+type PermissionInfo struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+// AvailablePermissions lists every permission a role can be granted
+var AvailablePermissions = []PermissionInfo{
+	{PermBrowse, "Browse the catalog and play media"},
+	{PermDownload, "Download media for offline viewing"},
+	{PermPostComments, "Post comments"},
+	{PermModerateComments, "Edit and delete anyone's comments"},
+	{PermManageUsers, "Manage users, roles, invite codes and parental controls"},
+	{PermEditGlobalSettings, "Change settings that apply to everyone"},
+	{PermViewAdminDB, "Inspect the database from the admin pages"},
+	{PermManageBackups, "Create and restore database backups"},
+	{PermManageMedia, "Delete cached media and set content ratings"},
+}
+
+// roleNamePattern keeps role names simple enough to show and type
+var roleNamePattern = regexp.MustCompile(`^[a-z][a-z0-9_-]{1,31}$`)
+
+// Role is a named set of permissions
+type Role struct {
+	ID          int      `json:"id"`
+	Name        string   `json:"name"`
+	Description string   `json:"description"`
+	BuiltIn     bool     `json:"built_in"`
+	Permissions []string `json:"permissions"`
+	UserCount   int      `json:"user_count"`
+}
+
+// Authorize returns an error unless userID holds permission.
+// A userID of 0 checks the guest role.
+func (ds *DatabaseService) Authorize(userID int, permission string) error {
+	ok, err := ds.HasPermission(userID, permission)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return fmt.Errorf("permission denied: %s required", permission)
+	}
+	return nil
+}
+
+// HasPermission reports whether userID holds permission
+func (ds *DatabaseService) HasPermission(userID int, permission string) (bool, error) {
+	role, err := ds.effectiveRole(userID)
+	if err != nil {
+		return false, err
+	}
+	if role == RoleAdmin {
+		return true, nil
+	}
+
+	var granted bool
+	err = ds.db.QueryRow(`
+		SELECT EXISTS(
+			SELECT 1 FROM role_permissions rp
+			JOIN roles r ON r.id = rp.role_id
+			WHERE r.name = ? AND rp.permission = ?
+		)
+	`, role, permission).Scan(&granted)
+	if err != nil {
+		return false, fmt.Errorf("failed to check permission: %w", err)
+	}
+	return granted, nil
+}
+
+// GetUserPermissions returns every permission userID holds, for gating the UI
+func (ds *DatabaseService) GetUserPermissions(userID int) ([]string, error) {
+	role, err := ds.effectiveRole(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	permissions := []string{}
+	if role == RoleAdmin {
+		for _, p := range AvailablePermissions {
+			permissions = append(permissions, p.Name)
+		}
+		return permissions, nil
+	}
+
+	rows, err := ds.db.Query(`
+		SELECT rp.permission FROM role_permissions rp
+		JOIN roles r ON r.id = rp.role_id
+		WHERE r.name = ?
+		ORDER BY rp.permission
+	`, role)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query permissions: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var p string
+		if err := rows.Scan(&p); err != nil {
+			return nil, err
+		}
+		permissions = append(permissions, p)
+	}
+	return permissions, rows.Err()
+}
+
+// effectiveRole returns the role permissions are checked against
+func (ds *DatabaseService) effectiveRole(userID int) (string, error) {
+	if userID <= 0 {
+		return RoleGuest, nil
+	}
+
+	var role string
+	var disabled bool
+	err := ds.db.QueryRow("SELECT user_role, disabled FROM users WHERE id = ?", userID).Scan(&role, &disabled)
+	if err == sql.ErrNoRows {
+		return "", fmt.Errorf("user not found")
+	} else if err != nil {
+		return "", err
+	}
+	if disabled {
+		return "", fmt.Errorf("account is disabled")
+	}
+	return role, nil
+}
+
+// ListRoles returns every role with its permissions and how many users hold it
+func (ds *DatabaseService) ListRoles() ([]Role, error) {
+	rows, err := ds.db.Query(`
+		SELECT r.id, r.name, COALESCE(r.description, ''), r.built_in,
+			(SELECT COUNT(*) FROM users u WHERE u.user_role = r.name)
+		FROM roles r
+		ORDER BY r.built_in DESC, r.name
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query roles: %w", err)
+	}
+
+	roles := []Role{}
+	for rows.Next() {
+		var role Role
+		if err := rows.Scan(&role.ID, &role.Name, &role.Description, &role.BuiltIn, &role.UserCount); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		roles = append(roles, role)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for i := range roles {
+		permissions, err := ds.rolePermissions(roles[i].ID)
+		if err != nil {
+			return nil, err
+		}
+		roles[i].Permissions = permissions
+	}
+	return roles, nil
+}
+
+// SaveRole creates a role or replaces its description and permissions.
+// The admin role always holds every permission and cannot be edited, and
+// actors can only grant permissions they hold themselves.
+func (ds *DatabaseService) SaveRole(actorID int, name, description string, permissions []string) error {
+	name = strings.TrimSpace(name)
+	if name == RoleAdmin {
+		return fmt.Errorf("the admin role cannot be edited")
+	}
+	if !roleNamePattern.MatchString(name) {
+		return fmt.Errorf("role names must be 2-32 lower case letters, digits, '-' or '_'")
+	}
+	for _, p := range permissions {
+		if !isKnownPermission(p) {
+			return fmt.Errorf("unknown permission: %s", p)
+		}
+		if name == RoleGuest && p == PermManageUsers {
+			return fmt.Errorf("the guest role cannot manage users")
+		}
+	}
+
+	held, err := ds.GetUserPermissions(actorID)
+	if err != nil {
+		return err
+	}
+
+	tx, err := ds.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	before, err := roleSnapshot(tx, name)
+	if err != nil {
+		return err
+	}
+
+	// Permissions the role already has may stay, but new ones must be the actor's own
+	var existing []string
+	if before != nil {
+		existing = before["permissions"].([]string)
+	}
+	for _, p := range permissions {
+		if !slices.Contains(existing, p) && !slices.Contains(held, p) {
+			return fmt.Errorf("permission denied: cannot grant %s without holding it", p)
+		}
+	}
+
+	_, err = tx.Exec(`
+		INSERT INTO roles (name, description, created_at, updated_at)
+		VALUES (?, ?, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP)
+		ON CONFLICT(name) DO UPDATE SET
+			description = excluded.description,
+			updated_at = CURRENT_TIMESTAMP
+	`, name, strings.TrimSpace(description))
+	if err != nil {
+		return fmt.Errorf("failed to save role: %w", err)
+	}
+
+	var roleID int
+	if err := tx.QueryRow("SELECT id FROM roles WHERE name = ?", name).Scan(&roleID); err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec("DELETE FROM role_permissions WHERE role_id = ?", roleID); err != nil {
+		return fmt.Errorf("failed to update permissions: %w", err)
+	}
+	for _, p := range permissions {
+		_, err := tx.Exec(`
+			INSERT INTO role_permissions (role_id, permission) VALUES (?, ?)
+			ON CONFLICT(role_id, permission) DO NOTHING
+		`, roleID, p)
+		if err != nil {
+			return fmt.Errorf("failed to update permissions: %w", err)
+		}
+	}
+
+	after, err := roleSnapshot(tx, name)
+	if err != nil {
+		return err
+	}
+	if err := recordAudit(tx, actorID, AuditRoleSaved, AuditTargetRole, name, before, after); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// DeleteRole removes a custom role that no user or invite code refers to
+func (ds *DatabaseService) DeleteRole(actorID int, name string) error {
+	tx, err := ds.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var roleID int
+	var builtIn bool
+	err = tx.QueryRow("SELECT id, built_in FROM roles WHERE name = ?", name).Scan(&roleID, &builtIn)
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("role not found")
+	} else if err != nil {
+		return err
+	}
+	if builtIn {
+		return fmt.Errorf("built-in roles cannot be deleted")
+	}
+
+	var inUse bool
+	err = tx.QueryRow(`
+		SELECT EXISTS(SELECT 1 FROM users WHERE user_role = ?)
+			OR EXISTS(SELECT 1 FROM invite_codes WHERE user_role = ?)
+	`, name, name).Scan(&inUse)
+	if err != nil {
+		return err
+	}
+	if inUse {
+		return fmt.Errorf("role is still assigned to users or invite codes")
+	}
+
+	before, err := roleSnapshot(tx, name)
+	if err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec("DELETE FROM role_permissions WHERE role_id = ?", roleID); err != nil {
+		return err
+	}
+	if _, err := tx.Exec("DELETE FROM roles WHERE id = ?", roleID); err != nil {
+		return err
+	}
+	if err := recordAudit(tx, actorID, AuditRoleDeleted, AuditTargetRole, name, before, nil); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (ds *DatabaseService) rolePermissions(roleID int) ([]string, error) {
+	rows, err := ds.db.Query("SELECT permission FROM role_permissions WHERE role_id = ? ORDER BY permission", roleID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	permissions := []string{}
+	for rows.Next() {
+		var p string
+		if err := rows.Scan(&p); err != nil {
+			return nil, err
+		}
+		permissions = append(permissions, p)
+	}
+	return permissions, rows.Err()
+}
+
+// roleSnapshot returns a role's description and permissions for the audit log, or nil if it does not exist
+func roleSnapshot(tx *sql.Tx, name string) (map[string]interface{}, error) {
+	var description string
+	err := tx.QueryRow("SELECT COALESCE(description, '') FROM roles WHERE name = ?", name).Scan(&description)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	rows, err := tx.Query(`
+		SELECT rp.permission FROM role_permissions rp
+		JOIN roles r ON r.id = rp.role_id
+		WHERE r.name = ?
+		ORDER BY rp.permission
+	`, name)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	permissions := []string{}
+	for rows.Next() {
+		var p string
+		if err := rows.Scan(&p); err != nil {
+			return nil, err
+		}
+		permissions = append(permissions, p)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{"description": description, "permissions": permissions}, nil
+}
+
+// checkAssignableRole fails unless role exists and can be held by an account
+func checkAssignableRole(q queryRower, role string) error {
+	if role == RoleGuest {
+		return fmt.Errorf("the guest role cannot be assigned to accounts")
+	}
+	var exists bool
+	if err := q.QueryRow("SELECT EXISTS(SELECT 1 FROM roles WHERE name = ?)", role).Scan(&exists); err != nil {
+		return fmt.Errorf("failed to check role: %w", err)
+	}
+	if !exists {
+		return fmt.Errorf("invalid role: %s", role)
+	}
+	return nil
+}
+
+// checkActorIsAdmin fails unless actorID is an enabled admin. Holding manage_users
+// is not enough to hand out or take away the admin role, or to act on an admin's account.
+func checkActorIsAdmin(q queryRower, actorID int) error {
+	var role string
+	var disabled bool
+	err := q.QueryRow("SELECT user_role, disabled FROM users WHERE id = ?", actorID).Scan(&role, &disabled)
+	if err != nil && err != sql.ErrNoRows {
+		return fmt.Errorf("failed to query user: %w", err)
+	}
+	if err == sql.ErrNoRows || role != RoleAdmin || disabled {
+		return fmt.Errorf("permission denied: only admins can manage admin accounts")
+	}
+	return nil
+}
+
+func isKnownPermission(permission string) bool {
+	for _, p := range AvailablePermissions {
+		if p.Name == permission {
+			return true
+		}
+	}
+	return false
+}
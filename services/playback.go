@@ -0,0 +1,460 @@
+package services
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// playbackSessionTTL is how long an idle playback session is kept
+	playbackSessionTTL = 12 * time.Hour
+
+	// sourceErrorsBeforeSwitch non-fatal errors in a row move playback to the next source
+	sourceErrorsBeforeSwitch = 3
+
+	// hostFailuresUnhealthy recent failures mark a host unhealthy for hostFailureWindow
+	hostFailuresUnhealthy = 3
+	hostFailureWindow     = 10 * time.Minute
+)
+
+// episodeNumberPattern finds the episode number in labels such as "第12集", "EP12" or "12"
+var episodeNumberPattern = regexp.MustCompile(`\d+`)
+
+// PlaybackService tracks what each player is watching so it can fail over to
+// another source when the current one dies
+type PlaybackService struct {
+	db    *DatabaseService
+	proxy *ProxyService
+
+	mu       sync.Mutex
+	sessions map[string]*playbackSession
+	hosts    map[string]*HostHealth
+}
+
+// PlaybackSource is one provider's list of episode playlists for a title
+type PlaybackSource struct {
+	Name string            `json:"name"`
+	URLs map[string]string `json:"urls"` // episode label -> playlist URL
+}
+
+// PlaybackState tells the player which source and URL to play
+type PlaybackState struct {
+	SessionID string   `json:"session_id"`
+	MCID      string   `json:"mc_id"`
+	Episode   string   `json:"episode"`
+	Source    string   `json:"source"`
+	URL       string   `json:"url"`
+	Sources   []string `json:"sources"`
+
+	// Markers of the series for the user's active profile; nil without a user
+	Markers *SkipMarkers `json:"markers,omitempty"`
+}
+
+// PlaybackErrorReport is a player error forwarded from hls.js
+type PlaybackErrorReport struct {
+	URL          string  `json:"url"`           // segment or playlist that failed
+	Details      string  `json:"details"`       // hls.js error details, e.g. fragLoadError
+	Fatal        bool    `json:"fatal"`         // hls.js gave up on its own recovery
+	Position     float64 `json:"position"`      // currentTime of the video element, in seconds
+	SegmentIndex int     `json:"segment_index"` // index of the failing segment, -1 if unknown
+}
+
+// PlaybackSwitch is the answer to an error report. When Switch is set the
+// player loads URL and seeks to ResumeAt; Exhausted means no source is left.
+type PlaybackSwitch struct {
+	Switch       bool    `json:"switch"`
+	Exhausted    bool    `json:"exhausted"`
+	Source       string  `json:"source,omitempty"`
+	URL          string  `json:"url,omitempty"`
+	ResumeAt     float64 `json:"resume_at"`
+	SegmentIndex int     `json:"segment_index"`
+	Reason       string  `json:"reason,omitempty"`
+}
+
+// HostHealth is the recent failure record of a CDN host
+type HostHealth struct {
+	Host        string    `json:"host"`
+	Failures    int       `json:"failures"`
+	LastFailure time.Time `json:"last_failure"`
+	LastError   string    `json:"last_error"`
+}
+
+type playbackSession struct {
+	userID     int
+	mcID       string
+	episode    string
+	sources    []PlaybackSource
+	current    int
+	failed     map[int]bool
+	errorCount int
+	lastUsed   time.Time
+}
+
+// NewPlaybackService creates a new PlaybackService instance
+func NewPlaybackService(db *DatabaseService, proxy *ProxyService) *PlaybackService {
+	return &PlaybackService{
+		db:       db,
+		proxy:    proxy,
+		sessions: make(map[string]*playbackSession),
+		hosts:    make(map[string]*HostHealth),
+	}
+}
+
+// StartPlayback opens a session for an episode of mcID. sources lists every
+// provider for the title; when empty, the sources stored with the title are
+// used. Sources are ranked by the reputation of their hosts and the first one
+// with the episode on a healthy host is picked.
+func (ps *PlaybackService) StartPlayback(userID int, mcID, episode string, sources []PlaybackSource) (*PlaybackState, error) {
+	if len(sources) == 0 {
+		cached, err := ps.cachedSources(mcID)
+		if err != nil {
+			return nil, err
+		}
+		sources = cached
+	}
+	sources = ps.db.rankSources(sources, episode)
+
+	session := &playbackSession{
+		userID:   userID,
+		mcID:     mcID,
+		episode:  episode,
+		sources:  sources,
+		current:  -1,
+		failed:   make(map[int]bool),
+		lastUsed: time.Now(),
+	}
+
+	var markers *SkipMarkers
+	if userID > 0 {
+		m, err := ps.db.GetSkipMarkers(userID, mcID)
+		if err != nil {
+			log.Printf("Failed to load skip markers: %v", err)
+		}
+		markers = m
+	}
+
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+	ps.pruneSessionsLocked()
+
+	next, playlistURL := ps.nextSourceLocked(session)
+	if next < 0 {
+		return nil, fmt.Errorf("no source has episode %s", episode)
+	}
+	session.current = next
+
+	id, err := newPlaybackSessionID()
+	if err != nil {
+		return nil, err
+	}
+	ps.sessions[id] = session
+
+	names := make([]string, len(sources))
+	for i, s := range sources {
+		names[i] = s.Name
+	}
+	return &PlaybackState{
+		SessionID: id,
+		MCID:      mcID,
+		Episode:   episode,
+		Source:    sources[next].Name,
+		URL:       playlistURL,
+		Sources:   names,
+		Markers:   markers,
+	}, nil
+}
+
+// ReportPlaybackError records a player error against the failing host and,
+// once the current source looks dead, picks the next healthy source that has
+// the same episode and works out where to resume in it.
+func (ps *PlaybackService) ReportPlaybackError(sessionID string, report PlaybackErrorReport) (*PlaybackSwitch, error) {
+	ps.mu.Lock()
+	session, ok := ps.sessions[sessionID]
+	if !ok {
+		ps.mu.Unlock()
+		return nil, fmt.Errorf("playback session not found")
+	}
+	session.lastUsed = time.Now()
+
+	failingURL := unproxiedURL(report.URL)
+	if failingURL == "" {
+		failingURL, _ = matchEpisode(session.sources[session.current].URLs, session.episode)
+	}
+	ps.recordHostFailureLocked(failingURL, report.Details)
+
+	session.errorCount++
+	if !report.Fatal && session.errorCount < sourceErrorsBeforeSwitch {
+		ps.mu.Unlock()
+		ps.persistFailure(session.mcID, failingURL, report.Details)
+		return &PlaybackSwitch{SegmentIndex: report.SegmentIndex, ResumeAt: report.Position}, nil
+	}
+
+	failedSource := session.sources[session.current].Name
+	session.failed[session.current] = true
+	session.errorCount = 0
+	candidates := ps.candidateOrderLocked(session)
+	ps.mu.Unlock()
+	ps.persistFailure(session.mcID, failingURL, report.Details)
+
+	// Candidates are probed outside the lock since each one is a network round trip
+	for _, i := range candidates {
+		playlistURL, _ := matchEpisode(session.sources[i].URLs, session.episode)
+		index, start, err := ps.locateSegment(playlistURL, report.Position)
+
+		ps.mu.Lock()
+		if err != nil {
+			log.Printf("Failover candidate %s failed: %v", session.sources[i].Name, err)
+			session.failed[i] = true
+			ps.recordHostFailureLocked(playlistURL, err.Error())
+			ps.mu.Unlock()
+			ps.persistFailure(session.mcID, playlistURL, err.Error())
+			continue
+		}
+		session.current = i
+		ps.mu.Unlock()
+
+		return &PlaybackSwitch{
+			Switch:       true,
+			Source:       session.sources[i].Name,
+			URL:          playlistURL,
+			ResumeAt:     start,
+			SegmentIndex: index,
+			Reason:       fmt.Sprintf("%s failed: %s", failedSource, report.Details),
+		}, nil
+	}
+
+	return &PlaybackSwitch{
+		Exhausted:    true,
+		ResumeAt:     report.Position,
+		SegmentIndex: report.SegmentIndex,
+		Reason:       "every source for this episode has failed",
+	}, nil
+}
+
+// EndPlayback closes a session when the player goes away
+func (ps *PlaybackService) EndPlayback(sessionID string) {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+	delete(ps.sessions, sessionID)
+}
+
+// GetHostHealth returns the failure record of every host that has failed recently
+func (ps *PlaybackService) GetHostHealth() []HostHealth {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+
+	hosts := []HostHealth{}
+	for _, h := range ps.hosts {
+		if time.Since(h.LastFailure) < hostFailureWindow {
+			hosts = append(hosts, *h)
+		}
+	}
+	return hosts
+}
+
+// cachedSources builds sources from the episodes stored with the title
+func (ps *PlaybackService) cachedSources(mcID string) ([]PlaybackSource, error) {
+	stored, err := ps.db.GetMediaSources(mcID)
+	if err != nil {
+		return nil, err
+	}
+	if len(stored) == 0 {
+		return nil, fmt.Errorf("media has no stored sources")
+	}
+
+	sources := make([]PlaybackSource, 0, len(stored))
+	for _, s := range stored {
+		source := PlaybackSource{Name: s.Name, URLs: make(map[string]string, len(s.Episodes))}
+		for _, e := range s.Episodes {
+			source.URLs[e.Title] = e.URL
+		}
+		sources = append(sources, source)
+	}
+	return sources, nil
+}
+
+// nextSourceLocked returns the first candidate source and its playlist URL, or -1
+func (ps *PlaybackService) nextSourceLocked(session *playbackSession) (int, string) {
+	for _, i := range ps.candidateOrderLocked(session) {
+		if playlistURL, ok := matchEpisode(session.sources[i].URLs, session.episode); ok {
+			return i, playlistURL
+		}
+	}
+	return -1, ""
+}
+
+// candidateOrderLocked lists the sources still worth trying after the current
+// one: those on healthy hosts first, then those on hosts that failed recently
+func (ps *PlaybackService) candidateOrderLocked(session *playbackSession) []int {
+	var healthy, unhealthy []int
+	n := len(session.sources)
+	for step := 1; step <= n; step++ {
+		i := (session.current + step + n) % n
+		if session.current < 0 {
+			i = step - 1
+		}
+		if session.failed[i] {
+			continue
+		}
+		playlistURL, ok := matchEpisode(session.sources[i].URLs, session.episode)
+		if !ok {
+			continue
+		}
+		if ps.hostHealthyLocked(playlistURL) {
+			healthy = append(healthy, i)
+		} else {
+			unhealthy = append(unhealthy, i)
+		}
+	}
+	return append(healthy, unhealthy...)
+}
+
+func (ps *PlaybackService) recordHostFailureLocked(rawURL, detail string) {
+	host := urlHost(rawURL)
+	if host == "" {
+		return
+	}
+	h, ok := ps.hosts[host]
+	if !ok || time.Since(h.LastFailure) >= hostFailureWindow {
+		h = &HostHealth{Host: host}
+		ps.hosts[host] = h
+	}
+	h.Failures++
+	h.LastFailure = time.Now()
+	h.LastError = detail
+}
+
+// persistFailure adds a failure to the host's stored history, which outlives the session
+func (ps *PlaybackService) persistFailure(mcID, rawURL, detail string) {
+	if rawURL == "" {
+		return
+	}
+	if err := ps.db.RecordPlaybackError(mcID, rawURL, detail); err != nil {
+		log.Printf("Failed to record playback error: %v", err)
+	}
+}
+
+func (ps *PlaybackService) hostHealthyLocked(rawURL string) bool {
+	h, ok := ps.hosts[urlHost(rawURL)]
+	if !ok {
+		return true
+	}
+	return h.Failures < hostFailuresUnhealthy || time.Since(h.LastFailure) >= hostFailureWindow
+}
+
+func (ps *PlaybackService) pruneSessionsLocked() {
+	for id, s := range ps.sessions {
+		if time.Since(s.lastUsed) > playbackSessionTTL {
+			delete(ps.sessions, id)
+		}
+	}
+}
+
+// locateSegment fetches a playlist and returns the segment playing at position
+// and that segment's start time. Master playlists are followed to their first variant.
+func (ps *PlaybackService) locateSegment(playlistURL string, position float64) (int, float64, error) {
+	resp, err := ps.proxy.ProxyURL(playlistURL)
+	if err != nil {
+		return 0, 0, err
+	}
+	playlist := string(resp.Data)
+	if strings.Contains(playlist, "#EXT-X-STREAM-INF") {
+		variantURL := pickFirstVariantURL(playlist, playlistURL)
+		if variantURL == "" {
+			return 0, 0, fmt.Errorf("variant playlist not found")
+		}
+		if resp, err = ps.proxy.ProxyURL(variantURL); err != nil {
+			return 0, 0, err
+		}
+		playlist = string(resp.Data)
+	}
+
+	_, runs, _ := parseHLSRuns(playlist)
+	index, start, last := 0, 0.0, 0.0
+	for _, run := range runs {
+		for _, seg := range run.segments {
+			if start+seg.duration > position {
+				return index, start, nil
+			}
+			start += seg.duration
+			last = seg.duration
+			index++
+		}
+	}
+	if index == 0 {
+		return 0, 0, fmt.Errorf("playlist has no segments")
+	}
+	// Past the end of this source's copy; resume from its last segment
+	return index - 1, start - last, nil
+}
+
+// matchEpisode finds an episode's playlist URL in a source: by exact label,
+// then by episode number, and for single-entry sources such as movies, the only
+// entry unless both it and the requested episode are numbered (and so differ)
+func matchEpisode(urls map[string]string, episode string) (string, bool) {
+	if u, ok := urls[episode]; ok && u != "" {
+		return normalizePlaylistURL(u), true
+	}
+	number := episodeNumber(episode)
+	if number >= 0 {
+		for label, u := range urls {
+			if episodeNumber(label) == number && u != "" {
+				return normalizePlaylistURL(u), true
+			}
+		}
+	}
+	if len(urls) == 1 {
+		for label, u := range urls {
+			if u != "" && (number < 0 || episodeNumber(label) < 0) {
+				return normalizePlaylistURL(u), true
+			}
+		}
+	}
+	return "", false
+}
+
+// episodeNumber returns the first number in an episode label, or -1
+func episodeNumber(label string) int {
+	m := episodeNumberPattern.FindString(label)
+	if m == "" {
+		return -1
+	}
+	n, err := strconv.Atoi(m)
+	if err != nil {
+		return -1
+	}
+	return n
+}
+
+// normalizePlaylistURL appends /index.m3u8 to source URLs that point at a directory
+func normalizePlaylistURL(u string) string {
+	u = strings.TrimSpace(u)
+	if !strings.HasSuffix(u, ".m3u8") {
+		u += "/index.m3u8"
+	}
+	return u
+}
+
+func urlHost(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return ""
+	}
+	return strings.ToLower(u.Hostname())
+}
+
+func newPlaybackSessionID() (string, error) {
+	raw := make([]byte, 16)
+	if _, err := rand.Read(raw); err != nil {
+		return "", fmt.Errorf("failed to generate session id: %w", err)
+	}
+	return hex.EncodeToString(raw), nil
+}
@@ -0,0 +1,274 @@
+package services
+
+import (
+	"log"
+	"math"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	defaultPrefetchSegments = 3
+
+	// maxActivePlaylists playlists are read ahead at once; older ones are forgotten
+	maxActivePlaylists = 4
+
+	// Prefetch concurrency stays within these bounds
+	minPrefetchWorkers = 1
+	maxPrefetchWorkers = 4
+
+	// prefetchRealtimeTarget is how many seconds of video the read-ahead aims
+	// to download per second of wall time, leaving headroom over playback speed
+	prefetchRealtimeTarget = 2.0
+
+	// throughputSmoothing is the weight of a new sample in the moving averages
+	throughputSmoothing = 0.3
+)
+
+// segmentPrefetcher remembers the media playlists the player is reading so
+// that a request for one segment can start fetching the ones after it
+type segmentPrefetcher struct {
+	mu        sync.Mutex
+	ahead     int
+	playlists map[string]*prefetchPlaylist   // by playlist URL
+	owners    map[string]prefetchSegmentRef  // by segment URL
+	hosts     map[string]*prefetchThroughput // by segment host
+	latest    *prefetchPlaylist
+
+	prefetched, errors uint64
+}
+
+type prefetchPlaylist struct {
+	url       string
+	segments  []string
+	durations []float64
+	lastUsed  time.Time
+}
+
+type prefetchSegmentRef struct {
+	playlist *prefetchPlaylist
+	index    int
+}
+
+// prefetchThroughput tracks how fast a host delivers segments over one connection
+type prefetchThroughput struct {
+	bytesPerSecond float64
+	realtime       float64 // seconds of video fetched per second of wall time
+}
+
+// prefetchJob is a segment to fetch ahead of the player
+type prefetchJob struct {
+	url      string
+	duration float64
+}
+
+func newSegmentPrefetcher() *segmentPrefetcher {
+	return &segmentPrefetcher{
+		ahead:     defaultPrefetchSegments,
+		playlists: make(map[string]*prefetchPlaylist),
+		owners:    make(map[string]prefetchSegmentRef),
+		hosts:     make(map[string]*prefetchThroughput),
+	}
+}
+
+// setAhead changes how many segments are fetched ahead; 0 turns read-ahead off
+func (pf *segmentPrefetcher) setAhead(n int) {
+	pf.mu.Lock()
+	defer pf.mu.Unlock()
+	pf.ahead = n
+}
+
+// track records the segments of a media playlist served to the player.
+// Live playlists are tracked again on every refresh.
+func (pf *segmentPrefetcher) track(playlistURL, playlist string) {
+	if strings.Contains(playlist, "#EXT-X-STREAM-INF") {
+		return
+	}
+	_, runs, _ := parseHLSRuns(playlist)
+
+	tracked := &prefetchPlaylist{url: playlistURL, lastUsed: time.Now()}
+	for _, run := range runs {
+		for _, seg := range run.segments {
+			tracked.segments = append(tracked.segments, resolveURL(playlistURL, seg.uri))
+			tracked.durations = append(tracked.durations, seg.duration)
+		}
+	}
+	if len(tracked.segments) == 0 {
+		return
+	}
+
+	pf.mu.Lock()
+	defer pf.mu.Unlock()
+
+	if old, ok := pf.playlists[playlistURL]; ok {
+		pf.forgetLocked(old)
+	}
+	pf.playlists[playlistURL] = tracked
+	for i, u := range tracked.segments {
+		pf.owners[u] = prefetchSegmentRef{playlist: tracked, index: i}
+	}
+	pf.latest = tracked
+
+	for len(pf.playlists) > maxActivePlaylists {
+		var oldest *prefetchPlaylist
+		for _, pl := range pf.playlists {
+			if oldest == nil || pl.lastUsed.Before(oldest.lastUsed) {
+				oldest = pl
+			}
+		}
+		pf.forgetLocked(oldest)
+	}
+}
+
+// tracks reports whether segmentURL is a segment of a playlist being read ahead
+func (pf *segmentPrefetcher) tracks(segmentURL string) bool {
+	pf.mu.Lock()
+	defer pf.mu.Unlock()
+	_, ok := pf.owners[segmentURL]
+	return ok
+}
+
+// next returns the segments after segmentURL to fetch and how many to fetch at once
+func (pf *segmentPrefetcher) next(segmentURL string) ([]prefetchJob, int) {
+	pf.mu.Lock()
+	defer pf.mu.Unlock()
+
+	ref, ok := pf.owners[segmentURL]
+	if !ok || pf.ahead <= 0 {
+		return nil, 0
+	}
+	ref.playlist.lastUsed = time.Now()
+	pf.latest = ref.playlist
+
+	var jobs []prefetchJob
+	for i := ref.index + 1; i < len(ref.playlist.segments) && len(jobs) < pf.ahead; i++ {
+		jobs = append(jobs, prefetchJob{url: ref.playlist.segments[i], duration: ref.playlist.durations[i]})
+	}
+	return jobs, pf.concurrencyLocked(urlHost(segmentURL))
+}
+
+// record folds a finished prefetch into the host's throughput estimate
+func (pf *segmentPrefetcher) record(job prefetchJob, bytes int, elapsed time.Duration, err error) {
+	pf.mu.Lock()
+	defer pf.mu.Unlock()
+
+	if err != nil {
+		pf.errors++
+		return
+	}
+	pf.prefetched++
+
+	seconds := elapsed.Seconds()
+	if seconds <= 0 || bytes == 0 {
+		return
+	}
+	host := urlHost(job.url)
+	h, ok := pf.hosts[host]
+	if !ok {
+		pf.hosts[host] = &prefetchThroughput{
+			bytesPerSecond: float64(bytes) / seconds,
+			realtime:       job.duration / seconds,
+		}
+		return
+	}
+	h.bytesPerSecond += throughputSmoothing * (float64(bytes)/seconds - h.bytesPerSecond)
+	h.realtime += throughputSmoothing * (job.duration/seconds - h.realtime)
+}
+
+// concurrencyLocked picks enough parallel fetches for the host to deliver
+// prefetchRealtimeTarget seconds of video per second. Hosts that have not
+// been measured yet get two.
+func (pf *segmentPrefetcher) concurrencyLocked(host string) int {
+	h, ok := pf.hosts[host]
+	if !ok || h.realtime <= 0 {
+		return 2
+	}
+	workers := int(math.Ceil(prefetchRealtimeTarget / h.realtime))
+	if workers < minPrefetchWorkers {
+		return minPrefetchWorkers
+	}
+	if workers > maxPrefetchWorkers {
+		return maxPrefetchWorkers
+	}
+	return workers
+}
+
+// stats fills in the prefetch part of SegmentCacheStats
+func (pf *segmentPrefetcher) stats(s *SegmentCacheStats) {
+	pf.mu.Lock()
+	defer pf.mu.Unlock()
+
+	s.Prefetched = pf.prefetched
+	s.PrefetchErrors = pf.errors
+	s.ActivePlaylists = len(pf.playlists)
+	if pf.latest != nil {
+		host := urlHost(pf.latest.segments[0])
+		s.Concurrency = pf.concurrencyLocked(host)
+		if h, ok := pf.hosts[host]; ok {
+			s.Throughput = h.bytesPerSecond
+		}
+	}
+}
+
+func (pf *segmentPrefetcher) forgetLocked(pl *prefetchPlaylist) {
+	delete(pf.playlists, pl.url)
+	for _, u := range pl.segments {
+		if ref, ok := pf.owners[u]; ok && ref.playlist == pl {
+			delete(pf.owners, u)
+		}
+	}
+	if pf.latest == pl {
+		pf.latest = nil
+	}
+}
+
+// readAhead starts fetching the segments after segmentURL in the background
+func (p *ProxyService) readAhead(segmentURL string) {
+	jobs, workers := p.prefetch.next(segmentURL)
+	if len(jobs) == 0 {
+		return
+	}
+
+	go func() {
+		sem := make(chan struct{}, workers)
+		var wg sync.WaitGroup
+		for _, job := range jobs {
+			if p.cache.contains(job.url) {
+				continue
+			}
+			sem <- struct{}{}
+			wg.Add(1)
+			go func(job prefetchJob) {
+				defer func() {
+					<-sem
+					wg.Done()
+				}()
+				start := time.Now()
+				resp, err := p.cache.load(job.url, false, p.fetchURL)
+				if err != nil {
+					log.Printf("Prefetch of %s failed: %v", redactQuery(job.url), err)
+					p.prefetch.record(job, 0, 0, err)
+					return
+				}
+				if resp == nil {
+					// Already cached by the time this worker ran
+					return
+				}
+				p.prefetch.record(job, len(resp.Data), time.Since(start), nil)
+			}(job)
+		}
+		wg.Wait()
+	}()
+}
+
+// redactQuery drops the query string, which often carries signed tokens, from logged URLs
+func redactQuery(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return rawURL
+	}
+	u.RawQuery = ""
+	return u.String()
+}
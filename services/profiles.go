@@ -0,0 +1,309 @@
+package services
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+)
+
+// maxProfilesPerUser bounds how many viewer profiles one account can hold
+const maxProfilesPerUser = 8
+
+// Profile is a viewer inside an account with its own bookmarks, history and personal settings
+type Profile struct {
+	ID        int    `json:"id"`
+	UserID    int    `json:"user_id"`
+	Name      string `json:"name"`
+	Avatar    string `json:"avatar"`
+	HasPIN    bool   `json:"has_pin"`
+	Active    bool   `json:"active"`
+	CreatedAt string `json:"created_at"`
+	UpdatedAt string `json:"updated_at"`
+}
+
+// ActiveProfileID returns the profile the user is currently viewing as.
+// Accounts without a usable profile get a default one named after the account.
+func (ds *DatabaseService) ActiveProfileID(userID int) (int, error) {
+	var activeID sql.NullInt64
+	var username string
+	err := ds.db.QueryRow("SELECT active_profile_id, username FROM users WHERE id = ?", userID).Scan(&activeID, &username)
+	if err == sql.ErrNoRows {
+		return 0, fmt.Errorf("user not found")
+	} else if err != nil {
+		return 0, fmt.Errorf("failed to query user: %w", err)
+	}
+
+	if activeID.Valid {
+		var exists bool
+		err := ds.db.QueryRow("SELECT EXISTS(SELECT 1 FROM profiles WHERE id = ? AND user_id = ?)", activeID.Int64, userID).Scan(&exists)
+		if err != nil {
+			return 0, fmt.Errorf("failed to query profile: %w", err)
+		}
+		if exists {
+			return int(activeID.Int64), nil
+		}
+	}
+
+	// Fall back to the oldest profile, creating one for accounts that have none
+	var profileID int64
+	err = ds.db.QueryRow("SELECT id FROM profiles WHERE user_id = ? ORDER BY id LIMIT 1", userID).Scan(&profileID)
+	if err == sql.ErrNoRows {
+		result, err := ds.db.Exec(`
+			INSERT INTO profiles (user_id, name, created_at, updated_at)
+			VALUES (?, ?, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP)
+		`, userID, username)
+		if err != nil {
+			return 0, fmt.Errorf("failed to create default profile: %w", err)
+		}
+		if profileID, err = result.LastInsertId(); err != nil {
+			return 0, err
+		}
+	} else if err != nil {
+		return 0, fmt.Errorf("failed to query profiles: %w", err)
+	}
+
+	if _, err := ds.db.Exec("UPDATE users SET active_profile_id = ? WHERE id = ?", profileID, userID); err != nil {
+		return 0, fmt.Errorf("failed to set active profile: %w", err)
+	}
+	return int(profileID), nil
+}
+
+// ListProfiles returns the user's profiles, marking the active one
+func (as *AuthService) ListProfiles(userID int) ([]Profile, error) {
+	activeID, err := as.db.ActiveProfileID(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := as.db.GetDB().Query(`
+		SELECT id, user_id, name, COALESCE(avatar, ''), pin_hash IS NOT NULL, created_at, updated_at
+		FROM profiles
+		WHERE user_id = ?
+		ORDER BY id
+	`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query profiles: %w", err)
+	}
+	defer rows.Close()
+
+	profiles := []Profile{}
+	for rows.Next() {
+		var p Profile
+		if err := rows.Scan(&p.ID, &p.UserID, &p.Name, &p.Avatar, &p.HasPIN, &p.CreatedAt, &p.UpdatedAt); err != nil {
+			return nil, err
+		}
+		p.Active = p.ID == activeID
+		profiles = append(profiles, p)
+	}
+	return profiles, rows.Err()
+}
+
+// CreateProfile adds a profile to the user's account. pin may be empty or four digits.
+// From a profile under parental controls the account's parental PIN is required.
+func (as *AuthService) CreateProfile(userID int, name, avatar, pin, parentalPIN string) (*Profile, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, fmt.Errorf("profile name is required")
+	}
+	if err := as.checkLeaveRestrictedProfile(userID, parentalPIN); err != nil {
+		return nil, err
+	}
+
+	var pinHash interface{}
+	if pin != "" {
+		if err := validateProfilePIN(pin); err != nil {
+			return nil, err
+		}
+		hashed, err := as.hashPassword(pin)
+		if err != nil {
+			return nil, fmt.Errorf("failed to hash PIN: %w", err)
+		}
+		pinHash = hashed
+	}
+
+	// Make sure the account's default profile exists before counting
+	if _, err := as.db.ActiveProfileID(userID); err != nil {
+		return nil, err
+	}
+
+	var count int
+	var nameTaken bool
+	err := as.db.GetDB().QueryRow(`
+		SELECT COUNT(*), COALESCE(SUM(name = ?), 0) > 0 FROM profiles WHERE user_id = ?
+	`, name, userID).Scan(&count, &nameTaken)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count profiles: %w", err)
+	}
+	if nameTaken {
+		return nil, fmt.Errorf("a profile with this name already exists")
+	}
+	if count >= maxProfilesPerUser {
+		return nil, fmt.Errorf("an account can have at most %d profiles", maxProfilesPerUser)
+	}
+
+	result, err := as.db.GetDB().Exec(`
+		INSERT INTO profiles (user_id, name, avatar, pin_hash, created_at, updated_at)
+		VALUES (?, ?, ?, ?, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP)
+	`, userID, name, avatar, pinHash)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create profile: %w", err)
+	}
+	profileID, err := result.LastInsertId()
+	if err != nil {
+		return nil, err
+	}
+
+	return as.getProfile(userID, int(profileID))
+}
+
+// SwitchProfile makes profileID the active profile, checking its PIN if it has one.
+// Leaving a profile under parental controls also needs the account's parental PIN.
+func (as *AuthService) SwitchProfile(userID, profileID int, pin, parentalPIN string) (*Profile, error) {
+	activeID, err := as.db.ActiveProfileID(userID)
+	if err != nil {
+		return nil, err
+	}
+	if profileID != activeID {
+		if err := as.checkLeaveRestrictedProfile(userID, parentalPIN); err != nil {
+			return nil, err
+		}
+	}
+	if err := as.checkProfilePIN(userID, profileID, pin); err != nil {
+		return nil, err
+	}
+
+	if _, err := as.db.GetDB().Exec("UPDATE users SET active_profile_id = ? WHERE id = ?", profileID, userID); err != nil {
+		return nil, fmt.Errorf("failed to switch profile: %w", err)
+	}
+
+	return as.getProfile(userID, profileID)
+}
+
+// DeleteProfile removes a profile with its bookmarks, history and personal settings.
+// The last profile of an account cannot be deleted, and from a profile under parental
+// controls the account's parental PIN is required.
+func (as *AuthService) DeleteProfile(userID, profileID int, pin, parentalPIN string) error {
+	if err := as.checkLeaveRestrictedProfile(userID, parentalPIN); err != nil {
+		return err
+	}
+	if err := as.checkProfilePIN(userID, profileID, pin); err != nil {
+		return err
+	}
+
+	tx, err := as.db.GetDB().Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var count int
+	if err := tx.QueryRow("SELECT COUNT(*) FROM profiles WHERE user_id = ?", userID).Scan(&count); err != nil {
+		return fmt.Errorf("failed to count profiles: %w", err)
+	}
+	if count <= 1 {
+		return fmt.Errorf("cannot delete the last profile")
+	}
+
+	for _, table := range []string{"bookmarks", "history", "settings", "parental_controls", "skip_markers"} {
+		if _, err := tx.Exec("DELETE FROM "+table+" WHERE profile_id = ?", profileID); err != nil {
+			return fmt.Errorf("failed to delete %s: %w", table, err)
+		}
+	}
+	if _, err := tx.Exec("DELETE FROM profiles WHERE id = ?", profileID); err != nil {
+		return fmt.Errorf("failed to delete profile: %w", err)
+	}
+
+	// ActiveProfileID picks another profile on next use
+	if _, err := tx.Exec("UPDATE users SET active_profile_id = NULL WHERE id = ? AND active_profile_id = ?", userID, profileID); err != nil {
+		return fmt.Errorf("failed to reset active profile: %w", err)
+	}
+
+	return tx.Commit()
+}
+
+// getProfile loads one of the user's profiles
+func (as *AuthService) getProfile(userID, profileID int) (*Profile, error) {
+	var p Profile
+	var activeID sql.NullInt64
+	err := as.db.GetDB().QueryRow(`
+		SELECT p.id, p.user_id, p.name, COALESCE(p.avatar, ''), p.pin_hash IS NOT NULL, p.created_at, p.updated_at, u.active_profile_id
+		FROM profiles p
+		JOIN users u ON u.id = p.user_id
+		WHERE p.id = ? AND p.user_id = ?
+	`, profileID, userID).Scan(&p.ID, &p.UserID, &p.Name, &p.Avatar, &p.HasPIN, &p.CreatedAt, &p.UpdatedAt, &activeID)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("profile not found")
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to query profile: %w", err)
+	}
+	p.Active = activeID.Valid && int(activeID.Int64) == p.ID
+	return &p, nil
+}
+
+// checkProfilePIN verifies pin against the profile's PIN, if it has one.
+// Wrong PINs count against the pin_attempts throttle so four digits cannot be brute forced.
+func (as *AuthService) checkProfilePIN(userID, profileID int, pin string) error {
+	var pinHash sql.NullString
+	err := as.db.GetDB().QueryRow("SELECT pin_hash FROM profiles WHERE id = ? AND user_id = ?", profileID, userID).Scan(&pinHash)
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("profile not found")
+	} else if err != nil {
+		return fmt.Errorf("failed to query profile: %w", err)
+	}
+	if !pinHash.Valid {
+		return nil
+	}
+
+	key := fmt.Sprintf("profile:%d", profileID)
+	if err := as.checkPINAllowed(key); err != nil {
+		return err
+	}
+
+	valid, err := as.verifyPassword(pin, pinHash.String)
+	if err != nil {
+		return fmt.Errorf("failed to verify PIN: %w", err)
+	}
+	if !valid {
+		as.recordPINFailure(key)
+		return fmt.Errorf("incorrect PIN")
+	}
+
+	as.clearPINFailures(key)
+	return nil
+}
+
+// checkLeaveRestrictedProfile guards the ways out of a profile under parental controls:
+// creating, deleting or switching profiles needs manage_users or the account's parental PIN
+func (as *AuthService) checkLeaveRestrictedProfile(userID int, parentalPIN string) error {
+	rules, err := as.db.activeParentalRules(userID)
+	if err != nil {
+		return err
+	}
+	if rules == nil {
+		return nil
+	}
+	isManager, err := as.db.HasPermission(userID, PermManageUsers)
+	if err != nil {
+		return err
+	}
+	if isManager {
+		return nil
+	}
+	if parentalPIN == "" {
+		return fmt.Errorf("this profile has parental controls, enter the parental PIN")
+	}
+	return as.checkParentalPIN(userID, parentalPIN)
+}
+
+// validateProfilePIN requires exactly four digits
+func validateProfilePIN(pin string) error {
+	if len(pin) != 4 {
+		return fmt.Errorf("PIN must be 4 digits")
+	}
+	for _, r := range pin {
+		if r < '0' || r > '9' {
+			return fmt.Errorf("PIN must be 4 digits")
+		}
+	}
+	return nil
+}
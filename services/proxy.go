@@ -4,24 +4,105 @@ import (
 	"context"
 	"fmt"
 	"io"
+	"log"
 	"net/http"
 	"regexp"
 	"strings"
+	"sync"
 	"time"
 )
 
+const defaultProxyUserAgent = "Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36"
+
 // ProxyService handles HTTP proxy operations
-type ProxyService struct{}
+type ProxyService struct {
+	mu        sync.RWMutex
+	userAgent string
+	timeout   time.Duration
+	adFilter  bool
+	adRules   []AdFilterRule
+	cache     *segmentCache
+	prefetch  *segmentPrefetcher
+	stats     *DatabaseService // records speed tests once WireSourceStats has run
+}
 
 // NewProxyService creates a new ProxyService instance
 func NewProxyService() *ProxyService {
-	return &ProxyService{}
+	return &ProxyService{
+		userAgent: defaultProxyUserAgent,
+		timeout:   30 * time.Second,
+		adFilter:  true,
+		cache:     newSegmentCache(),
+		prefetch:  newSegmentPrefetcher(),
+	}
+}
+
+// WireProxySettings applies the proxy_* global settings to p and keeps them
+// in sync as they change. It is a function rather than a method so Wails
+// does not expose it to the frontend.
+func WireProxySettings(p *ProxyService, db *DatabaseService) {
+	apply := func() {
+		p.mu.Lock()
+		defer p.mu.Unlock()
+		p.userAgent = db.GetEffectiveString(0, "proxy_user_agent")
+		p.timeout = time.Duration(db.GetEffectiveInt(0, "proxy_timeout_seconds")) * time.Second
+		p.adFilter = db.GetEffectiveBool(0, "proxy_ad_filter")
+		rules, err := parseAdFilterRules(db.GetEffectiveString(0, "proxy_ad_filter_rules"))
+		if err != nil {
+			log.Printf("Ignoring ad filter rules: %v", err)
+			rules = nil
+		}
+		p.adRules = rules
+
+		p.prefetch.setAhead(db.GetEffectiveInt(0, "proxy_prefetch_segments"))
+		p.cache.setLimits(
+			int64(db.GetEffectiveInt(0, "proxy_cache_memory_mb"))<<20,
+			int64(db.GetEffectiveInt(0, "proxy_cache_disk_mb"))<<20,
+		)
+	}
+	apply()
+
+	db.Events().Subscribe(EventSettingChanged, func(e Event) {
+		change, ok := e.Payload.(SettingChangedEvent)
+		if !ok || change.UserID != nil || !strings.HasPrefix(change.Key, "proxy_") {
+			return
+		}
+		apply()
+	})
+}
+
+// EnableSegmentDiskCache lets segments that no longer fit in memory spill to
+// dir. Like WireProxySettings it is a function so it is not bound.
+func EnableSegmentDiskCache(p *ProxyService, dir string) error {
+	return p.cache.setDir(dir)
+}
+
+// GetSegmentCacheStats returns the read-ahead cache's hit and miss counters and usage
+func (p *ProxyService) GetSegmentCacheStats() *SegmentCacheStats {
+	stats := p.cache.stats()
+	p.prefetch.stats(&stats)
+	return &stats
+}
+
+// config returns the current proxy configuration
+func (p *ProxyService) config() (string, time.Duration) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.userAgent, p.timeout
+}
+
+// adFilterConfig returns whether ad stripping is on and the rule for a playlist host
+func (p *ProxyService) adFilterConfig(host string) (bool, AdFilterRule) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.adFilter, adFilterRuleFor(p.adRules, host)
 }
 
 // SpeedTestResult represents the result of a speed test
 type SpeedTestResult struct {
 	SpeedMBps float64 `json:"speedMBps"`
 	Error     string  `json:"error,omitempty"`
+	Cached    bool    `json:"cached,omitempty"` // a recent recorded result was reused
 }
 
 // ProxyImageResponse represents the response from ProxyImage
@@ -82,8 +163,29 @@ func pickFirstVariantURL(masterManifest, masterURL string) string {
 	return ""
 }
 
-// TestMediaSpeed tests the download speed of an m3u8 media stream
-func (p *ProxyService) TestMediaSpeed(m3u8URL string) *SpeedTestResult {
+// TestMediaSpeed tests the download speed of an m3u8 media stream. Results
+// are recorded against mcID and the stream's host, and a result from the last
+// few minutes is returned instead of testing again.
+func (p *ProxyService) TestMediaSpeed(m3u8URL, mcID string) *SpeedTestResult {
+	p.mu.RLock()
+	stats := p.stats
+	p.mu.RUnlock()
+	if stats == nil {
+		return p.measureMediaSpeed(m3u8URL)
+	}
+
+	if result, ok := stats.recentSpeedTest(mcID, m3u8URL); ok {
+		return result
+	}
+	result := p.measureMediaSpeed(m3u8URL)
+	if err := stats.RecordSpeedTest(mcID, m3u8URL, result); err != nil {
+		log.Printf("Failed to record speed test: %v", err)
+	}
+	return result
+}
+
+// measureMediaSpeed downloads the start of the first segment of an m3u8 stream
+func (p *ProxyService) measureMediaSpeed(m3u8URL string) *SpeedTestResult {
 	const bytesToFetch = 512 * 1024 // 512KB
 	const timeoutMS = 6000
 
@@ -198,14 +300,44 @@ func (p *ProxyService) TestMediaSpeed(m3u8URL string) *SpeedTestResult {
 
 // ProxyURLResponse represents the response from ProxyURL
 type ProxyURLResponse struct {
-	Data        []byte `json:"data"`
-	ContentType string `json:"contentType"`
+	Data        []byte          `json:"data"`
+	ContentType string          `json:"contentType"`
+	AdFilter    *AdFilterReport `json:"adFilter,omitempty"`  // set for HLS playlists
+	Sanitized   []string        `json:"sanitized,omitempty"` // fixes applied to a broken playlist or disguised segment
+
+	finalURL string // URL after redirects, which relative playlist entries resolve against
 }
 
-// ProxyURL fetches any URL and returns the data with content type
+// ProxyURL fetches any URL and returns the data with content type.
+// HLS playlists are sanitized and, when proxy_ad_filter is on, stripped of
+// spliced ads; segments disguised as images are returned as plain MPEG-TS.
+// Segments of a playlist fetched through the proxy are cached and read ahead,
+// so the player usually finds the next one already buffered; any other URL is
+// fetched without being cached.
 func (p *ProxyService) ProxyURL(url string) (*ProxyURLResponse, error) {
+	var resp *ProxyURLResponse
+	var err error
+	if p.prefetch.tracks(url) {
+		resp, err = p.cache.load(url, true, p.fetchURL)
+	} else {
+		resp, err = p.fetchURL(url)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if isHLSPlaylist(resp.Data) {
+		p.prefetch.track(resp.finalURL, string(resp.Data))
+	} else {
+		p.readAhead(url)
+	}
+	return resp, nil
+}
+
+// fetchURL does the network side of ProxyURL
+func (p *ProxyService) fetchURL(url string) (*ProxyURLResponse, error) {
+	userAgent, timeout := p.config()
 	client := &http.Client{
-		Timeout: 30 * time.Second,
+		Timeout: timeout,
 		CheckRedirect: func(req *http.Request, via []*http.Request) error {
 			// Follow up to 10 redirects
 			if len(via) >= 10 {
@@ -225,7 +357,7 @@ func (p *ProxyService) ProxyURL(url string) (*ProxyURLResponse, error) {
 	origin := fmt.Sprintf("%s://%s", parsedURL.Scheme, parsedURL.Host)
 
 	// Set comprehensive headers to mimic a real browser request
-	req.Header.Set("User-Agent", "Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36")
+	req.Header.Set("User-Agent", userAgent)
 	req.Header.Set("Accept", "*/*")
 	req.Header.Set("Accept-Language", "en-US,en;q=0.9,zh-CN;q=0.8,zh;q=0.7")
 	req.Header.Set("Accept-Encoding", "gzip, deflate, br")
@@ -263,10 +395,36 @@ func (p *ProxyService) ProxyURL(url string) (*ProxyURLResponse, error) {
 		}
 	}
 
-	return &ProxyURLResponse{
+	response := &ProxyURLResponse{
 		Data:        data,
 		ContentType: contentType,
-	}, nil
+		finalURL:    resp.Request.URL.String(),
+	}
+
+	if isHLSPlaylist(data) {
+		response.Data, response.Sanitized = SanitizePlaylist(data)
+		if !strings.Contains(strings.ToLower(contentType), "mpegurl") {
+			response.ContentType = hlsPlaylistContentType
+			response.Sanitized = append(response.Sanitized, "corrected content type "+contentType)
+		}
+
+		if enabled, rule := p.adFilterConfig(parsedURL.Hostname()); enabled {
+			// Segments are resolved against the final URL after redirects
+			playlistURL := response.finalURL
+			filtered, report := FilterAdSegments(string(response.Data), playlistURL, rule)
+			if report.Applied {
+				log.Printf("Stripped %d ad segments (%.1fs) from %s", report.RemovedSegments, report.RemovedSeconds, playlistURL)
+				response.Data = []byte(filtered)
+			}
+			response.AdFilter = report
+		}
+	} else if segment, fix := SanitizeSegment(data); fix != "" {
+		response.Data = segment
+		response.ContentType = "video/MP2T"
+		response.Sanitized = []string{fix}
+	}
+
+	return response, nil
 }
 
 // ProxyImage fetches an image from a URL and returns the image data with content type
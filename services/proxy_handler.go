@@ -0,0 +1,109 @@
+package services
+
+import (
+	"bytes"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// hlsProxyPath is where the asset server serves HLS through the proxy; the
+// upstream URL goes in the url query parameter
+const hlsProxyPath = "/proxy/hls"
+
+// playlistURIAttr matches the URI attribute of tags such as EXT-X-KEY and EXT-X-MAP
+var playlistURIAttr = regexp.MustCompile(`URI="([^"]*)"`)
+
+type proxyHandler struct {
+	proxy *ProxyService
+}
+
+// NewProxyHandler returns the asset server handler that lets the player load
+// HLS through ProxyURL. Playlists are rewritten so their segments, keys and
+// variants come back through the handler too, which is what lets them be
+// sanitized, ad-filtered and read ahead.
+func NewProxyHandler(p *ProxyService) http.Handler {
+	return &proxyHandler{proxy: p}
+}
+
+func (h *proxyHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != hlsProxyPath {
+		http.NotFound(w, r)
+		return
+	}
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	target := r.URL.Query().Get("url")
+	if u, err := url.Parse(target); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		http.Error(w, "invalid url", http.StatusBadRequest)
+		return
+	}
+
+	resp, err := h.proxy.ProxyURL(target)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+
+	data := resp.Data
+	w.Header().Set("Content-Type", resp.ContentType)
+	if !isHLSPlaylist(data) {
+		// Segments addressed with EXT-X-BYTERANGE are requested a range at a time
+		http.ServeContent(w, r, "", time.Time{}, bytes.NewReader(data))
+		return
+	}
+
+	data = []byte(rewritePlaylistURIs(string(data), resp.finalURL))
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Content-Length", strconv.Itoa(len(data)))
+	w.WriteHeader(http.StatusOK)
+	if r.Method == http.MethodGet {
+		w.Write(data)
+	}
+}
+
+// proxiedHLSURL returns the handler URL that serves rawURL through the proxy
+func proxiedHLSURL(rawURL string) string {
+	return hlsProxyPath + "?url=" + url.QueryEscape(rawURL)
+}
+
+// unproxiedURL returns the upstream URL behind a handler URL, or rawURL unchanged
+func unproxiedURL(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Path != hlsProxyPath {
+		return rawURL
+	}
+	if target := u.Query().Get("url"); target != "" {
+		return target
+	}
+	return rawURL
+}
+
+// rewritePlaylistURIs points every URI in a playlist at the proxy handler,
+// resolving relative ones against the playlist's own URL
+func rewritePlaylistURIs(playlist, playlistURL string) string {
+	lines := strings.Split(playlist, "\n")
+	for i, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case trimmed == "":
+		case strings.HasPrefix(trimmed, "#"):
+			lines[i] = playlistURIAttr.ReplaceAllStringFunc(line, func(attr string) string {
+				uri := playlistURIAttr.FindStringSubmatch(attr)[1]
+				if uri == "" || strings.HasPrefix(uri, "data:") {
+					return attr
+				}
+				return `URI="` + proxiedHLSURL(resolveURL(playlistURL, uri)) + `"`
+			})
+		default:
+			lines[i] = proxiedHLSURL(resolveURL(playlistURL, trimmed))
+		}
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,103 @@
+package services
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// secretKeyFileName is the file next to the database holding the key for secrets at rest
+const secretKeyFileName = "secret.key"
+
+const secretPrefix = "v1:"
+
+// loadSecretKey reads the AES-256 key next to the database, creating it on first use
+func (ds *DatabaseService) loadSecretKey() ([]byte, error) {
+	ds.keyMu.Lock()
+	defer ds.keyMu.Unlock()
+
+	if ds.secretKey != nil {
+		return ds.secretKey, nil
+	}
+
+	path := filepath.Join(filepath.Dir(ds.path), secretKeyFileName)
+	key, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		key = make([]byte, 32)
+		if _, err := rand.Read(key); err != nil {
+			return nil, fmt.Errorf("failed to generate secret key: %w", err)
+		}
+		if err := os.WriteFile(path, key, 0600); err != nil {
+			return nil, fmt.Errorf("failed to write secret key: %w", err)
+		}
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to read secret key: %w", err)
+	}
+
+	if len(key) != 32 {
+		return nil, fmt.Errorf("secret key has invalid length")
+	}
+
+	ds.secretKey = key
+	return key, nil
+}
+
+// EncryptSecret seals a value with AES-GCM for storage in the database
+func (ds *DatabaseService) EncryptSecret(plaintext string) (string, error) {
+	gcm, err := ds.secretCipher()
+	if err != nil {
+		return "", err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", fmt.Errorf("failed to generate nonce: %w", err)
+	}
+
+	sealed := gcm.Seal(nonce, nonce, []byte(plaintext), nil)
+	return secretPrefix + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// DecryptSecret opens a value sealed by EncryptSecret
+func (ds *DatabaseService) DecryptSecret(ciphertext string) (string, error) {
+	if !strings.HasPrefix(ciphertext, secretPrefix) {
+		return "", fmt.Errorf("unsupported secret format")
+	}
+
+	sealed, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(ciphertext, secretPrefix))
+	if err != nil {
+		return "", fmt.Errorf("failed to decode secret: %w", err)
+	}
+
+	gcm, err := ds.secretCipher()
+	if err != nil {
+		return "", err
+	}
+	if len(sealed) < gcm.NonceSize() {
+		return "", fmt.Errorf("secret is truncated")
+	}
+
+	nonce, data := sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():]
+	plaintext, err := gcm.Open(nil, nonce, data, nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to decrypt secret: %w", err)
+	}
+	return string(plaintext), nil
+}
+
+func (ds *DatabaseService) secretCipher() (cipher.AEAD, error) {
+	key, err := ds.loadSecretKey()
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,278 @@
+package services
+
+import (
+	"container/list"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+const (
+	defaultCacheMemoryMB = 64
+	defaultCacheDiskMB   = 512
+)
+
+// SegmentCacheStats reports how well the segment read-ahead cache is doing
+type SegmentCacheStats struct {
+	Hits            uint64  `json:"hits"`            // segments served from the cache or a prefetch already under way
+	Misses          uint64  `json:"misses"`          // segments fetched on demand
+	Prefetched      uint64  `json:"prefetched"`      // segments fetched ahead of the player
+	PrefetchErrors  uint64  `json:"prefetchErrors"`  // prefetches that failed
+	MemoryEntries   int     `json:"memoryEntries"`   // segments held in memory
+	MemoryBytes     int64   `json:"memoryBytes"`     // bytes held in memory
+	DiskEntries     int     `json:"diskEntries"`     // segments spilled to disk
+	DiskBytes       int64   `json:"diskBytes"`       // bytes spilled to disk
+	ActivePlaylists int     `json:"activePlaylists"` // playlists being read ahead
+	Concurrency     int     `json:"concurrency"`     // parallel prefetches for the most recent playlist
+	Throughput      float64 `json:"throughput"`      // measured bytes per second per connection for its host
+}
+
+// segmentCache keeps recently fetched segments in a bounded in-memory LRU.
+// Entries pushed out of memory spill to a bounded directory on disk, which is
+// emptied at startup since nothing in it outlives the session.
+type segmentCache struct {
+	mu sync.Mutex
+
+	memLimit int64
+	memBytes int64
+	memLRU   *list.List // front is most recently used
+	mem      map[string]*list.Element
+
+	dir       string
+	diskLimit int64
+	diskBytes int64
+	diskLRU   *list.List
+	disk      map[string]*list.Element
+
+	inflight map[string]*segmentFetch
+
+	hits, misses uint64
+}
+
+type cachedSegment struct {
+	url         string
+	data        []byte // nil once spilled to disk
+	size        int64
+	contentType string
+	sanitized   []string
+}
+
+// segmentFetch lets requests for a segment that is already being fetched wait for it
+type segmentFetch struct {
+	done chan struct{}
+	resp *ProxyURLResponse
+	err  error
+}
+
+func newSegmentCache() *segmentCache {
+	return &segmentCache{
+		memLimit:  defaultCacheMemoryMB << 20,
+		memLRU:    list.New(),
+		mem:       make(map[string]*list.Element),
+		diskLimit: defaultCacheDiskMB << 20,
+		diskLRU:   list.New(),
+		disk:      make(map[string]*list.Element),
+		inflight:  make(map[string]*segmentFetch),
+	}
+}
+
+// setLimits changes the memory and disk budgets, evicting as needed
+func (c *segmentCache) setLimits(memBytes, diskBytes int64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.memLimit = memBytes
+	c.diskLimit = diskBytes
+	c.evictLocked()
+}
+
+// setDir enables the disk tier in dir, removing whatever a previous run left there
+func (c *segmentCache) setDir(dir string) error {
+	if err := os.RemoveAll(dir); err != nil {
+		return fmt.Errorf("failed to clear segment cache: %w", err)
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create segment cache: %w", err)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.dir = dir
+	return nil
+}
+
+// get returns a cached segment and counts a hit. Segments on disk are read
+// back into memory.
+func (c *segmentCache) get(url string) (*ProxyURLResponse, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if el, ok := c.mem[url]; ok {
+		c.memLRU.MoveToFront(el)
+		c.hits++
+		return el.Value.(*cachedSegment).response(), true
+	}
+
+	el, ok := c.disk[url]
+	if !ok {
+		return nil, false
+	}
+	seg := el.Value.(*cachedSegment)
+	data, err := os.ReadFile(c.pathLocked(url))
+	c.removeDiskLocked(el)
+	if err != nil {
+		log.Printf("Dropping unreadable cached segment: %v", err)
+		return nil, false
+	}
+	seg.data = data
+	c.hits++
+	c.insertLocked(seg)
+	return seg.response(), true
+}
+
+// contains reports whether a segment is cached or being fetched, without counting anything
+func (c *segmentCache) contains(url string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	_, inMem := c.mem[url]
+	_, onDisk := c.disk[url]
+	_, fetching := c.inflight[url]
+	return inMem || onDisk || fetching
+}
+
+// load returns a segment from the cache or fetches it, sharing a fetch that
+// is already in flight. Only the player's requests are counted: a request
+// that finds its segment cached or already being prefetched is a hit, one that
+// has to fetch a segment itself is a miss. A nil response with no error means
+// an uncounted load found the segment already cached.
+func (c *segmentCache) load(url string, counted bool, fetch func(string) (*ProxyURLResponse, error)) (*ProxyURLResponse, error) {
+	if counted {
+		if resp, ok := c.get(url); ok {
+			return resp, nil
+		}
+	}
+
+	c.mu.Lock()
+	if f, ok := c.inflight[url]; ok {
+		if counted {
+			c.hits++
+		}
+		c.mu.Unlock()
+		<-f.done
+		return f.resp, f.err
+	}
+	if !counted {
+		// A prefetch racing a segment that has just been cached has nothing to do
+		_, inMem := c.mem[url]
+		_, onDisk := c.disk[url]
+		if inMem || onDisk {
+			c.mu.Unlock()
+			return nil, nil
+		}
+	}
+	f := &segmentFetch{done: make(chan struct{})}
+	c.inflight[url] = f
+	c.mu.Unlock()
+
+	f.resp, f.err = fetch(url)
+
+	c.mu.Lock()
+	delete(c.inflight, url)
+	// Playlists change and are never cached, so they are not counted either
+	if f.err == nil && !isHLSPlaylist(f.resp.Data) {
+		if counted {
+			c.misses++
+		}
+		c.insertLocked(&cachedSegment{
+			url:         url,
+			data:        f.resp.Data,
+			size:        int64(len(f.resp.Data)),
+			contentType: f.resp.ContentType,
+			sanitized:   f.resp.Sanitized,
+		})
+	}
+	c.mu.Unlock()
+	close(f.done)
+
+	return f.resp, f.err
+}
+
+// stats fills in the cache part of SegmentCacheStats
+func (c *segmentCache) stats() SegmentCacheStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return SegmentCacheStats{
+		Hits:          c.hits,
+		Misses:        c.misses,
+		MemoryEntries: len(c.mem),
+		MemoryBytes:   c.memBytes,
+		DiskEntries:   len(c.disk),
+		DiskBytes:     c.diskBytes,
+	}
+}
+
+func (c *segmentCache) insertLocked(seg *cachedSegment) {
+	if old, ok := c.mem[seg.url]; ok {
+		c.memBytes -= old.Value.(*cachedSegment).size
+		c.memLRU.Remove(old)
+	}
+	if old, ok := c.disk[seg.url]; ok {
+		c.removeDiskLocked(old)
+	}
+	c.mem[seg.url] = c.memLRU.PushFront(seg)
+	c.memBytes += seg.size
+	c.evictLocked()
+}
+
+// evictLocked spills the least recently used segments to disk until memory is
+// within budget, then deletes the oldest files until disk is too
+func (c *segmentCache) evictLocked() {
+	for c.memBytes > c.memLimit && c.memLRU.Len() > 0 {
+		el := c.memLRU.Back()
+		seg := el.Value.(*cachedSegment)
+		c.memLRU.Remove(el)
+		delete(c.mem, seg.url)
+		c.memBytes -= seg.size
+
+		if c.dir == "" || seg.size > c.diskLimit {
+			continue
+		}
+		if err := os.WriteFile(c.pathLocked(seg.url), seg.data, 0644); err != nil {
+			log.Printf("Failed to spill segment to disk: %v", err)
+			continue
+		}
+		seg.data = nil
+		c.disk[seg.url] = c.diskLRU.PushFront(seg)
+		c.diskBytes += seg.size
+	}
+
+	for c.diskBytes > c.diskLimit && c.diskLRU.Len() > 0 {
+		c.removeDiskLocked(c.diskLRU.Back())
+	}
+}
+
+func (c *segmentCache) removeDiskLocked(el *list.Element) {
+	seg := el.Value.(*cachedSegment)
+	c.diskLRU.Remove(el)
+	delete(c.disk, seg.url)
+	c.diskBytes -= seg.size
+	if err := os.Remove(c.pathLocked(seg.url)); err != nil && !os.IsNotExist(err) {
+		log.Printf("Failed to remove cached segment: %v", err)
+	}
+}
+
+func (c *segmentCache) pathLocked(url string) string {
+	sum := sha1.Sum([]byte(url))
+	return filepath.Join(c.dir, hex.EncodeToString(sum[:]))
+}
+
+func (s *cachedSegment) response() *ProxyURLResponse {
+	return &ProxyURLResponse{
+		Data:        s.data,
+		ContentType: s.contentType,
+		Sanitized:   s.sanitized,
+	}
+}
@@ -0,0 +1,105 @@
+package services
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"time"
+)
+
+// sessionLifetime is how long a session stays valid without being used
+const sessionLifetime = 30 * 24 * time.Hour
+
+// hashSessionToken returns the form a session token is stored in
+func hashSessionToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// createSession starts a session for userID and returns its token
+func (as *AuthService) createSession(userID int) (string, error) {
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return "", fmt.Errorf("failed to generate session token: %w", err)
+	}
+	token := base64.RawURLEncoding.EncodeToString(raw)
+
+	_, err := as.db.GetDB().Exec(`
+		INSERT INTO sessions (user_id, token_hash, created_at, last_seen_at, expires_at)
+		VALUES (?, ?, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP, ?)
+	`, userID, hashSessionToken(token), time.Now().Add(sessionLifetime).UTC().Format(dbTimeLayout))
+	if err != nil {
+		return "", fmt.Errorf("failed to create session: %w", err)
+	}
+
+	return token, nil
+}
+
+// ValidateSession returns the user a session token belongs to and extends the session
+func (as *AuthService) ValidateSession(token string) (*User, error) {
+	if token == "" {
+		return nil, fmt.Errorf("session token is required")
+	}
+
+	var user User
+	var sessionID int
+	var disabled bool
+	err := as.db.GetDB().QueryRow(`
+		SELECT s.id, u.id, u.username, u.email, u.user_role, u.disabled, u.must_change_password, u.totp_enabled, u.meta_data, u.created_at, u.updated_at
+		FROM sessions s
+		JOIN users u ON u.id = s.user_id
+		WHERE s.token_hash = ? AND s.expires_at > ?
+	`, hashSessionToken(token), time.Now().UTC().Format(dbTimeLayout)).Scan(
+		&sessionID, &user.ID, &user.Username, &user.Email, &user.UserRole, &disabled, &user.MustChangePassword, &user.TwoFactorEnabled, &user.MetaData, &user.CreatedAt, &user.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("session expired or invalid")
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to query session: %w", err)
+	}
+
+	if disabled {
+		return nil, fmt.Errorf("account is disabled")
+	}
+
+	_, err = as.db.GetDB().Exec(`
+		UPDATE sessions SET last_seen_at = CURRENT_TIMESTAMP, expires_at = ?
+		WHERE id = ?
+	`, time.Now().Add(sessionLifetime).UTC().Format(dbTimeLayout), sessionID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to refresh session: %w", err)
+	}
+
+	user.SessionToken = token
+	return &user, nil
+}
+
+// Logout ends the session identified by token
+func (as *AuthService) Logout(token string) error {
+	var userID int
+	var username string
+	err := as.db.GetDB().QueryRow(`
+		SELECT u.id, u.username FROM sessions s JOIN users u ON u.id = s.user_id
+		WHERE s.token_hash = ?
+	`, hashSessionToken(token)).Scan(&userID, &username)
+	if err == sql.ErrNoRows {
+		return nil
+	} else if err != nil {
+		return fmt.Errorf("failed to query session: %w", err)
+	}
+
+	if _, err := as.db.GetDB().Exec("DELETE FROM sessions WHERE token_hash = ?", hashSessionToken(token)); err != nil {
+		return err
+	}
+
+	as.recordAuthEvent(&userID, throttleKey(username), AuthEventLogout, true, "")
+	return nil
+}
+
+// revokeOtherSessions ends every session of userID except the one identified by keepToken
+func revokeOtherSessions(tx *sql.Tx, userID int, keepToken string) error {
+	_, err := tx.Exec("DELETE FROM sessions WHERE user_id = ? AND token_hash != ?", userID, hashSessionToken(keepToken))
+	return err
+}
@@ -0,0 +1,483 @@
+package services
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// SettingType is the value type of a registered setting
+type SettingType string
+
+const (
+	SettingTypeString SettingType = "string"
+	SettingTypeInt    SettingType = "int"
+	SettingTypeBool   SettingType = "bool"
+	SettingTypeEnum   SettingType = "enum"
+	SettingTypeJSON   SettingType = "json"
+)
+
+// SettingScope controls whether a setting may be stored globally, per user, or both
+type SettingScope string
+
+const (
+	SettingScopeGlobal   SettingScope = "global"
+	SettingScopePersonal SettingScope = "personal"
+	SettingScopeBoth     SettingScope = "both"
+)
+
+// Where an effective setting value came from
+const (
+	SettingSourcePersonal = "personal"
+	SettingSourceGlobal   = "global"
+	SettingSourceDefault  = "default"
+)
+
+// SettingDefinition declares a known setting key
+type SettingDefinition struct {
+	Key         string       `json:"key"`
+	Type        SettingType  `json:"type"`
+	Default     string       `json:"default"`
+	Scope       SettingScope `json:"scope"`
+	Description string       `json:"description"`
+	Options     []string     `json:"options,omitempty"`
+	Min         *int         `json:"min,omitempty"`
+	Max         *int         `json:"max,omitempty"`
+
+	// validate runs after type checks for rules the type cannot express
+	validate func(value string) error
+}
+
+// EffectiveSetting is the resolved value of a setting for a user
+type EffectiveSetting struct {
+	Key    string      `json:"key"`
+	Type   SettingType `json:"type"`
+	Value  string      `json:"value"`
+	Source string      `json:"source"`
+}
+
+var settingRegistry = map[string]SettingDefinition{}
+
+func intPtr(v int) *int {
+	return &v
+}
+
+// registerSetting adds a definition to the registry; keys must be unique
+func registerSetting(def SettingDefinition) {
+	if _, exists := settingRegistry[def.Key]; exists {
+		panic("setting registered twice: " + def.Key)
+	}
+	if _, err := def.Normalize(def.Default); err != nil {
+		panic(fmt.Sprintf("invalid default for setting %s: %v", def.Key, err))
+	}
+	settingRegistry[def.Key] = def
+}
+
+func init() {
+	registerSetting(SettingDefinition{
+		Key:         "backup_interval_hours",
+		Type:        SettingTypeInt,
+		Default:     "24",
+		Scope:       SettingScopeGlobal,
+		Description: "Hours between scheduled database snapshots; 0 disables them",
+		Min:         intPtr(0),
+		Max:         intPtr(24 * 30),
+	})
+	registerSetting(SettingDefinition{
+		Key:         "backup_retention",
+		Type:        SettingTypeInt,
+		Default:     "7",
+		Scope:       SettingScopeGlobal,
+		Description: "Number of database snapshots to keep; 0 keeps all",
+		Min:         intPtr(0),
+		Max:         intPtr(365),
+	})
+	registerSetting(SettingDefinition{
+		Key:         "link_check_interval_hours",
+		Type:        SettingTypeInt,
+		Default:     "24",
+		Scope:       SettingScopeGlobal,
+		Description: "Hours between checks of the video sources of bookmarked media; 0 disables them",
+		Min:         intPtr(0),
+		Max:         intPtr(24 * 30),
+	})
+	registerSetting(SettingDefinition{
+		Key:         "link_check_concurrency",
+		Type:        SettingTypeInt,
+		Default:     "4",
+		Scope:       SettingScopeGlobal,
+		Description: "Sources probed at once by the link checker",
+		Min:         intPtr(1),
+		Max:         intPtr(16),
+	})
+	registerSetting(SettingDefinition{
+		Key:         "proxy_user_agent",
+		Type:        SettingTypeString,
+		Default:     defaultProxyUserAgent,
+		Scope:       SettingScopeGlobal,
+		Description: "User-Agent sent by the media proxy",
+		validate: func(value string) error {
+			if strings.TrimSpace(value) == "" {
+				return fmt.Errorf("proxy_user_agent cannot be empty")
+			}
+			return nil
+		},
+	})
+	registerSetting(SettingDefinition{
+		Key:         "proxy_timeout_seconds",
+		Type:        SettingTypeInt,
+		Default:     "30",
+		Scope:       SettingScopeGlobal,
+		Description: "Timeout for a single proxied request",
+		Min:         intPtr(5),
+		Max:         intPtr(300),
+	})
+	registerSetting(SettingDefinition{
+		Key:         "proxy_ad_filter",
+		Type:        SettingTypeBool,
+		Default:     "true",
+		Scope:       SettingScopeGlobal,
+		Description: "Strip ad blocks spliced into HLS playlists served through the proxy",
+	})
+	registerSetting(SettingDefinition{
+		Key:         "proxy_ad_filter_rules",
+		Type:        SettingTypeJSON,
+		Default:     "[]",
+		Scope:       SettingScopeGlobal,
+		Description: `Per-host ad filter rules: [{"host", "disabled", "adHosts", "segmentPatterns", "adDurations", "maxAdBlockSeconds"}]; host "*" applies to all other hosts`,
+		validate: func(value string) error {
+			_, err := parseAdFilterRules(value)
+			return err
+		},
+	})
+	registerSetting(SettingDefinition{
+		Key:         "proxy_prefetch_segments",
+		Type:        SettingTypeInt,
+		Default:     strconv.Itoa(defaultPrefetchSegments),
+		Scope:       SettingScopeGlobal,
+		Description: "Segments fetched ahead of the player for playlists served through the proxy; 0 disables read-ahead",
+		Min:         intPtr(0),
+		Max:         intPtr(20),
+	})
+	registerSetting(SettingDefinition{
+		Key:         "proxy_cache_memory_mb",
+		Type:        SettingTypeInt,
+		Default:     strconv.Itoa(defaultCacheMemoryMB),
+		Scope:       SettingScopeGlobal,
+		Description: "Memory used to buffer proxied segments, in MB",
+		Min:         intPtr(8),
+		Max:         intPtr(2048),
+	})
+	registerSetting(SettingDefinition{
+		Key:         "proxy_cache_disk_mb",
+		Type:        SettingTypeInt,
+		Default:     strconv.Itoa(defaultCacheDiskMB),
+		Scope:       SettingScopeGlobal,
+		Description: "Disk space for segments that no longer fit in memory, in MB; 0 disables the disk cache",
+		Min:         intPtr(0),
+		Max:         intPtr(20480),
+	})
+	registerSetting(SettingDefinition{
+		Key:         "login_lockout_threshold",
+		Type:        SettingTypeInt,
+		Default:     "10",
+		Scope:       SettingScopeGlobal,
+		Description: "Consecutive failed logins that lock a username; 0 disables lockout",
+		Min:         intPtr(0),
+		Max:         intPtr(100),
+	})
+	registerSetting(SettingDefinition{
+		Key:         "login_lockout_minutes",
+		Type:        SettingTypeInt,
+		Default:     "15",
+		Scope:       SettingScopeGlobal,
+		Description: "How long a locked username stays locked",
+		Min:         intPtr(1),
+		Max:         intPtr(24 * 60),
+	})
+	registerSetting(SettingDefinition{
+		Key:         "registration_mode",
+		Type:        SettingTypeEnum,
+		Default:     RegistrationOpen,
+		Scope:       SettingScopeGlobal,
+		Description: "Who may create accounts: anyone, only holders of an invite code, or nobody",
+		Options:     []string{RegistrationOpen, RegistrationInviteOnly, RegistrationClosed},
+	})
+	registerSetting(SettingDefinition{
+		Key:         "auto_skip_intro",
+		Type:        SettingTypeBool,
+		Default:     "true",
+		Scope:       SettingScopeBoth,
+		Description: "Jump past a series' intro marker instead of offering a skip button",
+	})
+	registerSetting(SettingDefinition{
+		Key:         "auto_next_episode",
+		Type:        SettingTypeBool,
+		Default:     "true",
+		Scope:       SettingScopeBoth,
+		Description: "Move on to the next episode when a series' outro marker is reached",
+	})
+	registerSetting(SettingDefinition{
+		Key:         "danmaku_enabled",
+		Type:        SettingTypeBool,
+		Default:     "true",
+		Scope:       SettingScopeBoth,
+		Description: "Show bullet comments over the video",
+	})
+	registerSetting(SettingDefinition{
+		Key:         "danmaku_filter",
+		Type:        SettingTypeJSON,
+		Default:     `{"keywords":[],"users":[]}`,
+		Scope:       SettingScopePersonal,
+		Description: `Bullet comments to hide: {"keywords", "users"}; keywords match anywhere in the text, users are authors`,
+		validate: func(value string) error {
+			_, err := parseDanmakuFilter(value)
+			return err
+		},
+	})
+}
+
+// LookupSetting returns the definition for a registered key
+func LookupSetting(key string) (SettingDefinition, bool) {
+	def, ok := settingRegistry[key]
+	return def, ok
+}
+
+// ListSettingDefinitions returns all registered settings sorted by key
+func ListSettingDefinitions() []SettingDefinition {
+	defs := make([]SettingDefinition, 0, len(settingRegistry))
+	for _, def := range settingRegistry {
+		defs = append(defs, def)
+	}
+	sort.Slice(defs, func(i, j int) bool {
+		return defs[i].Key < defs[j].Key
+	})
+	return defs
+}
+
+// AllowsGlobal reports whether the setting may be stored without a user
+func (d SettingDefinition) AllowsGlobal() bool {
+	return d.Scope == SettingScopeGlobal || d.Scope == SettingScopeBoth
+}
+
+// AllowsPersonal reports whether the setting may be stored per user
+func (d SettingDefinition) AllowsPersonal() bool {
+	return d.Scope == SettingScopePersonal || d.Scope == SettingScopeBoth
+}
+
+// Normalize validates a value against the definition and returns its canonical form
+func (d SettingDefinition) Normalize(value string) (string, error) {
+	switch d.Type {
+	case SettingTypeInt:
+		n, err := strconv.Atoi(strings.TrimSpace(value))
+		if err != nil {
+			return "", fmt.Errorf("%s must be an integer", d.Key)
+		}
+		if d.Min != nil && n < *d.Min {
+			return "", fmt.Errorf("%s must be at least %d", d.Key, *d.Min)
+		}
+		if d.Max != nil && n > *d.Max {
+			return "", fmt.Errorf("%s must be at most %d", d.Key, *d.Max)
+		}
+		value = strconv.Itoa(n)
+	case SettingTypeBool:
+		b, err := strconv.ParseBool(strings.TrimSpace(value))
+		if err != nil {
+			return "", fmt.Errorf("%s must be true or false", d.Key)
+		}
+		value = strconv.FormatBool(b)
+	case SettingTypeEnum:
+		value = strings.TrimSpace(value)
+		valid := false
+		for _, option := range d.Options {
+			if value == option {
+				valid = true
+				break
+			}
+		}
+		if !valid {
+			return "", fmt.Errorf("%s must be one of: %s", d.Key, strings.Join(d.Options, ", "))
+		}
+	case SettingTypeJSON:
+		if !json.Valid([]byte(value)) {
+			return "", fmt.Errorf("%s must be valid JSON", d.Key)
+		}
+	case SettingTypeString:
+	default:
+		return "", fmt.Errorf("%s has unknown type %s", d.Key, d.Type)
+	}
+
+	if d.validate != nil {
+		if err := d.validate(value); err != nil {
+			return "", err
+		}
+	}
+	return value, nil
+}
+
+// GetEffectiveSetting resolves a registered setting for a user,
+// falling back from the personal value of the active profile to the global value to the default.
+// A userID of 0 skips the personal lookup.
+func (ds *DatabaseService) GetEffectiveSetting(userID int, key string) (*EffectiveSetting, error) {
+	def, ok := LookupSetting(key)
+	if !ok {
+		return nil, fmt.Errorf("unknown setting: %s", key)
+	}
+
+	if userID > 0 && def.AllowsPersonal() {
+		profileID, err := ds.ActiveProfileID(userID)
+		if err != nil {
+			return nil, err
+		}
+		var value string
+		err = ds.db.QueryRow(`
+			SELECT setting_value FROM settings
+			WHERE profile_id = ? AND setting_key = ?
+		`, profileID, key).Scan(&value)
+		if err == nil {
+			if normalized, err := def.Normalize(value); err == nil {
+				return &EffectiveSetting{Key: key, Type: def.Type, Value: normalized, Source: SettingSourcePersonal}, nil
+			}
+		} else if err != sql.ErrNoRows {
+			return nil, err
+		}
+	}
+
+	if def.AllowsGlobal() {
+		value, ok, err := ds.GetGlobalSettingValue(key)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			if normalized, err := def.Normalize(value); err == nil {
+				return &EffectiveSetting{Key: key, Type: def.Type, Value: normalized, Source: SettingSourceGlobal}, nil
+			}
+		}
+	}
+
+	return &EffectiveSetting{Key: key, Type: def.Type, Value: def.Default, Source: SettingSourceDefault}, nil
+}
+
+// GetEffectiveInt resolves an int setting, returning its default if the lookup fails
+func (ds *DatabaseService) GetEffectiveInt(userID int, key string) int {
+	def, _ := LookupSetting(key)
+	setting, err := ds.GetEffectiveSetting(userID, key)
+	if err == nil {
+		if n, err := strconv.Atoi(setting.Value); err == nil {
+			return n
+		}
+	}
+	n, _ := strconv.Atoi(def.Default)
+	return n
+}
+
+// GetEffectiveBool resolves a bool setting, returning its default if the lookup fails
+func (ds *DatabaseService) GetEffectiveBool(userID int, key string) bool {
+	def, _ := LookupSetting(key)
+	setting, err := ds.GetEffectiveSetting(userID, key)
+	if err == nil {
+		if b, err := strconv.ParseBool(setting.Value); err == nil {
+			return b
+		}
+	}
+	b, _ := strconv.ParseBool(def.Default)
+	return b
+}
+
+// GetEffectiveString resolves a setting's raw value, returning its default if the lookup fails
+func (ds *DatabaseService) GetEffectiveString(userID int, key string) string {
+	setting, err := ds.GetEffectiveSetting(userID, key)
+	if err != nil {
+		def, _ := LookupSetting(key)
+		return def.Default
+	}
+	return setting.Value
+}
+
+// UpsertSetting validates and writes a registered setting.
+// Global settings need the edit_global_settings permission; personal settings belong to userID's active profile.
+func (ds *DatabaseService) UpsertSetting(key, value string, userID int, global bool) error {
+	def, ok := LookupSetting(key)
+	if !ok {
+		return fmt.Errorf("unknown setting: %s", key)
+	}
+
+	if global {
+		if !def.AllowsGlobal() {
+			return fmt.Errorf("%s cannot be set globally", key)
+		}
+		if err := ds.Authorize(userID, PermEditGlobalSettings); err != nil {
+			return err
+		}
+	} else if !def.AllowsPersonal() {
+		return fmt.Errorf("%s can only be set globally", key)
+	}
+
+	normalized, err := def.Normalize(value)
+	if err != nil {
+		return err
+	}
+
+	if !global {
+		profileID, err := ds.ActiveProfileID(userID)
+		if err != nil {
+			return err
+		}
+		_, err = ds.db.Exec(`
+			INSERT INTO settings (user_id, profile_id, setting_key, setting_value, created_at, updated_at)
+			VALUES (?, ?, ?, ?, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP)
+			ON CONFLICT(profile_id, setting_key) DO UPDATE SET
+				setting_value = excluded.setting_value,
+				updated_at = CURRENT_TIMESTAMP
+		`, userID, profileID, key, normalized)
+		if err != nil {
+			return err
+		}
+		ds.publishSettingChanged(key, normalized, sql.NullInt64{Int64: int64(userID), Valid: true}, false)
+		return nil
+	}
+
+	// NULL profile_id never conflicts in the UNIQUE constraint, so global rows are matched by hand
+	tx, err := ds.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var before interface{}
+	var previous string
+	err = tx.QueryRow("SELECT setting_value FROM settings WHERE user_id IS NULL AND setting_key = ?", key).Scan(&previous)
+	if err == nil {
+		before = previous
+	} else if err != sql.ErrNoRows {
+		return err
+	}
+
+	if before != nil {
+		_, err = tx.Exec(`
+			UPDATE settings
+			SET setting_value = ?, updated_at = CURRENT_TIMESTAMP
+			WHERE user_id IS NULL AND setting_key = ?
+		`, normalized, key)
+	} else {
+		_, err = tx.Exec(`
+			INSERT INTO settings (user_id, setting_key, setting_value, created_at, updated_at)
+			VALUES (NULL, ?, ?, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP)
+		`, key, normalized)
+	}
+	if err != nil {
+		return err
+	}
+	if err := recordAudit(tx, userID, AuditSettingUpdated, AuditTargetSetting, key, before, normalized); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	ds.publishSettingChanged(key, normalized, sql.NullInt64{}, false)
+	return nil
+}
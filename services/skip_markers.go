@@ -0,0 +1,158 @@
+package services
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+// Markers that can be set on a series
+const (
+	MarkerIntroStart = "intro_start"
+	MarkerIntroEnd   = "intro_end"
+	MarkerOutroStart = "outro_start"
+	MarkerOutroEnd   = "outro_end"
+)
+
+// SkipMarkers are a profile's intro and outro markers for a series, shared by
+// all its episodes. Intro offsets count from the start of an episode, outro
+// offsets back from its end. Unset markers are nil.
+type SkipMarkers struct {
+	MCID       string   `json:"mc_id"`
+	IntroStart *float64 `json:"intro_start"`
+	IntroEnd   *float64 `json:"intro_end"`
+	OutroStart *float64 `json:"outro_start"`
+	OutroEnd   *float64 `json:"outro_end"`
+
+	// Resolved from the auto_skip_intro and auto_next_episode settings
+	AutoSkip    bool `json:"auto_skip"`
+	AutoAdvance bool `json:"auto_advance"`
+}
+
+// GetSkipMarkers returns the active profile's markers for a series; a series
+// without markers gets empty ones
+func (ds *DatabaseService) GetSkipMarkers(userID int, mcID string) (*SkipMarkers, error) {
+	profileID, err := ds.ActiveProfileID(userID)
+	if err != nil {
+		return nil, err
+	}
+	markers, err := querySkipMarkers(ds.db, profileID, mcID)
+	if err != nil {
+		return nil, err
+	}
+	ds.resolveSkipSettings(userID, markers)
+	return markers, nil
+}
+
+// SetSkipMarker sets one marker of a series from the player's position in an
+// episode of the given duration. Outro markers are stored relative to the end.
+func (ds *DatabaseService) SetSkipMarker(userID int, mcID, marker string, position, duration float64) (*SkipMarkers, error) {
+	if mcID == "" {
+		return nil, fmt.Errorf("media id is required")
+	}
+	if position < 0 {
+		return nil, fmt.Errorf("position must not be negative")
+	}
+	profileID, err := ds.ActiveProfileID(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	value := position
+	switch marker {
+	case MarkerIntroStart, MarkerIntroEnd:
+	case MarkerOutroStart, MarkerOutroEnd:
+		if duration <= 0 || position > duration {
+			return nil, fmt.Errorf("episode duration is required for outro markers")
+		}
+		value = duration - position
+	default:
+		return nil, fmt.Errorf("unknown marker: %s", marker)
+	}
+
+	tx, err := ds.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	markers, err := querySkipMarkers(tx, profileID, mcID)
+	if err != nil {
+		return nil, err
+	}
+	switch marker {
+	case MarkerIntroStart:
+		markers.IntroStart = &value
+	case MarkerIntroEnd:
+		markers.IntroEnd = &value
+	case MarkerOutroStart:
+		markers.OutroStart = &value
+	case MarkerOutroEnd:
+		markers.OutroEnd = &value
+	}
+	if markers.IntroStart != nil && markers.IntroEnd != nil && *markers.IntroEnd <= *markers.IntroStart {
+		return nil, fmt.Errorf("the intro must end after it starts")
+	}
+	if markers.OutroStart != nil && markers.OutroEnd != nil && *markers.OutroEnd >= *markers.OutroStart {
+		return nil, fmt.Errorf("the outro must end after it starts")
+	}
+
+	if _, err := tx.Exec(`
+		INSERT INTO skip_markers (user_id, profile_id, mc_id, intro_start, intro_end, outro_start, outro_end, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP)
+		ON CONFLICT(profile_id, mc_id) DO UPDATE SET
+			intro_start = excluded.intro_start,
+			intro_end = excluded.intro_end,
+			outro_start = excluded.outro_start,
+			outro_end = excluded.outro_end,
+			updated_at = CURRENT_TIMESTAMP
+	`, userID, profileID, mcID, markers.IntroStart, markers.IntroEnd, markers.OutroStart, markers.OutroEnd); err != nil {
+		return nil, fmt.Errorf("failed to save skip markers: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	ds.resolveSkipSettings(userID, markers)
+	return markers, nil
+}
+
+// ClearSkipMarkers removes the active profile's markers for a series
+func (ds *DatabaseService) ClearSkipMarkers(userID int, mcID string) error {
+	profileID, err := ds.ActiveProfileID(userID)
+	if err != nil {
+		return err
+	}
+	_, err = ds.db.Exec("DELETE FROM skip_markers WHERE profile_id = ? AND mc_id = ?", profileID, mcID)
+	return err
+}
+
+func (ds *DatabaseService) resolveSkipSettings(userID int, markers *SkipMarkers) {
+	markers.AutoSkip = ds.GetEffectiveBool(userID, "auto_skip_intro")
+	markers.AutoAdvance = ds.GetEffectiveBool(userID, "auto_next_episode")
+}
+
+func querySkipMarkers(q queryRower, profileID int, mcID string) (*SkipMarkers, error) {
+	markers := &SkipMarkers{MCID: mcID}
+	var introStart, introEnd, outroStart, outroEnd sql.NullFloat64
+	err := q.QueryRow(`
+		SELECT intro_start, intro_end, outro_start, outro_end
+		FROM skip_markers WHERE profile_id = ? AND mc_id = ?
+	`, profileID, mcID).Scan(&introStart, &introEnd, &outroStart, &outroEnd)
+	if err == sql.ErrNoRows {
+		return markers, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to query skip markers: %w", err)
+	}
+	markers.IntroStart = nullFloatPtr(introStart)
+	markers.IntroEnd = nullFloatPtr(introEnd)
+	markers.OutroStart = nullFloatPtr(outroStart)
+	markers.OutroEnd = nullFloatPtr(outroEnd)
+	return markers, nil
+}
+
+func nullFloatPtr(v sql.NullFloat64) *float64 {
+	if !v.Valid {
+		return nil
+	}
+	return &v.Float64
+}
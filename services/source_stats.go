@@ -0,0 +1,221 @@
+package services
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"sort"
+)
+
+// Kinds of source_stats rows
+const (
+	SourceEventSpeedTest     = "speed_test"
+	SourceEventPlaybackError = "playback_error"
+)
+
+const (
+	// neutralReputation is the score of a host with no history
+	neutralReputation = 50.0
+
+	// reputationSmoothing is the weight of a new result in a host's score
+	reputationSmoothing = 0.2
+
+	// reputationTargetMBps is the speed that earns a full score; it matches
+	// the speed the media cards show in green
+	reputationTargetMBps = 4.0
+
+	// sourceStatsRetentionDays of raw results are kept; scores carry the rest
+	sourceStatsRetentionDays = 90
+
+	// speedTestFreshMinutes is how long a recorded speed test is reused
+	// instead of testing the source again
+	speedTestFreshMinutes = 10
+)
+
+// HostReport summarizes a CDN host's history for admins
+type HostReport struct {
+	Host           string  `json:"host"`
+	Score          float64 `json:"score"`
+	SpeedTests     int     `json:"speed_tests"`
+	Failures       int     `json:"failures"`
+	PlaybackErrors int     `json:"playback_errors"`
+	AvgSpeedMBps   float64 `json:"avg_speed_mbps"`
+	Titles         int     `json:"titles"`
+	LastError      string  `json:"last_error"`
+	UpdatedAt      string  `json:"updated_at"`
+}
+
+// WireSourceStats lets the proxy record speed tests and reuse recent ones.
+// It is a function rather than a method so Wails does not expose it.
+func WireSourceStats(p *ProxyService, db *DatabaseService) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.stats = db
+}
+
+// RecordSpeedTest stores a speed test result for a title's source
+func (ds *DatabaseService) RecordSpeedTest(mcID, sourceURL string, result *SpeedTestResult) error {
+	if result.Error != "" {
+		return ds.recordSourceEvent(mcID, sourceURL, SourceEventSpeedTest, nil, result.Error, 0)
+	}
+	sample := result.SpeedMBps / reputationTargetMBps * 100
+	if sample > 100 {
+		sample = 100
+	}
+	return ds.recordSourceEvent(mcID, sourceURL, SourceEventSpeedTest, &result.SpeedMBps, "", sample)
+}
+
+// RecordPlaybackError stores a playback failure against a title's source
+func (ds *DatabaseService) RecordPlaybackError(mcID, sourceURL, details string) error {
+	if details == "" {
+		details = "playback error"
+	}
+	return ds.recordSourceEvent(mcID, sourceURL, SourceEventPlaybackError, nil, details, 0)
+}
+
+// recordSourceEvent appends a result and folds it into the host's score.
+// Failures count as a zero sample.
+func (ds *DatabaseService) recordSourceEvent(mcID, sourceURL, kind string, speed *float64, errText string, sample float64) error {
+	host := urlHost(sourceURL)
+	if host == "" {
+		return fmt.Errorf("invalid source url")
+	}
+
+	var errValue interface{}
+	if errText != "" {
+		errValue = errText
+	}
+	failed := 0
+	if errText != "" {
+		failed = 1
+	}
+	speedTest := 0
+	if kind == SourceEventSpeedTest {
+		speedTest = 1
+	}
+
+	tx, err := ds.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`
+		INSERT INTO source_stats (host, mc_id, url, kind, speed_mbps, error, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, CURRENT_TIMESTAMP)
+	`, host, mcID, sourceURL, kind, speed, errValue); err != nil {
+		return fmt.Errorf("failed to record source stats: %w", err)
+	}
+
+	if _, err := tx.Exec(`
+		INSERT INTO host_reputation (host, score, speed_tests, failures, avg_speed_mbps, updated_at)
+		VALUES (?1, ?2 + ?3 * (?4 - ?2), ?5, ?6, ?7, CURRENT_TIMESTAMP)
+		ON CONFLICT(host) DO UPDATE SET
+			score = score + ?3 * (?4 - score),
+			speed_tests = speed_tests + ?5,
+			failures = failures + ?6,
+			avg_speed_mbps = CASE
+				WHEN ?7 IS NULL THEN avg_speed_mbps
+				WHEN avg_speed_mbps IS NULL THEN ?7
+				ELSE avg_speed_mbps + ?3 * (?7 - avg_speed_mbps)
+			END,
+			updated_at = CURRENT_TIMESTAMP
+	`, host, neutralReputation, reputationSmoothing, sample, speedTest, failed, speed); err != nil {
+		return fmt.Errorf("failed to update host reputation: %w", err)
+	}
+
+	if _, err := tx.Exec(
+		"DELETE FROM source_stats WHERE created_at < datetime('now', ?)",
+		fmt.Sprintf("-%d days", sourceStatsRetentionDays),
+	); err != nil {
+		return fmt.Errorf("failed to prune source stats: %w", err)
+	}
+
+	return tx.Commit()
+}
+
+// recentSpeedTest returns the last speed test of a title's source if it is
+// still fresh enough to show instead of testing again
+func (ds *DatabaseService) recentSpeedTest(mcID, sourceURL string) (*SpeedTestResult, bool) {
+	var speed sql.NullFloat64
+	var errText sql.NullString
+	err := ds.db.QueryRow(`
+		SELECT speed_mbps, error FROM source_stats
+		WHERE kind = ? AND mc_id = ? AND url = ? AND created_at >= datetime('now', ?)
+		ORDER BY id DESC LIMIT 1
+	`, SourceEventSpeedTest, mcID, sourceURL, fmt.Sprintf("-%d minutes", speedTestFreshMinutes)).Scan(&speed, &errText)
+	if err != nil {
+		if err != sql.ErrNoRows {
+			log.Printf("Failed to look up speed test: %v", err)
+		}
+		return nil, false
+	}
+	return &SpeedTestResult{SpeedMBps: speed.Float64, Error: errText.String, Cached: true}, true
+}
+
+// HostScores returns the reputation of each host; hosts without history get a neutral score
+func (ds *DatabaseService) HostScores(hosts []string) map[string]float64 {
+	scores := make(map[string]float64, len(hosts))
+	for _, host := range hosts {
+		score := neutralReputation
+		err := ds.db.QueryRow("SELECT score FROM host_reputation WHERE host = ?", host).Scan(&score)
+		if err != nil && err != sql.ErrNoRows {
+			log.Printf("Failed to look up host reputation: %v", err)
+		}
+		scores[host] = score
+	}
+	return scores
+}
+
+// GetHostReport returns every host with history, best first
+func (ds *DatabaseService) GetHostReport() ([]HostReport, error) {
+	rows, err := ds.db.Query(`
+		SELECT r.host, r.score, r.speed_tests, r.failures, COALESCE(r.avg_speed_mbps, 0), r.updated_at,
+			(SELECT COUNT(*) FROM source_stats s WHERE s.host = r.host AND s.kind = ?),
+			(SELECT COUNT(DISTINCT s.mc_id) FROM source_stats s WHERE s.host = r.host AND s.mc_id != ''),
+			COALESCE((SELECT s.error FROM source_stats s WHERE s.host = r.host AND s.error IS NOT NULL ORDER BY s.id DESC LIMIT 1), '')
+		FROM host_reputation r
+		ORDER BY r.score DESC, r.host
+	`, SourceEventPlaybackError)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query host reputation: %w", err)
+	}
+	defer rows.Close()
+
+	report := []HostReport{}
+	for rows.Next() {
+		var h HostReport
+		if err := rows.Scan(&h.Host, &h.Score, &h.SpeedTests, &h.Failures, &h.AvgSpeedMBps, &h.UpdatedAt,
+			&h.PlaybackErrors, &h.Titles, &h.LastError); err != nil {
+			return nil, err
+		}
+		report = append(report, h)
+	}
+	return report, rows.Err()
+}
+
+// rankSources orders sources by the reputation of the host serving episode,
+// best first. Sources on equally rated hosts keep their order.
+func (ds *DatabaseService) rankSources(sources []PlaybackSource, episode string) []PlaybackSource {
+	hosts := make([]string, len(sources))
+	for i, s := range sources {
+		if u, ok := matchEpisode(s.URLs, episode); ok {
+			hosts[i] = urlHost(u)
+		}
+	}
+	scores := ds.HostScores(hosts)
+
+	ranked := make([]PlaybackSource, len(sources))
+	copy(ranked, sources)
+	order := make([]int, len(sources))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		return scores[hosts[order[a]]] > scores[hosts[order[b]]]
+	})
+	for i, j := range order {
+		ranked[i] = sources[j]
+	}
+	return ranked
+}
@@ -0,0 +1,59 @@
+package services
+
+import (
+	"database/sql"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// subtitlePathPrefix is where the asset server serves subtitles as /subtitles/<id>.vtt
+const subtitlePathPrefix = "/subtitles/"
+
+type subtitleHandler struct {
+	subtitles *SubtitleService
+}
+
+// NewSubtitleHandler returns the asset server handler that serves attached
+// subtitles as WebVTT for the player's <track> elements
+func NewSubtitleHandler(s *SubtitleService) http.Handler {
+	return &subtitleHandler{subtitles: s}
+}
+
+func (h *subtitleHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	name, ok := strings.CutSuffix(strings.TrimPrefix(r.URL.Path, subtitlePathPrefix), ".vtt")
+	id, err := strconv.ParseInt(name, 10, 64)
+	if !ok || err != nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	vtt, err := h.subtitles.vtt(id)
+	if err == sql.ErrNoRows {
+		http.NotFound(w, r)
+		return
+	} else if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/vtt; charset=utf-8")
+	w.Header().Set("Content-Length", strconv.Itoa(len(vtt)))
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusOK)
+	if r.Method == http.MethodGet {
+		w.Write([]byte(vtt))
+	}
+}
+
+// subtitleURL returns the handler URL of a subtitle. The offset is only there
+// so the player reloads the track after it changes.
+func subtitleURL(id int64, offsetMS int) string {
+	return fmt.Sprintf("%s%d.vtt?offset=%d", subtitlePathPrefix, id, offsetMS)
+}
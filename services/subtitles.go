@@ -0,0 +1,238 @@
+package services
+
+import (
+	"database/sql"
+	"encoding/base64"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"mooncaketv/subtitle"
+)
+
+const (
+	// maxSubtitleBytes is the largest subtitle file accepted; real ones are a few hundred KB
+	maxSubtitleBytes = 5 << 20
+
+	// maxSubtitleOffsetMS bounds how far a subtitle can be shifted either way
+	maxSubtitleOffsetMS = 10 * 60 * 1000
+)
+
+// SubtitleTrack describes a subtitle attached to an episode
+type SubtitleTrack struct {
+	ID        int64  `json:"id"`
+	MCID      string `json:"mc_id"`
+	Episode   string `json:"episode"`
+	Label     string `json:"label"`
+	FileName  string `json:"file_name"`
+	Format    string `json:"format"`
+	Charset   string `json:"charset"`
+	OffsetMS  int    `json:"offset_ms"`
+	Cues      int    `json:"cues"`
+	UserID    int    `json:"user_id"`
+	URL       string `json:"url"` // where the asset server serves the WebVTT
+	CreatedAt string `json:"created_at"`
+}
+
+// SubtitleService stores subtitle files converted to WebVTT and serves them
+// to the player. It is created before the database is open, so the database
+// is wired in at startup.
+type SubtitleService struct {
+	mu sync.RWMutex
+	db *DatabaseService
+}
+
+// NewSubtitleService creates a new SubtitleService instance
+func NewSubtitleService() *SubtitleService {
+	return &SubtitleService{}
+}
+
+// WireSubtitles gives the subtitle service its database once it is open.
+// It is a function rather than a method so Wails does not expose it.
+func WireSubtitles(s *SubtitleService, db *DatabaseService) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.db = db
+}
+
+func (s *SubtitleService) database() (*DatabaseService, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.db == nil {
+		return nil, fmt.Errorf("database is not ready")
+	}
+	return s.db, nil
+}
+
+// Attach converts a subtitle file, sent base64 encoded since it may not be
+// UTF-8, and attaches it to an episode of a title
+func (s *SubtitleService) Attach(userID int, mcID, episode, fileName, contentBase64 string) (*SubtitleTrack, error) {
+	db, err := s.database()
+	if err != nil {
+		return nil, err
+	}
+	// Guests share user 0, so what one attaches would be every guest's to change
+	if userID <= 0 {
+		return nil, fmt.Errorf("sign in to attach subtitles")
+	}
+	if mcID == "" || episode == "" {
+		return nil, fmt.Errorf("media and episode are required")
+	}
+	data, err := base64.StdEncoding.DecodeString(contentBase64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid subtitle data: %w", err)
+	}
+	if len(data) == 0 {
+		return nil, fmt.Errorf("subtitle file is empty")
+	}
+	if len(data) > maxSubtitleBytes {
+		return nil, fmt.Errorf("subtitle file is larger than %d MB", maxSubtitleBytes>>20)
+	}
+
+	text, charset, err := subtitle.Decode(data)
+	if err != nil {
+		return nil, err
+	}
+	result, err := subtitle.ConvertText(fileName, text, charset, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	fileName = filepath.Base(fileName)
+	label := strings.TrimSuffix(fileName, filepath.Ext(fileName))
+	if label == "" || label == "." {
+		label = "Subtitle"
+	}
+
+	var id int64
+	err = db.db.QueryRow(`
+		INSERT INTO subtitles (mc_id, episode, label, file_name, format, charset, offset_ms, source, vtt, cues, user_id, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, 0, ?, ?, ?, ?, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP)
+		RETURNING id
+	`, mcID, episode, label, fileName, string(result.Format), result.Charset, text, result.VTT, result.Cues, userID).Scan(&id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to save subtitle: %w", err)
+	}
+	return s.track(db, id)
+}
+
+// List returns the subtitles attached to an episode, oldest first
+func (s *SubtitleService) List(mcID, episode string) ([]SubtitleTrack, error) {
+	db, err := s.database()
+	if err != nil {
+		return nil, err
+	}
+	rows, err := db.db.Query(`
+		SELECT `+subtitleTrackColumns+`
+		FROM subtitles WHERE mc_id = ? AND episode = ?
+		ORDER BY id
+	`, mcID, episode)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query subtitles: %w", err)
+	}
+	defer rows.Close()
+
+	tracks := []SubtitleTrack{}
+	for rows.Next() {
+		t, err := scanSubtitleTrack(rows)
+		if err != nil {
+			return nil, err
+		}
+		tracks = append(tracks, *t)
+	}
+	return tracks, rows.Err()
+}
+
+// SetOffset shifts a subtitle by offsetMS milliseconds from the original
+// file's timing. Anyone may adjust their own subtitles; others' need the
+// manage_media permission.
+func (s *SubtitleService) SetOffset(userID int, id int64, offsetMS int) (*SubtitleTrack, error) {
+	db, err := s.database()
+	if err != nil {
+		return nil, err
+	}
+	if offsetMS < -maxSubtitleOffsetMS || offsetMS > maxSubtitleOffsetMS {
+		return nil, fmt.Errorf("offset must be within %d minutes", maxSubtitleOffsetMS/60000)
+	}
+	if err := s.authorizeChange(db, userID, id); err != nil {
+		return nil, err
+	}
+
+	var fileName, charset, source string
+	err = db.db.QueryRow("SELECT file_name, charset, source FROM subtitles WHERE id = ?", id).Scan(&fileName, &charset, &source)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load subtitle: %w", err)
+	}
+	result, err := subtitle.ConvertText(fileName, source, charset, time.Duration(offsetMS)*time.Millisecond)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := db.db.Exec(`
+		UPDATE subtitles SET offset_ms = ?, vtt = ?, cues = ?, updated_at = CURRENT_TIMESTAMP
+		WHERE id = ?
+	`, offsetMS, result.VTT, result.Cues, id); err != nil {
+		return nil, fmt.Errorf("failed to update subtitle: %w", err)
+	}
+	return s.track(db, id)
+}
+
+// Delete removes a subtitle, with the same permissions as SetOffset
+func (s *SubtitleService) Delete(userID int, id int64) error {
+	db, err := s.database()
+	if err != nil {
+		return err
+	}
+	if err := s.authorizeChange(db, userID, id); err != nil {
+		return err
+	}
+	_, err = db.db.Exec("DELETE FROM subtitles WHERE id = ?", id)
+	return err
+}
+
+// vtt returns the converted WebVTT of a subtitle
+func (s *SubtitleService) vtt(id int64) (string, error) {
+	db, err := s.database()
+	if err != nil {
+		return "", err
+	}
+	var vtt string
+	if err := db.db.QueryRow("SELECT vtt FROM subtitles WHERE id = ?", id).Scan(&vtt); err != nil {
+		return "", err
+	}
+	return vtt, nil
+}
+
+func (s *SubtitleService) authorizeChange(db *DatabaseService, userID int, id int64) error {
+	var owner int
+	err := db.db.QueryRow("SELECT user_id FROM subtitles WHERE id = ?", id).Scan(&owner)
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("subtitle not found")
+	} else if err != nil {
+		return fmt.Errorf("failed to query subtitle: %w", err)
+	}
+	// Rows attached by guests belong to no one
+	if owner != 0 && owner == userID {
+		return nil
+	}
+	return db.Authorize(userID, PermManageMedia)
+}
+
+func (s *SubtitleService) track(db *DatabaseService, id int64) (*SubtitleTrack, error) {
+	row := db.db.QueryRow("SELECT "+subtitleTrackColumns+" FROM subtitles WHERE id = ?", id)
+	return scanSubtitleTrack(row)
+}
+
+const subtitleTrackColumns = "id, mc_id, episode, label, file_name, format, charset, offset_ms, cues, user_id, created_at"
+
+func scanSubtitleTrack(row interface{ Scan(...interface{}) error }) (*SubtitleTrack, error) {
+	var t SubtitleTrack
+	if err := row.Scan(&t.ID, &t.MCID, &t.Episode, &t.Label, &t.FileName, &t.Format, &t.Charset,
+		&t.OffsetMS, &t.Cues, &t.UserID, &t.CreatedAt); err != nil {
+		return nil, err
+	}
+	t.URL = subtitleURL(t.ID, t.OffsetMS)
+	return &t, nil
+}
@@ -0,0 +1,297 @@
+package services
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	defaultTablePageSize = 50
+	maxTablePageSize     = 500
+	redactedValue        = "[redacted]"
+)
+
+// browsableTables is the whitelist of tables the admin table browser may read
+var browsableTables = map[string]bool{
+	"users":           true,
+	"settings":        true,
+	"medias":          true,
+	"bookmarks":       true,
+	"profiles":        true,
+	"history":         true,
+	"mc_comments":     true,
+	"migrations":      true,
+	"auth_events":     true,
+	"audit_log":       true,
+	"source_stats":    true,
+	"host_reputation": true,
+	"source_checks":   true,
+	"media_sources":   true,
+	"episodes":        true,
+	"skip_markers":    true,
+	"subtitles":       true,
+	"danmaku":         true,
+}
+
+// redactedColumns are never returned by the table browser, whatever table they appear in
+var redactedColumns = map[string]bool{
+	"password_hash":     true,
+	"totp_secret":       true,
+	"pin_hash":          true,
+	"parental_pin_hash": true,
+}
+
+// TableColumn describes a column as reported by PRAGMA table_info
+type TableColumn struct {
+	Name         string  `json:"name"`
+	Type         string  `json:"type"`
+	NotNull      bool    `json:"not_null"`
+	DefaultValue *string `json:"default_value"`
+	PrimaryKey   bool    `json:"primary_key"`
+	Redacted     bool    `json:"redacted"`
+}
+
+// TableInfo describes a browsable table
+type TableInfo struct {
+	Name     string        `json:"name"`
+	Columns  []TableColumn `json:"columns"`
+	RowCount int           `json:"row_count"`
+}
+
+// TableQuery selects a page of rows from a browsable table
+type TableQuery struct {
+	Table    string            `json:"table"`
+	Page     int               `json:"page"`
+	PageSize int               `json:"page_size"`
+	SortBy   string            `json:"sort_by"`
+	SortDesc bool              `json:"sort_desc"`
+	Search   string            `json:"search"`
+	Filters  map[string]string `json:"filters"`
+}
+
+// TablePage is one page of rows from a browsable table
+type TablePage struct {
+	Table    string                   `json:"table"`
+	Columns  []TableColumn            `json:"columns"`
+	Rows     []map[string]interface{} `json:"rows"`
+	Total    int                      `json:"total"`
+	Page     int                      `json:"page"`
+	PageSize int                      `json:"page_size"`
+}
+
+// GetBrowsableTables returns metadata for every whitelisted table that exists
+func (ds *DatabaseService) GetBrowsableTables() ([]TableInfo, error) {
+	tables, err := ds.GetAllTables()
+	if err != nil {
+		return nil, err
+	}
+
+	infos := []TableInfo{}
+	for _, table := range tables {
+		if !browsableTables[table] {
+			continue
+		}
+		info, err := ds.GetTableInfo(table)
+		if err != nil {
+			return nil, err
+		}
+		infos = append(infos, *info)
+	}
+	return infos, nil
+}
+
+// GetTableInfo returns column metadata and the row count of a browsable table
+func (ds *DatabaseService) GetTableInfo(table string) (*TableInfo, error) {
+	columns, err := ds.getTableColumns(table)
+	if err != nil {
+		return nil, err
+	}
+
+	var count int
+	if err := ds.db.QueryRow(fmt.Sprintf("SELECT COUNT(*) FROM %s", quoteIdentifier(table))).Scan(&count); err != nil {
+		return nil, err
+	}
+
+	return &TableInfo{
+		Name:     table,
+		Columns:  columns,
+		RowCount: count,
+	}, nil
+}
+
+// QueryTableRows returns a sorted, filtered page of rows from a browsable table.
+// Search matches any column; Filters match individual columns. Both use substring matching.
+func (ds *DatabaseService) QueryTableRows(q TableQuery) (*TablePage, error) {
+	columns, err := ds.getTableColumns(q.Table)
+	if err != nil {
+		return nil, err
+	}
+
+	known := make(map[string]bool, len(columns))
+	for _, column := range columns {
+		known[column.Name] = true
+	}
+
+	if q.Page < 1 {
+		q.Page = 1
+	}
+	if q.PageSize <= 0 {
+		q.PageSize = defaultTablePageSize
+	}
+	if q.PageSize > maxTablePageSize {
+		q.PageSize = maxTablePageSize
+	}
+
+	var conditions []string
+	var args []interface{}
+
+	for column, value := range q.Filters {
+		if value == "" {
+			continue
+		}
+		if !known[column] {
+			return nil, fmt.Errorf("unknown column: %s", column)
+		}
+		if redactedColumns[column] {
+			return nil, fmt.Errorf("cannot filter on redacted column: %s", column)
+		}
+		conditions = append(conditions, fmt.Sprintf("CAST(%s AS TEXT) LIKE ? ESCAPE '\\'", quoteIdentifier(column)))
+		args = append(args, likePattern(value))
+	}
+
+	if q.Search != "" {
+		var matches []string
+		for _, column := range columns {
+			if column.Redacted {
+				continue
+			}
+			matches = append(matches, fmt.Sprintf("CAST(%s AS TEXT) LIKE ? ESCAPE '\\'", quoteIdentifier(column.Name)))
+			args = append(args, likePattern(q.Search))
+		}
+		if len(matches) > 0 {
+			conditions = append(conditions, "("+strings.Join(matches, " OR ")+")")
+		}
+	}
+
+	where := ""
+	if len(conditions) > 0 {
+		where = " WHERE " + strings.Join(conditions, " AND ")
+	}
+
+	orderBy := ""
+	if q.SortBy != "" {
+		if !known[q.SortBy] {
+			return nil, fmt.Errorf("unknown column: %s", q.SortBy)
+		}
+		if redactedColumns[q.SortBy] {
+			return nil, fmt.Errorf("cannot sort on redacted column: %s", q.SortBy)
+		}
+		direction := "ASC"
+		if q.SortDesc {
+			direction = "DESC"
+		}
+		orderBy = fmt.Sprintf(" ORDER BY %s %s", quoteIdentifier(q.SortBy), direction)
+	}
+
+	from := quoteIdentifier(q.Table)
+
+	var total int
+	if err := ds.db.QueryRow("SELECT COUNT(*) FROM "+from+where, args...).Scan(&total); err != nil {
+		return nil, err
+	}
+
+	rows, err := ds.db.Query(
+		"SELECT * FROM "+from+where+orderBy+" LIMIT ? OFFSET ?",
+		append(args, q.PageSize, (q.Page-1)*q.PageSize)...,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	names, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+
+	page := &TablePage{
+		Table:    q.Table,
+		Columns:  columns,
+		Rows:     []map[string]interface{}{},
+		Total:    total,
+		Page:     q.Page,
+		PageSize: q.PageSize,
+	}
+
+	for rows.Next() {
+		values := make([]interface{}, len(names))
+		pointers := make([]interface{}, len(names))
+		for i := range values {
+			pointers[i] = &values[i]
+		}
+		if err := rows.Scan(pointers...); err != nil {
+			return nil, err
+		}
+
+		row := make(map[string]interface{}, len(names))
+		for i, name := range names {
+			switch {
+			case redactedColumns[name]:
+				row[name] = redactedValue
+			case values[i] == nil:
+				row[name] = nil
+			default:
+				if b, ok := values[i].([]byte); ok {
+					row[name] = string(b)
+				} else {
+					row[name] = values[i]
+				}
+			}
+		}
+		page.Rows = append(page.Rows, row)
+	}
+
+	return page, rows.Err()
+}
+
+// getTableColumns reads PRAGMA table_info for a whitelisted table
+func (ds *DatabaseService) getTableColumns(table string) ([]TableColumn, error) {
+	if !browsableTables[table] {
+		return nil, fmt.Errorf("table not available: %s", table)
+	}
+
+	rows, err := ds.db.Query(fmt.Sprintf("PRAGMA table_info(%s)", quoteIdentifier(table)))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var columns []TableColumn
+	for rows.Next() {
+		var cid, notNull, pk int
+		var column TableColumn
+		if err := rows.Scan(&cid, &column.Name, &column.Type, &notNull, &column.DefaultValue, &pk); err != nil {
+			return nil, err
+		}
+		column.NotNull = notNull != 0
+		column.PrimaryKey = pk != 0
+		column.Redacted = redactedColumns[column.Name]
+		columns = append(columns, column)
+	}
+
+	if len(columns) == 0 {
+		return nil, fmt.Errorf("table not found: %s", table)
+	}
+	return columns, rows.Err()
+}
+
+// quoteIdentifier quotes a table or column name that has already been validated
+func quoteIdentifier(name string) string {
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
+
+// likePattern builds a substring LIKE pattern with wildcards in value escaped
+func likePattern(value string) string {
+	escaped := strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(value)
+	return "%" + escaped + "%"
+}
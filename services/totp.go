@@ -0,0 +1,431 @@
+package services
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/subtle"
+	"database/sql"
+	"encoding/base32"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/skip2/go-qrcode"
+)
+
+const (
+	totpIssuer = "MooncakeTV"
+	totpDigits = 6
+	totpPeriod = 30
+	// totpSkew is how many periods either side of now are accepted
+	totpSkew = 1
+
+	recoveryCodeCount = 10
+
+	// twoFactorChallengeLifetime is how long the second login step may take
+	twoFactorChallengeLifetime = 5 * time.Minute
+)
+
+var totpEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// TOTPEnrollment is returned when a user starts setting up two-factor authentication
+type TOTPEnrollment struct {
+	Secret     string `json:"secret"`
+	OTPAuthURI string `json:"otpauth_uri"`
+	QRCodePNG  []byte `json:"qr_code_png"`
+}
+
+// twoFactorChallenge is a login that passed the password step and awaits a code
+type twoFactorChallenge struct {
+	userID    int
+	expiresAt time.Time
+}
+
+// totpCode computes the RFC 6238 code for a counter value
+func totpCode(secret []byte, counter uint64) string {
+	var msg [8]byte
+	binary.BigEndian.PutUint64(msg[:], counter)
+
+	mac := hmac.New(sha1.New, secret)
+	mac.Write(msg[:])
+	sum := mac.Sum(nil)
+
+	// Dynamic truncation, RFC 4226 section 5.3
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+
+	mod := uint32(1)
+	for i := 0; i < totpDigits; i++ {
+		mod *= 10
+	}
+	return fmt.Sprintf("%0*d", totpDigits, value%mod)
+}
+
+// matchTOTP returns the counter a code is valid for, within the allowed skew.
+// An empty secret, one that could not be decrypted, matches nothing.
+func matchTOTP(secret []byte, code string, now time.Time) (uint64, bool) {
+	code = strings.ReplaceAll(strings.TrimSpace(code), " ", "")
+	if len(secret) == 0 || len(code) != totpDigits {
+		return 0, false
+	}
+
+	current := uint64(now.Unix() / totpPeriod)
+	for delta := -totpSkew; delta <= totpSkew; delta++ {
+		counter := uint64(int64(current) + int64(delta))
+		if subtle.ConstantTimeCompare([]byte(totpCode(secret, counter)), []byte(code)) == 1 {
+			return counter, true
+		}
+	}
+	return 0, false
+}
+
+// BeginTOTPEnrollment generates a new secret for the user and stores it encrypted
+// until the first code is confirmed
+func (as *AuthService) BeginTOTPEnrollment(userID int) (*TOTPEnrollment, error) {
+	var username string
+	var enabled bool
+	err := as.db.GetDB().QueryRow("SELECT username, totp_enabled FROM users WHERE id = ?", userID).Scan(&username, &enabled)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("user not found")
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to query user: %w", err)
+	}
+	if enabled {
+		return nil, fmt.Errorf("two-factor authentication is already enabled")
+	}
+
+	raw := make([]byte, 20)
+	if _, err := rand.Read(raw); err != nil {
+		return nil, fmt.Errorf("failed to generate secret: %w", err)
+	}
+	secret := totpEncoding.EncodeToString(raw)
+
+	encrypted, err := as.db.EncryptSecret(secret)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = as.db.GetDB().Exec(`
+		UPDATE users SET totp_secret = ?, totp_enabled = 0, totp_last_counter = 0, updated_at = CURRENT_TIMESTAMP
+		WHERE id = ?
+	`, encrypted, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to store secret: %w", err)
+	}
+
+	label := url.PathEscape(totpIssuer + ":" + username)
+	params := url.Values{}
+	params.Set("secret", secret)
+	params.Set("issuer", totpIssuer)
+	params.Set("digits", fmt.Sprint(totpDigits))
+	params.Set("period", fmt.Sprint(totpPeriod))
+	uri := "otpauth://totp/" + label + "?" + params.Encode()
+
+	png, err := qrcode.Encode(uri, qrcode.Medium, 256)
+	if err != nil {
+		return nil, fmt.Errorf("failed to render QR code: %w", err)
+	}
+
+	return &TOTPEnrollment{
+		Secret:     secret,
+		OTPAuthURI: uri,
+		QRCodePNG:  png,
+	}, nil
+}
+
+// ConfirmTOTPEnrollment enables two-factor authentication once the user proves
+// their authenticator works, and returns one-time recovery codes
+func (as *AuthService) ConfirmTOTPEnrollment(userID int, code string) ([]string, error) {
+	secret, enabled, err := as.loadTOTPSecret(userID)
+	if err != nil {
+		return nil, err
+	}
+	if enabled {
+		return nil, fmt.Errorf("two-factor authentication is already enabled")
+	}
+	if secret == nil {
+		return nil, fmt.Errorf("two-factor setup can no longer be read, please start again")
+	}
+
+	counter, ok := matchTOTP(secret, code, time.Now())
+	if !ok {
+		return nil, fmt.Errorf("invalid verification code")
+	}
+
+	tx, err := as.db.GetDB().Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(`
+		UPDATE users SET totp_enabled = 1, totp_last_counter = ?, updated_at = CURRENT_TIMESTAMP
+		WHERE id = ?
+	`, counter, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to enable two-factor authentication: %w", err)
+	}
+
+	codes, err := replaceRecoveryCodes(tx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return codes, nil
+}
+
+// DisableTOTP turns off two-factor authentication after verifying the user's password
+func (as *AuthService) DisableTOTP(userID int, password string) error {
+	if err := as.checkPassword(userID, password); err != nil {
+		return err
+	}
+
+	tx, err := as.db.GetDB().Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(`
+		UPDATE users SET totp_secret = NULL, totp_enabled = 0, totp_last_counter = 0, updated_at = CURRENT_TIMESTAMP
+		WHERE id = ?
+	`, userID)
+	if err != nil {
+		return fmt.Errorf("failed to disable two-factor authentication: %w", err)
+	}
+
+	if _, err := tx.Exec("DELETE FROM user_recovery_codes WHERE user_id = ?", userID); err != nil {
+		return fmt.Errorf("failed to delete recovery codes: %w", err)
+	}
+
+	return tx.Commit()
+}
+
+// RegenerateRecoveryCodes replaces the user's recovery codes after verifying their password
+func (as *AuthService) RegenerateRecoveryCodes(userID int, password string) ([]string, error) {
+	if err := as.checkPassword(userID, password); err != nil {
+		return nil, err
+	}
+
+	_, enabled, err := as.loadTOTPSecret(userID)
+	if err != nil {
+		return nil, err
+	}
+	if !enabled {
+		return nil, fmt.Errorf("two-factor authentication is not enabled")
+	}
+
+	tx, err := as.db.GetDB().Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	codes, err := replaceRecoveryCodes(tx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return codes, nil
+}
+
+// LoginWithSecondFactor completes a two-step login with a TOTP code or a recovery code
+func (as *AuthService) LoginWithSecondFactor(challengeToken, code string) (*User, error) {
+	as.mu.Lock()
+	challenge, ok := as.challenges[challengeToken]
+	if ok && time.Now().After(challenge.expiresAt) {
+		delete(as.challenges, challengeToken)
+		ok = false
+	}
+	as.mu.Unlock()
+	if !ok {
+		return nil, fmt.Errorf("login expired, please sign in again")
+	}
+
+	userID := challenge.userID
+	var username string
+	if err := as.db.GetDB().QueryRow("SELECT username FROM users WHERE id = ?", userID).Scan(&username); err != nil {
+		return nil, fmt.Errorf("user not found")
+	}
+	key := throttleKey(username)
+
+	if err := as.checkLoginAllowed(key); err != nil {
+		as.recordAuthEvent(&userID, key, AuthEventLoginBlocked, false, err.Error())
+		return nil, err
+	}
+
+	method, err := as.verifySecondFactor(userID, code)
+	if err != nil {
+		as.recordLoginFailure(key)
+		as.recordAuthEvent(&userID, key, AuthEventLoginFailed, false, err.Error())
+		return nil, err
+	}
+
+	as.mu.Lock()
+	delete(as.challenges, challengeToken)
+	as.mu.Unlock()
+
+	user, err := as.getUser(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	as.clearLoginFailures(key)
+
+	user.SessionToken, err = as.createSession(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	as.recordAuthEvent(&userID, key, AuthEventLoginSuccess, true, method)
+	return user, nil
+}
+
+// startTwoFactorChallenge records a password-verified login awaiting its second factor
+func (as *AuthService) startTwoFactorChallenge(userID int) (string, error) {
+	raw := make([]byte, 24)
+	if _, err := rand.Read(raw); err != nil {
+		return "", fmt.Errorf("failed to generate challenge: %w", err)
+	}
+	token := base64.RawURLEncoding.EncodeToString(raw)
+
+	as.mu.Lock()
+	defer as.mu.Unlock()
+
+	now := time.Now()
+	for t, c := range as.challenges {
+		if now.After(c.expiresAt) {
+			delete(as.challenges, t)
+		}
+	}
+	as.challenges[token] = twoFactorChallenge{
+		userID:    userID,
+		expiresAt: now.Add(twoFactorChallengeLifetime),
+	}
+	return token, nil
+}
+
+// verifySecondFactor checks a TOTP code, then a recovery code, and reports which one matched.
+// TOTP codes cannot be replayed within their window. When the secret cannot be
+// decrypted only recovery codes are accepted.
+func (as *AuthService) verifySecondFactor(userID int, code string) (string, error) {
+	secret, enabled, err := as.loadTOTPSecret(userID)
+	if err != nil {
+		return "", err
+	}
+	if !enabled {
+		return "", fmt.Errorf("two-factor authentication is not enabled")
+	}
+
+	if counter, ok := matchTOTP(secret, code, time.Now()); ok {
+		// Checked and recorded in one statement so concurrent submissions of a code cannot both pass
+		result, err := as.db.GetDB().Exec(
+			"UPDATE users SET totp_last_counter = ? WHERE id = ? AND totp_last_counter < ?",
+			counter, userID, counter)
+		if err != nil {
+			return "", fmt.Errorf("failed to record code use: %w", err)
+		}
+		if affected, err := result.RowsAffected(); err != nil {
+			return "", err
+		} else if affected == 0 {
+			return "", fmt.Errorf("verification code already used")
+		}
+		return "totp", nil
+	}
+
+	result, err := as.db.GetDB().Exec(`
+		UPDATE user_recovery_codes SET used_at = CURRENT_TIMESTAMP
+		WHERE id = (
+			SELECT id FROM user_recovery_codes
+			WHERE user_id = ? AND code_hash = ? AND used_at IS NULL
+			LIMIT 1
+		)
+	`, userID, hashRecoveryCode(code))
+	if err != nil {
+		return "", fmt.Errorf("failed to check recovery code: %w", err)
+	}
+	if affected, _ := result.RowsAffected(); affected == 1 {
+		return "recovery_code", nil
+	}
+
+	return "", fmt.Errorf("invalid verification code")
+}
+
+// loadTOTPSecret returns the decrypted secret and whether 2FA is enabled.
+// The secret is nil when it cannot be decrypted, as after the database is restored or moved
+// without its secret.key; the user then signs in with a recovery code and sets up 2FA again.
+func (as *AuthService) loadTOTPSecret(userID int) ([]byte, bool, error) {
+	var encrypted sql.NullString
+	var enabled bool
+	err := as.db.GetDB().QueryRow(`
+		SELECT totp_secret, totp_enabled FROM users WHERE id = ?
+	`, userID).Scan(&encrypted, &enabled)
+	if err == sql.ErrNoRows {
+		return nil, false, fmt.Errorf("user not found")
+	} else if err != nil {
+		return nil, false, fmt.Errorf("failed to query user: %w", err)
+	}
+	if !encrypted.Valid {
+		return nil, false, fmt.Errorf("two-factor authentication has not been set up")
+	}
+
+	secret, err := as.db.DecryptSecret(encrypted.String)
+	if err != nil {
+		log.Printf("TOTP secret of user %d is unreadable, only recovery codes will work: %v", userID, err)
+		return nil, enabled, nil
+	}
+	raw, err := totpEncoding.DecodeString(secret)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to decode secret: %w", err)
+	}
+	return raw, enabled, nil
+}
+
+// replaceRecoveryCodes deletes the user's recovery codes and issues a new set
+func replaceRecoveryCodes(tx *sql.Tx, userID int) ([]string, error) {
+	if _, err := tx.Exec("DELETE FROM user_recovery_codes WHERE user_id = ?", userID); err != nil {
+		return nil, fmt.Errorf("failed to delete recovery codes: %w", err)
+	}
+
+	codes := make([]string, 0, recoveryCodeCount)
+	for i := 0; i < recoveryCodeCount; i++ {
+		raw := make([]byte, 5)
+		if _, err := rand.Read(raw); err != nil {
+			return nil, fmt.Errorf("failed to generate recovery code: %w", err)
+		}
+		encoded := strings.ToLower(hex.EncodeToString(raw))
+		code := encoded[:5] + "-" + encoded[5:]
+
+		_, err := tx.Exec(`
+			INSERT INTO user_recovery_codes (user_id, code_hash, created_at)
+			VALUES (?, ?, CURRENT_TIMESTAMP)
+		`, userID, hashRecoveryCode(code))
+		if err != nil {
+			return nil, fmt.Errorf("failed to store recovery code: %w", err)
+		}
+		codes = append(codes, code)
+	}
+	return codes, nil
+}
+
+// hashRecoveryCode normalizes and hashes a recovery code. The codes carry 40 random
+// bits, so a fast hash is enough.
+func hashRecoveryCode(code string) string {
+	normalized := strings.ToLower(strings.ReplaceAll(strings.TrimSpace(code), "-", ""))
+	sum := sha256.Sum256([]byte(normalized))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,241 @@
+package services
+
+import (
+	"crypto/rand"
+	"database/sql"
+	"fmt"
+	"math/big"
+	"strconv"
+)
+
+const temporaryPasswordLength = 12
+
+// temporaryPasswordAlphabet leaves out characters that are easy to misread
+const temporaryPasswordAlphabet = "abcdefghjkmnpqrstuvwxyzABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+
+// userDataTables hold rows keyed by user_id that are removed with the user
+var userDataTables = []string{"bookmarks", "history", "settings", "mc_comments", "sessions", "user_recovery_codes", "profiles", "parental_controls", "skip_markers", "subtitles", "danmaku"}
+
+// SetUserRole changes a user's role. Only admins may grant or take away the admin role,
+// and the last active admin cannot be demoted.
+func (as *AuthService) SetUserRole(actorID, targetID int, role string) error {
+	tx, err := as.db.GetDB().Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := checkAssignableRole(tx, role); err != nil {
+		return err
+	}
+
+	currentRole, _, err := getUserRoleTx(tx, targetID)
+	if err != nil {
+		return err
+	}
+	if currentRole == role {
+		return nil
+	}
+	if currentRole == RoleAdmin || role == RoleAdmin {
+		if err := checkActorIsAdmin(tx, actorID); err != nil {
+			return err
+		}
+	}
+
+	if currentRole == RoleAdmin {
+		if err := ensureNotLastAdmin(tx, targetID); err != nil {
+			return err
+		}
+	}
+
+	_, err = tx.Exec("UPDATE users SET user_role = ?, updated_at = CURRENT_TIMESTAMP WHERE id = ?", role, targetID)
+	if err != nil {
+		return fmt.Errorf("failed to update role: %w", err)
+	}
+	if err := recordAudit(tx, actorID, AuditUserRoleChanged, AuditTargetUser, strconv.Itoa(targetID), currentRole, role); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// SetUserDisabled disables or re-enables an account. Only admins may change an admin's account,
+// and the last active admin cannot be disabled.
+func (as *AuthService) SetUserDisabled(actorID, targetID int, disabled bool) error {
+	tx, err := as.db.GetDB().Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	role, isDisabled, err := getUserRoleTx(tx, targetID)
+	if err != nil {
+		return err
+	}
+	if isDisabled == disabled {
+		return nil
+	}
+	if role == RoleAdmin {
+		if err := checkActorIsAdmin(tx, actorID); err != nil {
+			return err
+		}
+	}
+
+	if disabled && role == RoleAdmin {
+		if err := ensureNotLastAdmin(tx, targetID); err != nil {
+			return err
+		}
+	}
+
+	_, err = tx.Exec("UPDATE users SET disabled = ?, updated_at = CURRENT_TIMESTAMP WHERE id = ?", disabled, targetID)
+	if err != nil {
+		return fmt.Errorf("failed to update account: %w", err)
+	}
+	if err := recordAudit(tx, actorID, AuditUserDisabled, AuditTargetUser, strconv.Itoa(targetID), isDisabled, disabled); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// DeleteUser removes a user together with all of their data. Only admins may delete an admin,
+// and the last active admin cannot be deleted.
+func (as *AuthService) DeleteUser(actorID, targetID int) error {
+	tx, err := as.db.GetDB().Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	role, _, err := getUserRoleTx(tx, targetID)
+	if err != nil {
+		return err
+	}
+	if role == RoleAdmin {
+		if err := checkActorIsAdmin(tx, actorID); err != nil {
+			return err
+		}
+		if err := ensureNotLastAdmin(tx, targetID); err != nil {
+			return err
+		}
+	}
+
+	var username, email string
+	if err := tx.QueryRow("SELECT username, email FROM users WHERE id = ?", targetID).Scan(&username, &email); err != nil {
+		return err
+	}
+	before := map[string]interface{}{"username": username, "email": email, "user_role": role}
+
+	for _, table := range userDataTables {
+		if _, err := tx.Exec("DELETE FROM "+table+" WHERE user_id = ?", targetID); err != nil {
+			return fmt.Errorf("failed to delete user data from %s: %w", table, err)
+		}
+	}
+
+	// Recorded before the row goes so the actor's name can still be looked up when users delete themselves
+	if err := recordAudit(tx, actorID, AuditUserDeleted, AuditTargetUser, strconv.Itoa(targetID), before, nil); err != nil {
+		return err
+	}
+	if _, err := tx.Exec("DELETE FROM users WHERE id = ?", targetID); err != nil {
+		return fmt.Errorf("failed to delete user: %w", err)
+	}
+
+	return tx.Commit()
+}
+
+// SetTemporaryPassword replaces a user's password with a generated one that
+// must be changed at next login, and returns it so the admin can pass it on.
+// Only admins may reset an admin's password.
+func (as *AuthService) SetTemporaryPassword(actorID, targetID int) (string, error) {
+	password, err := generateTemporaryPassword()
+	if err != nil {
+		return "", err
+	}
+
+	hashedPassword, err := as.hashPassword(password)
+	if err != nil {
+		return "", fmt.Errorf("failed to hash password: %w", err)
+	}
+
+	tx, err := as.db.GetDB().Begin()
+	if err != nil {
+		return "", fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	role, _, err := getUserRoleTx(tx, targetID)
+	if err != nil {
+		return "", err
+	}
+	if role == RoleAdmin {
+		if err := checkActorIsAdmin(tx, actorID); err != nil {
+			return "", err
+		}
+	}
+
+	_, err = tx.Exec(`
+		UPDATE users
+		SET password_hash = ?, must_change_password = 1, updated_at = CURRENT_TIMESTAMP
+		WHERE id = ?
+	`, hashedPassword, targetID)
+	if err != nil {
+		return "", fmt.Errorf("failed to set password: %w", err)
+	}
+
+	// Anyone still signed in with the old password is signed out
+	if _, err := tx.Exec("DELETE FROM sessions WHERE user_id = ?", targetID); err != nil {
+		return "", fmt.Errorf("failed to revoke sessions: %w", err)
+	}
+
+	// The password itself is never written to the audit log
+	if err := recordAudit(tx, actorID, AuditPasswordReset, AuditTargetUser, strconv.Itoa(targetID), nil, nil); err != nil {
+		return "", err
+	}
+	if err := tx.Commit(); err != nil {
+		return "", err
+	}
+
+	return password, nil
+}
+
+// getUserRoleTx returns a user's role and disabled flag inside a transaction
+func getUserRoleTx(tx *sql.Tx, userID int) (string, bool, error) {
+	var role string
+	var disabled bool
+	err := tx.QueryRow("SELECT user_role, disabled FROM users WHERE id = ?", userID).Scan(&role, &disabled)
+	if err == sql.ErrNoRows {
+		return "", false, fmt.Errorf("user not found")
+	} else if err != nil {
+		return "", false, err
+	}
+	return role, disabled, nil
+}
+
+// ensureNotLastAdmin fails if userID is the only enabled admin
+func ensureNotLastAdmin(tx *sql.Tx, userID int) error {
+	var others int
+	err := tx.QueryRow(`
+		SELECT COUNT(*) FROM users
+		WHERE user_role = ? AND disabled = 0 AND id != ?
+	`, RoleAdmin, userID).Scan(&others)
+	if err != nil {
+		return fmt.Errorf("failed to count admins: %w", err)
+	}
+	if others == 0 {
+		return fmt.Errorf("cannot remove the last admin")
+	}
+	return nil
+}
+
+func generateTemporaryPassword() (string, error) {
+	max := big.NewInt(int64(len(temporaryPasswordAlphabet)))
+	password := make([]byte, temporaryPasswordLength)
+	for i := range password {
+		n, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			return "", fmt.Errorf("failed to generate password: %w", err)
+		}
+		password[i] = temporaryPasswordAlphabet[n.Int64()]
+	}
+	return string(password), nil
+}
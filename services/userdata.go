@@ -0,0 +1,462 @@
+package services
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strconv"
+	"time"
+)
+
+// UserDataArchiveVersion is the archive format written by ExportUserData
+const UserDataArchiveVersion = 1
+
+// Conflict strategies for ImportUserData
+const (
+	ImportStrategySkip       = "skip"
+	ImportStrategyOverwrite  = "overwrite"
+	ImportStrategyNewestWins = "newest"
+)
+
+// dbTimeLayout matches SQLite's CURRENT_TIMESTAMP format
+const dbTimeLayout = "2006-01-02 15:04:05"
+
+// UserDataService exports and imports a user's personal data
+type UserDataService struct {
+	db *DatabaseService
+}
+
+// UserDataArchive is the portable JSON form of a user's data
+type UserDataArchive struct {
+	Version    int                `json:"version"`
+	ExportedAt string             `json:"exported_at"`
+	Username   string             `json:"username"`
+	Profile    string             `json:"profile,omitempty"`
+	Bookmarks  []ArchivedBookmark `json:"bookmarks"`
+	History    []ArchivedHistory  `json:"history"`
+	Settings   []ArchivedSetting  `json:"settings"`
+	Medias     []ArchivedMedia    `json:"medias"`
+}
+
+// ArchivedBookmark is a bookmark entry in an archive
+type ArchivedBookmark struct {
+	McID      string `json:"mc_id"`
+	CreatedAt string `json:"created_at"`
+}
+
+// ArchivedHistory is a history entry in an archive
+type ArchivedHistory struct {
+	McID      string `json:"mc_id"`
+	CreatedAt string `json:"created_at"`
+}
+
+// ArchivedSetting is a personal setting in an archive
+type ArchivedSetting struct {
+	Key       string `json:"key"`
+	Value     string `json:"value"`
+	UpdatedAt string `json:"updated_at"`
+}
+
+// ArchivedMedia is a cached medias row referenced by a bookmark or history entry
+type ArchivedMedia struct {
+	McID         string  `json:"mc_id"`
+	Title        string  `json:"title"`
+	Description  string  `json:"description"`
+	Year         int64   `json:"year"`
+	Genre        string  `json:"genre"`
+	Region       string  `json:"region"`
+	Category     string  `json:"category"`
+	DoubanRating float64 `json:"douban_rating"`
+	DoubanID     string  `json:"douban_id"`
+	ImdbRating   float64 `json:"imdb_rating"`
+	ImdbID       string  `json:"imdb_id"`
+	TmdbRating   float64 `json:"tmdb_rating"`
+	TmdbID       string  `json:"tmdb_id"`
+	PosterURL    string  `json:"poster_url"`
+	VideoURLs    string  `json:"video_urls"`
+	VideoURLType string  `json:"video_url_type"`
+	CreatedAt    string  `json:"created_at"`
+	UpdatedAt    string  `json:"updated_at"`
+}
+
+// ImportCounts tallies what happened to one kind of record during an import
+type ImportCounts struct {
+	Added   int `json:"added"`
+	Updated int `json:"updated"`
+	Skipped int `json:"skipped"`
+}
+
+// ImportReport summarizes an import
+type ImportReport struct {
+	Strategy  string       `json:"strategy"`
+	Bookmarks ImportCounts `json:"bookmarks"`
+	History   ImportCounts `json:"history"`
+	Settings  ImportCounts `json:"settings"`
+	Medias    ImportCounts `json:"medias"`
+}
+
+// NewUserDataService creates a new UserDataService instance
+func NewUserDataService(db *DatabaseService) *UserDataService {
+	return &UserDataService{db: db}
+}
+
+// ExportUserData builds an archive of the active profile's bookmarks, history, personal settings
+// and the cached media rows they reference
+func (us *UserDataService) ExportUserData(userID int) (*UserDataArchive, error) {
+	db := us.db.GetDB()
+
+	profileID, err := us.db.ActiveProfileID(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	archive := &UserDataArchive{
+		Version:    UserDataArchiveVersion,
+		ExportedAt: time.Now().UTC().Format(time.RFC3339),
+		Bookmarks:  []ArchivedBookmark{},
+		History:    []ArchivedHistory{},
+		Settings:   []ArchivedSetting{},
+		Medias:     []ArchivedMedia{},
+	}
+
+	err = db.QueryRow(`
+		SELECT u.username, p.name FROM users u JOIN profiles p ON p.id = ?
+		WHERE u.id = ?
+	`, profileID, userID).Scan(&archive.Username, &archive.Profile)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("user not found")
+		}
+		return nil, err
+	}
+
+	rows, err := db.Query("SELECT mc_id, created_at FROM bookmarks WHERE profile_id = ? ORDER BY created_at", profileID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read bookmarks: %w", err)
+	}
+	for rows.Next() {
+		var b ArchivedBookmark
+		if err := rows.Scan(&b.McID, &b.CreatedAt); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		archive.Bookmarks = append(archive.Bookmarks, b)
+	}
+	rows.Close()
+
+	rows, err = db.Query("SELECT mc_id, created_at FROM history WHERE profile_id = ? ORDER BY created_at", profileID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read history: %w", err)
+	}
+	for rows.Next() {
+		var h ArchivedHistory
+		if err := rows.Scan(&h.McID, &h.CreatedAt); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		archive.History = append(archive.History, h)
+	}
+	rows.Close()
+
+	rows, err = db.Query("SELECT setting_key, setting_value, updated_at FROM settings WHERE profile_id = ? ORDER BY setting_key", profileID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read settings: %w", err)
+	}
+	for rows.Next() {
+		var s ArchivedSetting
+		if err := rows.Scan(&s.Key, &s.Value, &s.UpdatedAt); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		archive.Settings = append(archive.Settings, s)
+	}
+	rows.Close()
+
+	rows, err = db.Query(`
+		SELECT mc_id, title, COALESCE(description, ''), COALESCE(year, 0), COALESCE(genre, ''),
+			COALESCE(region, ''), COALESCE(category, ''), COALESCE(douban_rating, 0), COALESCE(douban_id, ''),
+			COALESCE(imdb_rating, 0), COALESCE(imdb_id, ''), COALESCE(tmdb_rating, 0), COALESCE(tmdb_id, ''),
+			COALESCE(poster_url, ''), COALESCE(video_urls, ''), COALESCE(video_url_type, 'm3u8'),
+			created_at, updated_at
+		FROM medias
+		WHERE mc_id IN (
+			SELECT mc_id FROM bookmarks WHERE profile_id = ?
+			UNION
+			SELECT mc_id FROM history WHERE profile_id = ?
+		)
+		ORDER BY mc_id
+	`, profileID, profileID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read medias: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var m ArchivedMedia
+		if err := rows.Scan(&m.McID, &m.Title, &m.Description, &m.Year, &m.Genre, &m.Region, &m.Category,
+			&m.DoubanRating, &m.DoubanID, &m.ImdbRating, &m.ImdbID, &m.TmdbRating, &m.TmdbID,
+			&m.PosterURL, &m.VideoURLs, &m.VideoURLType, &m.CreatedAt, &m.UpdatedAt); err != nil {
+			return nil, err
+		}
+		archive.Medias = append(archive.Medias, m)
+	}
+
+	return archive, nil
+}
+
+// ExportUserDataJSON returns the user's archive encoded as indented JSON
+func (us *UserDataService) ExportUserDataJSON(userID int) (string, error) {
+	archive, err := us.ExportUserData(userID)
+	if err != nil {
+		return "", err
+	}
+	data, err := json.MarshalIndent(archive, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to encode archive: %w", err)
+	}
+	return string(data), nil
+}
+
+// ImportUserDataJSON decodes an archive and merges it into the user's data
+func (us *UserDataService) ImportUserDataJSON(userID int, archiveJSON string, strategy string) (*ImportReport, error) {
+	var archive UserDataArchive
+	if err := json.Unmarshal([]byte(archiveJSON), &archive); err != nil {
+		return nil, fmt.Errorf("invalid archive: %w", err)
+	}
+	return us.ImportUserData(userID, &archive, strategy)
+}
+
+// ImportUserData merges an archive into the active profile's data in a single transaction.
+// Conflicting bookmarks, history and settings are resolved according to strategy; cached
+// media rows are only ever added.
+func (us *UserDataService) ImportUserData(userID int, archive *UserDataArchive, strategy string) (*ImportReport, error) {
+	switch strategy {
+	case ImportStrategySkip, ImportStrategyOverwrite, ImportStrategyNewestWins:
+	case "":
+		strategy = ImportStrategySkip
+	default:
+		return nil, fmt.Errorf("unknown conflict strategy: %s", strategy)
+	}
+
+	if archive.Version < 1 || archive.Version > UserDataArchiveVersion {
+		return nil, fmt.Errorf("unsupported archive version: %d", archive.Version)
+	}
+
+	profileID, err := us.db.ActiveProfileID(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := us.db.GetDB().Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	report := &ImportReport{Strategy: strategy}
+
+	for _, m := range archive.Medias {
+		if m.McID == "" || m.Title == "" {
+			report.Medias.Skipped++
+			continue
+		}
+		if err := importMedia(tx, m, &report.Medias); err != nil {
+			return nil, fmt.Errorf("failed to import media %s: %w", m.McID, err)
+		}
+	}
+
+	for _, b := range archive.Bookmarks {
+		if b.McID == "" {
+			report.Bookmarks.Skipped++
+			continue
+		}
+		if err := importTimedEntry(tx, "bookmarks", userID, profileID, b.McID, b.CreatedAt, strategy, &report.Bookmarks); err != nil {
+			return nil, fmt.Errorf("failed to import bookmark %s: %w", b.McID, err)
+		}
+	}
+
+	for _, h := range archive.History {
+		if h.McID == "" {
+			report.History.Skipped++
+			continue
+		}
+		if err := importTimedEntry(tx, "history", userID, profileID, h.McID, h.CreatedAt, strategy, &report.History); err != nil {
+			return nil, fmt.Errorf("failed to import history %s: %w", h.McID, err)
+		}
+	}
+
+	var changedSettings []ArchivedSetting
+	for _, s := range archive.Settings {
+		// Only known personal settings are imported; global ones such as proxy_* or
+		// registration_mode are never taken from an archive
+		def, ok := LookupSetting(s.Key)
+		if !ok || !def.AllowsPersonal() {
+			report.Settings.Skipped++
+			continue
+		}
+		value, err := def.Normalize(s.Value)
+		if err != nil {
+			log.Printf("Skipping imported setting %s: %v", s.Key, err)
+			report.Settings.Skipped++
+			continue
+		}
+		s.Value = value
+
+		changed, err := importSetting(tx, userID, profileID, s, strategy, &report.Settings)
+		if err != nil {
+			return nil, fmt.Errorf("failed to import setting %s: %w", s.Key, err)
+		}
+		if changed {
+			changedSettings = append(changedSettings, s)
+		}
+	}
+
+	if err := recordAudit(tx, userID, AuditUserDataImport, AuditTargetUser, strconv.Itoa(userID), nil, report); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	for _, s := range changedSettings {
+		us.db.publishSettingChanged(s.Key, s.Value, sql.NullInt64{Int64: int64(userID), Valid: true}, false)
+	}
+
+	return report, nil
+}
+
+// importTimedEntry merges a bookmark or history row, which only carry a timestamp
+func importTimedEntry(tx *sql.Tx, table string, userID, profileID int, mcID, createdAt, strategy string, counts *ImportCounts) error {
+	var existing string
+	err := tx.QueryRow("SELECT created_at FROM "+table+" WHERE profile_id = ? AND mc_id = ?", profileID, mcID).Scan(&existing)
+	if err == sql.ErrNoRows {
+		_, err = tx.Exec("INSERT INTO "+table+" (user_id, profile_id, mc_id, created_at) VALUES (?, ?, ?, ?)", userID, profileID, mcID, normalizeDBTime(createdAt))
+		if err == nil {
+			counts.Added++
+		}
+		return err
+	} else if err != nil {
+		return err
+	}
+
+	if !shouldReplace(strategy, existing, createdAt) {
+		counts.Skipped++
+		return nil
+	}
+
+	_, err = tx.Exec("UPDATE "+table+" SET created_at = ? WHERE profile_id = ? AND mc_id = ?", normalizeDBTime(createdAt), profileID, mcID)
+	if err == nil {
+		counts.Updated++
+	}
+	return err
+}
+
+// importSetting merges a personal setting and reports whether the stored value changed
+func importSetting(tx *sql.Tx, userID, profileID int, s ArchivedSetting, strategy string, counts *ImportCounts) (bool, error) {
+	var id int
+	var existing string
+	err := tx.QueryRow("SELECT id, updated_at FROM settings WHERE profile_id = ? AND setting_key = ?", profileID, s.Key).Scan(&id, &existing)
+	if err == sql.ErrNoRows {
+		_, err = tx.Exec(`
+			INSERT INTO settings (user_id, profile_id, setting_key, setting_value, created_at, updated_at)
+			VALUES (?, ?, ?, ?, CURRENT_TIMESTAMP, ?)
+		`, userID, profileID, s.Key, s.Value, normalizeDBTime(s.UpdatedAt))
+		if err != nil {
+			return false, err
+		}
+		counts.Added++
+		return true, nil
+	} else if err != nil {
+		return false, err
+	}
+
+	if !shouldReplace(strategy, existing, s.UpdatedAt) {
+		counts.Skipped++
+		return false, nil
+	}
+
+	_, err = tx.Exec("UPDATE settings SET setting_value = ?, updated_at = ? WHERE id = ?", s.Value, normalizeDBTime(s.UpdatedAt), id)
+	if err != nil {
+		return false, err
+	}
+	counts.Updated++
+	return true, nil
+}
+
+// importMedia caches a media row the archive references. medias is shared by every
+// user, so rows that already exist, and their sources, are left as they are.
+func importMedia(tx *sql.Tx, m ArchivedMedia, counts *ImportCounts) error {
+	var exists bool
+	if err := tx.QueryRow("SELECT EXISTS(SELECT 1 FROM medias WHERE mc_id = ?)", m.McID).Scan(&exists); err != nil {
+		return err
+	}
+	if exists {
+		counts.Skipped++
+		return nil
+	}
+
+	if m.VideoURLType == "" {
+		m.VideoURLType = "m3u8"
+	}
+
+	_, err := tx.Exec(`
+		INSERT INTO medias (mc_id, title, description, year, genre, region, category,
+			douban_rating, douban_id, imdb_rating, imdb_id, tmdb_rating, tmdb_id,
+			poster_url, video_urls, video_url_type, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, m.McID, m.Title, m.Description, m.Year, m.Genre, m.Region, m.Category,
+		m.DoubanRating, m.DoubanID, m.ImdbRating, m.ImdbID, m.TmdbRating, m.TmdbID,
+		m.PosterURL, m.VideoURLs, m.VideoURLType, normalizeDBTime(m.CreatedAt), normalizeDBTime(m.UpdatedAt))
+	if err != nil {
+		return err
+	}
+
+	// Archives carry the video_urls blob; rebuild the structured sources from it
+	if sources, err := SourcesFromVideoURLs(m.VideoURLs, m.VideoURLType); err != nil {
+		log.Printf("Keeping sources of %s: %v", m.McID, err)
+	} else if err := saveMediaSources(tx, m.McID, sources); err != nil {
+		return err
+	}
+
+	counts.Added++
+	return nil
+}
+
+// shouldReplace decides whether an incoming row replaces an existing one
+func shouldReplace(strategy, existing, incoming string) bool {
+	switch strategy {
+	case ImportStrategyOverwrite:
+		return true
+	case ImportStrategyNewestWins:
+		existingTime, err1 := parseDBTime(existing)
+		incomingTime, err2 := parseDBTime(incoming)
+		if err2 != nil {
+			return false
+		}
+		if err1 != nil {
+			return true
+		}
+		return incomingTime.After(existingTime)
+	default:
+		return false
+	}
+}
+
+// parseDBTime accepts both SQLite's timestamp format and the RFC 3339 form the driver returns
+func parseDBTime(value string) (time.Time, error) {
+	for _, layout := range []string{time.RFC3339Nano, dbTimeLayout, "2006-01-02T15:04:05"} {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid timestamp: %s", value)
+}
+
+// normalizeDBTime converts a timestamp to SQLite's format, falling back to now
+func normalizeDBTime(value string) string {
+	t, err := parseDBTime(value)
+	if err != nil {
+		t = time.Now()
+	}
+	return t.UTC().Format(dbTimeLayout)
+}
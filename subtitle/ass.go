@@ -0,0 +1,116 @@
+package subtitle
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// defaultASSFormat is the [Events] layout used when a file has no Format line.
+// SSA files name the first field Marked instead of Layer; the rest line up.
+var defaultASSFormat = []string{"layer", "start", "end", "style", "name", "marginl", "marginr", "marginv", "effect", "text"}
+
+// drawingMode matches the override that turns a line into vector drawing commands
+var drawingMode = regexp.MustCompile(`\{[^}]*\\p[1-9][^}]*\}`)
+
+// parseASS reads the Dialogue lines of the [Events] section. Styling and
+// positioning are dropped; italics, bold and underline survive as tags.
+func parseASS(text string) ([]Cue, error) {
+	format := defaultASSFormat
+	inEvents := false
+	seen := make(map[string]bool)
+
+	var cues []Cue
+	var firstErr error
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			inEvents = strings.EqualFold(line, "[Events]")
+			continue
+		}
+		if !inEvents {
+			continue
+		}
+
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		switch strings.TrimSpace(key) {
+		case "Format":
+			format = nil
+			for _, f := range strings.Split(value, ",") {
+				format = append(format, strings.ToLower(strings.TrimSpace(f)))
+			}
+		case "Dialogue":
+			// Text is the last field and may itself contain commas
+			fields := strings.SplitN(strings.TrimSpace(value), ",", len(format))
+			if len(fields) != len(format) {
+				continue
+			}
+			named := make(map[string]string, len(format))
+			for i, name := range format {
+				named[name] = fields[i]
+			}
+			start, err := parseClock(named["start"])
+			var end time.Duration
+			if err == nil {
+				end, err = parseClock(named["end"])
+			}
+			if err != nil {
+				if firstErr == nil {
+					firstErr = fmt.Errorf("invalid ASS timing: %w", err)
+				}
+				continue
+			}
+			body := named["text"]
+			if drawingMode.MatchString(body) {
+				continue
+			}
+
+			body = convertASSText(body)
+			if body == "" || end <= start {
+				continue
+			}
+			// Effects are often built from the same line repeated on several layers
+			id := fmt.Sprintf("%d|%d|%s", start, end, body)
+			if seen[id] {
+				continue
+			}
+			seen[id] = true
+			cues = append(cues, Cue{Start: start, End: end, Text: body})
+		}
+	}
+	if len(cues) == 0 && firstErr != nil {
+		return nil, firstErr
+	}
+	return cues, nil
+}
+
+// convertASSText turns the italic, bold and underline overrides into tags,
+// drops every other override and expands the \N, \n and \h escapes
+func convertASSText(text string) string {
+	text = overrideBlock.ReplaceAllStringFunc(text, func(block string) string {
+		var tags strings.Builder
+		for _, o := range strings.Split(strings.Trim(block, "{}"), `\`) {
+			switch o {
+			case "i1":
+				tags.WriteString("<i>")
+			case "i0":
+				tags.WriteString("</i>")
+			case "b1":
+				tags.WriteString("<b>")
+			case "b0":
+				tags.WriteString("</b>")
+			case "u1":
+				tags.WriteString("<u>")
+			case "u0":
+				tags.WriteString("</u>")
+			}
+		}
+		return tags.String()
+	})
+	text = strings.NewReplacer(`\N`, "\n", `\n`, "\n", `\h`, " ").Replace(text)
+	return cleanText(text)
+}
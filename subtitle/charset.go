@@ -0,0 +1,88 @@
+package subtitle
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/simplifiedchinese"
+	"golang.org/x/text/encoding/traditionalchinese"
+	"golang.org/x/text/encoding/unicode"
+)
+
+// Charsets reported by Decode
+const (
+	CharsetUTF8    = "utf-8"
+	CharsetUTF16LE = "utf-16le"
+	CharsetUTF16BE = "utf-16be"
+	CharsetGBK     = "gbk" // decoded as GB18030, which is a superset
+	CharsetBig5    = "big5"
+)
+
+// commonHanzi are frequent characters in both simplified and traditional
+// dialogue. Decoding with the wrong charset turns them into rare characters,
+// so the decoding that yields more of them is taken as the right one.
+const commonHanzi = "的一是了我不人在他有这個个上们們来來到时時大地为為子中你说說生国國年着著就那和要她出也得里裡后後自以会會家可下而过過天去能对對小多然于於心学學么麼之都好看起发發当當没沒成只如事把还還用第样樣道想作种種开開美总總从從无無情己面最女但现現前些所同日手又行意动動方期它头頭经經长長儿兒回位分爱愛老因很给給名法间間知世什两兩次使身者被高已亲親其进進此话話常与與活正感吗嗎呢吧啊谢謝走"
+
+// maxInvalidRatio is the share of undecodable characters above which a
+// legacy charset guess is rejected
+const maxInvalidRatio = 0.02
+
+// Decode turns subtitle bytes into text. UTF-8 and UTF-16 are recognized by
+// their byte order marks or validity; anything else is tried as GBK and Big5,
+// which cover almost every Chinese subtitle that is not Unicode.
+func Decode(data []byte) (string, string, error) {
+	switch {
+	case bytes.HasPrefix(data, []byte{0xEF, 0xBB, 0xBF}):
+		return string(data[3:]), CharsetUTF8, nil
+	case bytes.HasPrefix(data, []byte{0xFF, 0xFE}):
+		text, err := decodeWith(unicode.UTF16(unicode.LittleEndian, unicode.ExpectBOM), data)
+		return text, CharsetUTF16LE, err
+	case bytes.HasPrefix(data, []byte{0xFE, 0xFF}):
+		text, err := decodeWith(unicode.UTF16(unicode.BigEndian, unicode.ExpectBOM), data)
+		return text, CharsetUTF16BE, err
+	case utf8.Valid(data):
+		return string(data), CharsetUTF8, nil
+	}
+
+	best, bestCharset, bestScore, bestInvalid := "", "", 0, 0
+	for _, candidate := range []struct {
+		charset string
+		enc     encoding.Encoding
+	}{
+		{CharsetGBK, simplifiedchinese.GB18030},
+		{CharsetBig5, traditionalchinese.Big5},
+	} {
+		text, err := decodeWith(candidate.enc, data)
+		if err != nil {
+			continue
+		}
+		invalid, common := 0, 0
+		for _, r := range text {
+			if r == utf8.RuneError {
+				invalid++
+			} else if r > 0x7F && strings.ContainsRune(commonHanzi, r) {
+				common++
+			}
+		}
+		score := common - 10*invalid
+		if bestCharset == "" || score > bestScore {
+			best, bestCharset, bestScore, bestInvalid = text, candidate.charset, score, invalid
+		}
+	}
+
+	if bestCharset == "" || float64(bestInvalid) > maxInvalidRatio*float64(utf8.RuneCountInString(best)) {
+		return "", "", fmt.Errorf("unsupported subtitle encoding; save the file as UTF-8, GBK or Big5")
+	}
+	return best, bestCharset, nil
+}
+
+func decodeWith(enc encoding.Encoding, data []byte) (string, error) {
+	decoded, err := enc.NewDecoder().Bytes(data)
+	if err != nil {
+		return "", fmt.Errorf("failed to decode subtitle: %w", err)
+	}
+	return string(decoded), nil
+}
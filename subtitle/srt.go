@@ -0,0 +1,66 @@
+package subtitle
+
+import (
+	"fmt"
+	"strings"
+)
+
+// parseSRT reads numbered blocks of a timing line followed by text. Blocks
+// that cannot be read are skipped so one bad entry does not lose the file.
+func parseSRT(text string) ([]Cue, error) {
+	var cues []Cue
+	var firstErr error
+	for _, block := range splitBlocks(text) {
+		timing := -1
+		for i, line := range block {
+			if i > 1 {
+				break
+			}
+			if strings.Contains(line, "-->") {
+				timing = i
+				break
+			}
+		}
+		if timing < 0 {
+			continue
+		}
+
+		start, end, _, err := parseTiming(block[timing])
+		if err != nil {
+			if firstErr == nil {
+				firstErr = fmt.Errorf("invalid SRT timing: %w", err)
+			}
+			continue
+		}
+		body := cleanText(strings.Join(block[timing+1:], "\n"))
+		if body == "" || end <= start {
+			continue
+		}
+		cues = append(cues, Cue{Start: start, End: end, Text: body})
+	}
+	if len(cues) == 0 && firstErr != nil {
+		return nil, firstErr
+	}
+	return cues, nil
+}
+
+// splitBlocks splits text into groups of non-blank lines
+func splitBlocks(text string) [][]string {
+	var blocks [][]string
+	var current []string
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.TrimRight(line, " \t")
+		if strings.TrimSpace(line) == "" {
+			if len(current) > 0 {
+				blocks = append(blocks, current)
+				current = nil
+			}
+			continue
+		}
+		current = append(current, line)
+	}
+	if len(current) > 0 {
+		blocks = append(blocks, current)
+	}
+	return blocks
+}
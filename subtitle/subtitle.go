@@ -0,0 +1,165 @@
+// Package subtitle reads SRT, ASS/SSA and WebVTT subtitles and writes them
+// as WebVTT, the only format the player's <track> element understands.
+package subtitle
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Format is a subtitle file format
+type Format string
+
+const (
+	FormatSRT Format = "srt"
+	FormatASS Format = "ass" // also covers SSA, which shares the [Events] layout
+	FormatVTT Format = "vtt"
+)
+
+// Cue is one timed piece of text. Text may hold the WebVTT tags <b>, <i> and <u>.
+type Cue struct {
+	Start    time.Duration
+	End      time.Duration
+	Text     string
+	Settings string // WebVTT cue settings, e.g. "line:0"; only kept from WebVTT input
+}
+
+// Result is a converted subtitle file
+type Result struct {
+	VTT     string
+	Format  Format
+	Charset string
+	Cues    int
+}
+
+// DetectFormat guesses the format from the file name, falling back to the content
+func DetectFormat(name, text string) (Format, error) {
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".srt":
+		return FormatSRT, nil
+	case ".ass", ".ssa":
+		return FormatASS, nil
+	case ".vtt":
+		return FormatVTT, nil
+	}
+
+	head := strings.TrimSpace(text)
+	switch {
+	case strings.HasPrefix(head, "WEBVTT"):
+		return FormatVTT, nil
+	case strings.HasPrefix(head, "[Script Info]") || strings.Contains(head, "\nDialogue:"):
+		return FormatASS, nil
+	case strings.Contains(head, "-->"):
+		return FormatSRT, nil
+	}
+	return "", fmt.Errorf("unrecognized subtitle format")
+}
+
+// Parse reads subtitle text in the given format
+func Parse(format Format, text string) ([]Cue, error) {
+	text = strings.ReplaceAll(strings.ReplaceAll(text, "\r\n", "\n"), "\r", "\n")
+
+	var cues []Cue
+	var err error
+	switch format {
+	case FormatSRT:
+		cues, err = parseSRT(text)
+	case FormatASS:
+		cues, err = parseASS(text)
+	case FormatVTT:
+		cues, err = parseVTT(text)
+	default:
+		return nil, fmt.Errorf("unsupported subtitle format: %s", format)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(cues) == 0 {
+		return nil, fmt.Errorf("no subtitles found in the file")
+	}
+
+	sort.SliceStable(cues, func(i, j int) bool {
+		return cues[i].Start < cues[j].Start
+	})
+	return cues, nil
+}
+
+// Shift moves every cue by offset. Cues that end up entirely before zero are
+// dropped and ones that straddle it are cut at zero.
+func Shift(cues []Cue, offset time.Duration) []Cue {
+	if offset == 0 {
+		return cues
+	}
+	shifted := make([]Cue, 0, len(cues))
+	for _, c := range cues {
+		c.Start += offset
+		c.End += offset
+		if c.End <= 0 {
+			continue
+		}
+		if c.Start < 0 {
+			c.Start = 0
+		}
+		shifted = append(shifted, c)
+	}
+	return shifted
+}
+
+// Convert decodes a subtitle file of any supported format and charset and
+// returns it as WebVTT, shifted by offset
+func Convert(name string, data []byte, offset time.Duration) (*Result, error) {
+	text, charset, err := Decode(data)
+	if err != nil {
+		return nil, err
+	}
+	return ConvertText(name, text, charset, offset)
+}
+
+// ConvertText is Convert for text that is already decoded
+func ConvertText(name, text, charset string, offset time.Duration) (*Result, error) {
+	format, err := DetectFormat(name, text)
+	if err != nil {
+		return nil, err
+	}
+	cues, err := Parse(format, text)
+	if err != nil {
+		return nil, err
+	}
+	cues = Shift(cues, offset)
+	return &Result{
+		VTT:     WriteVTT(cues),
+		Format:  format,
+		Charset: charset,
+		Cues:    len(cues),
+	}, nil
+}
+
+// WriteVTT renders cues as a WebVTT file
+func WriteVTT(cues []Cue) string {
+	var b strings.Builder
+	b.WriteString("WEBVTT\n\n")
+	for _, c := range cues {
+		b.WriteString(formatVTTTime(c.Start))
+		b.WriteString(" --> ")
+		b.WriteString(formatVTTTime(c.End))
+		if c.Settings != "" {
+			b.WriteString(" ")
+			b.WriteString(c.Settings)
+		}
+		b.WriteString("\n")
+		b.WriteString(c.Text)
+		b.WriteString("\n\n")
+	}
+	return b.String()
+}
+
+func formatVTTTime(d time.Duration) string {
+	if d < 0 {
+		d = 0
+	}
+	ms := d.Milliseconds()
+	return fmt.Sprintf("%02d:%02d:%02d.%03d", ms/3600000, ms/60000%60, ms/1000%60, ms%1000)
+}
@@ -0,0 +1,132 @@
+package subtitle
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	// markupTag matches an HTML-like tag such as <i> or <font color="red">
+	markupTag = regexp.MustCompile(`<[^<>]*>`)
+
+	// keptTag matches the tags WebVTT renders
+	keptTag = regexp.MustCompile(`^</?[biuBIU]>$`)
+
+	// overrideBlock matches an ASS override block such as {\an8} or {\c&H00FFFF&}
+	overrideBlock = regexp.MustCompile(`\{\\[^}]*\}`)
+
+	// entity matches a character reference that is already escaped
+	entity = regexp.MustCompile(`^&(?:[a-zA-Z]+|#[0-9]+|#x[0-9a-fA-F]+);`)
+)
+
+// parseClock reads a timestamp of the form [h:]mm:ss[.,]fff. The fraction may
+// have any number of digits, so ASS centiseconds read correctly.
+func parseClock(s string) (time.Duration, error) {
+	s = strings.TrimSpace(s)
+	parts := strings.Split(s, ":")
+	if len(parts) < 2 || len(parts) > 3 {
+		return 0, fmt.Errorf("invalid timestamp %q", s)
+	}
+
+	secPart := parts[len(parts)-1]
+	frac := ""
+	if i := strings.IndexAny(secPart, ".,"); i >= 0 {
+		secPart, frac = secPart[:i], secPart[i+1:]
+	}
+
+	var total time.Duration
+	units := []time.Duration{time.Hour, time.Minute}[3-len(parts):]
+	for i, p := range parts[:len(parts)-1] {
+		n, err := strconv.Atoi(p)
+		if err != nil || n < 0 {
+			return 0, fmt.Errorf("invalid timestamp %q", s)
+		}
+		total += time.Duration(n) * units[i]
+	}
+	sec, err := strconv.Atoi(secPart)
+	if err != nil || sec < 0 {
+		return 0, fmt.Errorf("invalid timestamp %q", s)
+	}
+	total += time.Duration(sec) * time.Second
+
+	if frac != "" {
+		if len(frac) > 3 {
+			frac = frac[:3]
+		}
+		ms, err := strconv.Atoi(frac + strings.Repeat("0", 3-len(frac)))
+		if err != nil {
+			return 0, fmt.Errorf("invalid timestamp %q", s)
+		}
+		total += time.Duration(ms) * time.Millisecond
+	}
+	return total, nil
+}
+
+// parseTiming reads a "start --> end [settings]" line
+func parseTiming(line string) (start, end time.Duration, settings string, err error) {
+	left, right, ok := strings.Cut(line, "-->")
+	if !ok {
+		return 0, 0, "", fmt.Errorf("missing -->")
+	}
+	fields := strings.Fields(right)
+	if len(fields) == 0 {
+		return 0, 0, "", fmt.Errorf("missing end time")
+	}
+	if start, err = parseClock(left); err != nil {
+		return 0, 0, "", err
+	}
+	if end, err = parseClock(fields[0]); err != nil {
+		return 0, 0, "", err
+	}
+	return start, end, strings.Join(fields[1:], " "), nil
+}
+
+// cleanText makes cue text safe for WebVTT: only <b>, <i> and <u> survive,
+// ASS overrides are dropped, special characters are escaped and blank lines,
+// which would end the cue, are removed
+func cleanText(text string) string {
+	text = overrideBlock.ReplaceAllString(text, "")
+
+	var b strings.Builder
+	last := 0
+	for _, loc := range markupTag.FindAllStringIndex(text, -1) {
+		b.WriteString(escapeText(text[last:loc[0]]))
+		if tag := text[loc[0]:loc[1]]; keptTag.MatchString(tag) {
+			b.WriteString(strings.ToLower(tag))
+		}
+		last = loc[1]
+	}
+	b.WriteString(escapeText(text[last:]))
+
+	var lines []string
+	for _, line := range strings.Split(b.String(), "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+func escapeText(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '&':
+			if entity.MatchString(s[i:]) {
+				b.WriteByte('&')
+			} else {
+				b.WriteString("&amp;")
+			}
+		case '<':
+			b.WriteString("&lt;")
+		case '>':
+			b.WriteString("&gt;")
+		default:
+			b.WriteByte(s[i])
+		}
+	}
+	return b.String()
+}
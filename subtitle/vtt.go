@@ -0,0 +1,55 @@
+package subtitle
+
+import (
+	"fmt"
+	"strings"
+)
+
+// parseVTT reads a WebVTT file back into cues so it can be shifted and
+// cleaned like the other formats. Comments, styles and regions are dropped.
+func parseVTT(text string) ([]Cue, error) {
+	blocks := splitBlocks(text)
+	if len(blocks) == 0 || !strings.HasPrefix(strings.TrimSpace(blocks[0][0]), "WEBVTT") {
+		return nil, fmt.Errorf("missing WEBVTT header")
+	}
+
+	var cues []Cue
+	var firstErr error
+	for _, block := range blocks[1:] {
+		switch first := strings.TrimSpace(block[0]); {
+		case strings.HasPrefix(first, "NOTE"), first == "STYLE", first == "REGION":
+			continue
+		}
+
+		timing := -1
+		for i, line := range block {
+			if i > 1 {
+				break
+			}
+			if strings.Contains(line, "-->") {
+				timing = i
+				break
+			}
+		}
+		if timing < 0 {
+			continue
+		}
+
+		start, end, settings, err := parseTiming(block[timing])
+		if err != nil {
+			if firstErr == nil {
+				firstErr = fmt.Errorf("invalid WebVTT timing: %w", err)
+			}
+			continue
+		}
+		body := cleanText(strings.Join(block[timing+1:], "\n"))
+		if body == "" || end <= start {
+			continue
+		}
+		cues = append(cues, Cue{Start: start, End: end, Text: body, Settings: settings})
+	}
+	if len(cues) == 0 && firstErr != nil {
+		return nil, firstErr
+	}
+	return cues, nil
+}